@@ -3,10 +3,10 @@
 package main
 
 import (
+	"context"
 	tool "github.com/GeertJohan/go.rice"
 	"github.com/getlantern/systray"
 	"github.com/pacerank/client/internal/gui"
-	"github.com/pacerank/client/internal/inspect"
 	"github.com/pacerank/client/internal/operation"
 	"github.com/pacerank/client/internal/store"
 	"github.com/pacerank/client/internal/watcher"
@@ -48,44 +48,62 @@ func onReady() {
 		apiClient.AddAuthorizationToken(storage.AuthorizationToken())
 	}
 
+	sys := system.New()
+
 	// Poll store to see if anything should be queued for dispatch to digest service
-	go watcher.Sessions(storage)
+	go watcher.Sessions(storage, sys, sys.WatchPower(context.Background()))
+
+	// Code changes found in watched directories and in the buffers of editors
+	code := func(event watcher.CodeEvent) {
+		if event.Err != nil {
+			log.Error().Err(event.Err).Msg("could not watch code")
+			return
+		}
+
+		added, removed, err := watcher.LineChurn(storage, event)
+		if err != nil {
+			log.Debug().Err(err).Str("path", event.Path).Msg("could not count changed lines")
+		}
+
+		err = storage.AddHeap(store.InHeap{
+			Id:           event.Id,
+			Language:     event.Language,
+			Branch:       event.Branch,
+			FileName:     event.FilePath,
+			Project:      event.Project,
+			Git:          event.Git,
+			Tags:         event.Tags,
+			Role:         event.Role,
+			LinesAdded:   added,
+			LinesRemoved: removed,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("could not save code activity to store")
+		}
+
+		log.Info().
+			Str("language", event.Language).
+			Str("filepath", event.FilePath).
+			Str("filename", event.FileName).
+			Str("project", event.Project).
+			Str("branch", event.Branch).
+			Str("git", event.Git).
+			Str("id", event.Id).
+			Msg("found code change")
+	}
 
 	go func() {
 		for {
 			directory := <-storage.NotifyListenToDirectory
 			log.Info().Str("directory", directory).Msg("add watcher to directory")
-			go watcher.Code(directory, func(event watcher.CodeEvent) {
-				if event.Err != nil {
-					log.Error().Err(event.Err).Msg("could not watch code")
-					return
-				}
-
-				err = storage.AddHeap(store.InHeap{
-					Id:       event.Id,
-					Language: event.Language,
-					Branch:   event.Branch,
-					FileName: event.FilePath,
-					Project:  event.Project,
-					Git:      event.Git,
-				})
-				if err != nil {
-					log.Error().Err(err).Msg("could not save code activity to store")
-				}
-
-				log.Info().
-					Str("language", event.Language).
-					Str("filepath", event.FilePath).
-					Str("filename", event.FileName).
-					Str("project", event.Project).
-					Str("branch", event.Branch).
-					Str("git", event.Git).
-					Str("id", event.Id).
-					Msg("found code change")
-			})
+			go watcher.Code(directory, code)
 		}
 	}()
 
+	// Follow the buffers of editors that can be reached over their server sockets
+	go watcher.Neovim(sys, code)
+	go watcher.Emacs(code)
+
 	// Setup watchers for currently saved directories
 	err = storage.NotifyCurrentDirectories()
 	if err != nil {
@@ -107,26 +125,52 @@ func onReady() {
 	err = storage.NewSession()
 
 	// Start recording typing
-	sys := system.New()
-	go watcher.Keyboard(func(key watcher.KeyEvent) {
-		process, err := sys.ActiveProcess()
-		if err != nil {
-			log.Error().Err(err).Msgf("could not get active process")
+	go watcher.Typing(sys, watcher.RecordTyping(storage))
+
+	// Notice editors that start and exit
+	go watcher.EditorProcesses(sys, func(event watcher.EditorProcessEvent) {
+		log.Info().Int64("pid", event.ProcessID).Msgf("%s has %s", event.Editor, event.Type)
+	})
+
+	// Serve heartbeats of editor plugins
+	go watcher.Heartbeats(func(event watcher.HeartbeatEvent) {
+		if event.Err != nil {
+			log.Error().Err(event.Err).Msg("could not serve heartbeats")
 			return
 		}
 
-		editor, ok := inspect.Editor(process.Executable)
-		if !ok {
+		if event.Code != nil {
+			err := storage.AddHeap(store.InHeap{
+				Id:       event.Code.Id,
+				Language: event.Code.Language,
+				Branch:   event.Code.Branch,
+				FileName: event.Code.FilePath,
+				Project:  event.Code.Project,
+				Git:      event.Code.Git,
+				Tags:     event.Code.Tags,
+				Role:     event.Code.Role,
+				Changes:  uint64(event.Heartbeat.Changes),
+			})
+			if err != nil {
+				log.Error().Err(err).Msg("could not save heartbeat to store")
+			}
+		}
+
+		if event.Editor == "" {
+			log.Debug().Str("user_agent", event.Heartbeat.UserAgent).Msg("heartbeat is from an unknown editor")
 			return
 		}
 
-		err = storage.MetaTypingActivity(editor)
+		err := storage.MetaTypingActivity(store.TypingActivity{
+			Editor:        event.Editor,
+			EditorVersion: event.EditorVersion,
+		})
 		if err != nil {
-			log.Error().Err(err).Msg("could not record typing activity to store")
+			log.Error().Err(err).Msg("could not record heartbeat activity to store")
 			return
 		}
 
-		log.Debug().Msgf("recorded typing activity in %s", process.FileName)
+		log.Debug().Str("entity", event.Heartbeat.Entity).Msgf("recorded heartbeat from %s", event.Editor)
 	})
 
 	box, err := tool.FindBox("resources")
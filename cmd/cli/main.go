@@ -1,14 +1,17 @@
 package main
 
 import (
+	"context"
 	"github.com/jessevdk/go-flags"
 	"github.com/pacerank/client/internal/inspect"
+	"github.com/pacerank/client/internal/lsp"
 	"github.com/pacerank/client/internal/operation"
 	"github.com/pacerank/client/internal/store"
 	"github.com/pacerank/client/internal/watcher"
 	"github.com/pacerank/client/pkg/api"
 	"github.com/pacerank/client/pkg/system"
 	"github.com/rs/zerolog/log"
+	"os"
 	"time"
 )
 
@@ -16,6 +19,19 @@ import (
 type Options struct {
 	Verbose bool     `short:"v" long:"verbose" description:"Show verbose debug information"`
 	Folders []string `short:"f" long:"folders" description:"Folders to watch for file changes"`
+
+	MaxFileSize int64 `long:"max-file-size" default:"4194304" description:"Largest file in bytes that is analyzed on change"`
+}
+
+// Run a language server behind a proxy that observes the documents the editor changes, as in
+// "pacerank lsp-proxy -- gopls"
+type LSPProxyCommand struct{}
+
+func (c *LSPProxyCommand) Execute(args []string) error {
+	// Stdout belongs to the language server protocol
+	operation.LogToStderr()
+
+	return lsp.Run(args, os.Stdin, os.Stdout, watcher.SendHeartbeats)
 }
 
 func main() {
@@ -34,8 +50,18 @@ func main() {
 	}()
 
 	var opts Options
-	_, err = flags.Parse(&opts)
+	parser := flags.NewParser(&opts, flags.Default)
+	parser.SubcommandsOptional = true
+
+	_, err = parser.AddCommand("lsp-proxy", "Run a language server behind a proxy",
+		"Run a language server behind a proxy that tells the client which files are changed, e.g. lsp-proxy -- gopls",
+		&LSPProxyCommand{})
 	if err != nil {
+		log.Fatal().Err(err).Msg("could not add lsp-proxy command")
+	}
+
+	_, err = parser.Parse()
+	if err != nil || parser.Active != nil {
 		return
 	}
 
@@ -43,6 +69,8 @@ func main() {
 		operation.EnableDebug()
 	}
 
+	inspect.SetMaxFileSize(opts.MaxFileSize)
+
 	if len(opts.Folders) == 0 {
 		log.Fatal().Msg("must give at least one folder to watch")
 	}
@@ -75,60 +103,115 @@ func main() {
 	apiClient.AddAuthorizationToken(token)
 
 	sys := system.New()
-	go watcher.Keyboard(func(key watcher.KeyEvent) {
-		process, err := sys.ActiveProcess()
-		if err != nil {
-			log.Error().Err(err).Msgf("could not get active process")
+	go watcher.Typing(sys, watcher.RecordTyping(storage))
+
+	// Notice editors that start and exit
+	go watcher.EditorProcesses(sys, func(event watcher.EditorProcessEvent) {
+		log.Info().Int64("pid", event.ProcessID).Msgf("%s has %s", event.Editor, event.Type)
+	})
+
+	// Serve heartbeats of editor plugins
+	go watcher.Heartbeats(func(event watcher.HeartbeatEvent) {
+		if event.Err != nil {
+			log.Error().Err(event.Err).Msg("could not serve heartbeats")
 			return
 		}
 
-		editor, ok := inspect.Editor(process.Executable)
-		if !ok {
+		if event.Code != nil {
+			err := storage.AddHeap(store.InHeap{
+				Id:       event.Code.Id,
+				Language: event.Code.Language,
+				Branch:   event.Code.Branch,
+				FileName: event.Code.FilePath,
+				Project:  event.Code.Project,
+				Git:      event.Code.Git,
+				Tags:     event.Code.Tags,
+				Role:     event.Code.Role,
+				Changes:  uint64(event.Heartbeat.Changes),
+			})
+			if err != nil {
+				log.Error().Err(err).Msg("could not save heartbeat to store")
+			}
+		}
+
+		if event.Editor == "" {
+			log.Debug().Str("user_agent", event.Heartbeat.UserAgent).Msg("heartbeat is from an unknown editor")
 			return
 		}
 
-		err = storage.MetaTypingActivity(editor)
+		err := storage.MetaTypingActivity(store.TypingActivity{
+			Editor:        event.Editor,
+			EditorVersion: event.EditorVersion,
+		})
 		if err != nil {
-			log.Error().Err(err).Msg("could not record typing activity to store")
+			log.Error().Err(err).Msg("could not record heartbeat activity to store")
 			return
 		}
 
-		log.Debug().Msgf("recorded typing activity in %s", process.FileName)
+		log.Debug().Str("entity", event.Heartbeat.Entity).Msgf("recorded heartbeat from %s", event.Editor)
 	})
 
-	for _, folder := range opts.Folders {
-		go watcher.Code(folder, func(event watcher.CodeEvent) {
-			if event.Err != nil {
-				log.Error().Err(event.Err).Msg("could not watch code")
-				return
-			}
+	// Code changes found in watched folders and in the buffers of editors
+	code := func(event watcher.CodeEvent) {
+		if event.Err != nil {
+			log.Error().Err(event.Err).Msg("could not watch code")
+			return
+		}
 
-			err = storage.AddHeap(store.InHeap{
-				Id:       event.Id,
-				Language: event.Language,
-				Branch:   event.Branch,
-				FileName: event.FilePath,
-				Project:  event.Project,
-				Git:      event.Git,
-			})
-			if err != nil {
-				log.Error().Err(err).Msg("could not save code activity to store")
-			}
+		added, removed, err := watcher.LineChurn(storage, event)
+		if err != nil {
+			log.Debug().Err(err).Str("path", event.Path).Msg("could not count changed lines")
+		}
 
-			log.Debug().
-				Str("language", event.Language).
-				Str("filepath", event.FilePath).
-				Str("filename", event.FileName).
-				Str("project", event.Project).
-				Str("branch", event.Branch).
-				Str("git", event.Git).
-				Str("id", event.Id).
-				Msg("code change found")
+		err = storage.AddHeap(store.InHeap{
+			Id:           event.Id,
+			Language:     event.Language,
+			Branch:       event.Branch,
+			FileName:     event.FilePath,
+			Project:      event.Project,
+			Git:          event.Git,
+			Tags:         event.Tags,
+			Role:         event.Role,
+			LinesAdded:   added,
+			LinesRemoved: removed,
 		})
+		if err != nil {
+			log.Error().Err(err).Msg("could not save code activity to store")
+		}
+
+		log.Debug().
+			Str("language", event.Language).
+			Str("filepath", event.FilePath).
+			Str("filename", event.FileName).
+			Str("project", event.Project).
+			Str("branch", event.Branch).
+			Str("git", event.Git).
+			Str("id", event.Id).
+			Msg("code change found")
 	}
 
+	for _, folder := range opts.Folders {
+		go watcher.Code(folder, code)
+	}
+
+	// Follow the buffers of editors that can be reached over their server sockets
+	go watcher.Neovim(sys, code)
+	go watcher.Emacs(code)
+
+	// Suggest projects that are open or were opened recently in editors but not watched
+	go watcher.Workspaces(func() []string {
+		return opts.Folders
+	}, func(event watcher.WorkspaceEvent) {
+		if event.Recent {
+			log.Info().Msgf("%s opened %s recently, run with -f %s to watch it", event.Editor, event.Directory, event.Directory)
+			return
+		}
+
+		log.Info().Msgf("%s has %s open, run with -f %s to watch it", event.Editor, event.Directory, event.Directory)
+	})
+
 	// Poll store to see if anything should be queued for dispatch to digest service
-	go watcher.Sessions(storage)
+	go watcher.Sessions(storage, sys, sys.WatchPower(context.Background()))
 
 	// Poll store to see if any messages are in queue, and send them
 	go watcher.Queue(storage, apiClient, func(structure *api.DefaultReplyStructure, err error) {
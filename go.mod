@@ -1,14 +1,16 @@
 module github.com/pacerank/client
 
-go 1.14
+go 1.16
 
 require (
 	github.com/GeertJohan/go.rice v1.0.0
 	github.com/boltdb/bolt v1.3.1
 	github.com/fsnotify/fsnotify v1.4.9
 	github.com/getlantern/systray v1.0.3
-	github.com/go-enry/go-enry/v2 v2.4.0
+	github.com/go-enry/go-enry/v2 v2.7.1
+	github.com/go-git/go-billy/v5 v5.0.0
 	github.com/go-git/go-git/v5 v5.1.0
+	github.com/godbus/dbus/v5 v5.0.3
 	github.com/jessevdk/go-flags v1.4.0
 	github.com/lxn/win v0.0.0-20191128105842-2da648fda5b4 // indirect
 	github.com/radovskyb/watcher v1.0.7
@@ -18,4 +20,6 @@ require (
 	github.com/sciter-sdk/go-sciter v0.5.1-0.20200602150116-89a4dd09b0f8
 	github.com/stretchr/testify v1.5.1
 	golang.org/x/sys v0.0.0-20200610111108-226ff32320da // indirect
+	gopkg.in/yaml.v2 v2.2.8
+	rsc.io/qr v0.2.0
 )
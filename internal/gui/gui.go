@@ -4,6 +4,7 @@ import (
 	tool "github.com/GeertJohan/go.rice"
 	"github.com/pacerank/client/internal/operation"
 	"github.com/pacerank/client/internal/store"
+	"github.com/pacerank/client/internal/watcher"
 	"github.com/pacerank/client/pkg/api"
 	"github.com/rs/zerolog/log"
 	"github.com/sciter-sdk/go-sciter"
@@ -132,6 +133,45 @@ func Start(storage *store.Store, apiClient *api.Api) *window.Window {
 		return a
 	})
 
+	// Get directories of projects that are open in editors but not watched
+	win.DefineFunction("suggested_directories", func(args ...*sciter.Value) *sciter.Value {
+		directories, err := storage.Directories()
+		if err != nil {
+			log.Error().Err(err).Msgf("could not get folders")
+			return nil
+		}
+
+		var watched []string
+		for _, v := range directories {
+			watched = append(watched, v.Directory)
+		}
+
+		a := sciter.NewValue()
+		for _, v := range watcher.UnwatchedWorkspaces(watched) {
+			err = a.Append(v.Directory)
+			if err != nil {
+				log.Error().Err(err).Msg("could not append suggested directory")
+			}
+		}
+
+		return a
+	})
+
+	// Watch a suggested directory
+	win.DefineFunction("watch_directory", func(args ...*sciter.Value) *sciter.Value {
+		err = storage.AddDirectory(args[0].String())
+		if err != nil {
+			log.Error().Err(err).Msgf("could not add folder to storage")
+			return nil
+		}
+
+		err = win.LoadFile("rice://resources/main.html")
+		if err != nil {
+			log.Error().Err(err).Msgf("could not load main.html")
+		}
+		return nil
+	})
+
 	win.DefineFunction("log", func(args ...*sciter.Value) *sciter.Value {
 		for _, arg := range args {
 			log.Info().Interface("v", arg.String()).Msg("")
@@ -0,0 +1,155 @@
+package inspect
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"sync/atomic"
+)
+
+// Largest edit distance that is searched for, beyond it lines are compared as sets which is fast but can not see moved
+// lines
+const churnMaxDistance = 4096
+
+// Fingerprint a file as a hash per line, so changes can be counted later without keeping the source. Trailing carriage
+// returns are left out, so changing line endings is not a change.
+func Fingerprint(path string) ([]uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Size() > atomic.LoadInt64(&maxFileSize) {
+		return nil, errors.New(fmt.Sprintf("file is larger than %d bytes, skip", atomic.LoadInt64(&maxFileSize)))
+	}
+
+	var result []uint64
+
+	reader := bufio.NewReader(file)
+	hash := fnv.New64a()
+	for {
+		// Long lines are hashed in parts
+		chunk, err := reader.ReadSlice('\n')
+		if err != nil && err != bufio.ErrBufferFull && err != io.EOF {
+			return nil, err
+		}
+
+		line := chunk
+		if err == nil {
+			line = trimLineEnding(line)
+		}
+
+		_, _ = hash.Write(line)
+
+		if err == nil || (err == io.EOF && len(chunk) > 0) {
+			result = append(result, hash.Sum64())
+			hash.Reset()
+		}
+
+		if err == io.EOF {
+			return result, nil
+		}
+	}
+}
+
+func trimLineEnding(line []byte) []byte {
+	line = line[:len(line)-1]
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		line = line[:len(line)-1]
+	}
+
+	return line
+}
+
+// Count the lines added and removed between two fingerprints of a file, a changed line is one of each
+func LineChurn(previous []uint64, current []uint64) (int, int) {
+	// Lines that did not change at the start and end are common, they do not need to be searched
+	for len(previous) > 0 && len(current) > 0 && previous[0] == current[0] {
+		previous, current = previous[1:], current[1:]
+	}
+
+	for len(previous) > 0 && len(current) > 0 && previous[len(previous)-1] == current[len(current)-1] {
+		previous, current = previous[:len(previous)-1], current[:len(current)-1]
+	}
+
+	distance, ok := editDistance(previous, current, churnMaxDistance)
+	if !ok {
+		return setChurn(previous, current)
+	}
+
+	// Lines in common are kept, the rest of the previous lines are removed and the rest of the current lines added
+	common := (len(previous) + len(current) - distance) / 2
+	return len(current) - common, len(previous) - common
+}
+
+// Find the number of lines to insert and delete to turn a into b, with the greedy algorithm of Myers, "An O(ND)
+// Difference Algorithm and Its Variations". It gives up when the distance is larger than max.
+func editDistance(a []uint64, b []uint64, max int) (int, bool) {
+	n, m := len(a), len(b)
+	if n+m < max {
+		max = n + m
+	}
+
+	// v[k+offset] is the furthest x on diagonal k
+	offset := max + 1
+	v := make([]int, 2*max+3)
+
+	for d := 0; d <= max; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[k-1+offset] < v[k+1+offset]) {
+				x = v[k+1+offset]
+			} else {
+				x = v[k-1+offset] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			v[k+offset] = x
+			if x >= n && y >= m {
+				return d, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// Count lines that are only in one of the fingerprints, for changes too large to search
+func setChurn(previous []uint64, current []uint64) (int, int) {
+	counts := make(map[uint64]int, len(previous))
+	for _, line := range previous {
+		counts[line]++
+	}
+
+	added := 0
+	for _, line := range current {
+		if counts[line] > 0 {
+			counts[line]--
+			continue
+		}
+
+		added++
+	}
+
+	removed := 0
+	for _, count := range counts {
+		removed += count
+	}
+
+	return added, removed
+}
@@ -0,0 +1,72 @@
+package inspect
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChurn_Fingerprint(t *testing.T) {
+	directory := t.TempDir()
+
+	fingerprint := func(content string) []uint64 {
+		path := filepath.Join(directory, "file.go")
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644), "writing file should not result in error")
+
+		result, err := Fingerprint(path)
+		assert.NoError(t, err, "fingerprinting should not result in error")
+		return result
+	}
+
+	unix := fingerprint("package main\n\nfunc main() {}\n")
+	assert.Len(t, unix, 3, "every line should have a hash")
+	assert.Equal(t, unix, fingerprint("package main\r\n\r\nfunc main() {}\r\n"), "line endings should not matter")
+	assert.Equal(t, unix, fingerprint("package main\n\nfunc main() {}"), "last line without line ending should count")
+	assert.Empty(t, fingerprint(""), "empty file should have no lines")
+
+	long := strings.Repeat("x", 10000)
+	lines := fingerprint(long + "\n" + long + "y\n" + long + "\n")
+	assert.Len(t, lines, 3, "long lines should be hashed as one line")
+	assert.Equal(t, lines[0], lines[2], "same long lines should have the same hash")
+	assert.NotEqual(t, lines[0], lines[1], "different long lines should have different hashes")
+}
+
+func TestChurn_LineChurn(t *testing.T) {
+	tests := []struct {
+		previous []uint64
+		current  []uint64
+		added    int
+		removed  int
+	}{
+		{nil, nil, 0, 0},
+		{[]uint64{1, 2, 3}, []uint64{1, 2, 3}, 0, 0},
+		{nil, []uint64{1, 2}, 2, 0},
+		{[]uint64{1, 2, 3}, []uint64{1, 3}, 0, 1},
+		{[]uint64{1, 2, 3}, []uint64{1, 4, 3}, 1, 1},
+		{[]uint64{1, 2, 3, 4}, []uint64{4, 1, 2, 3}, 1, 1},
+		{[]uint64{1, 2, 3, 4, 5}, []uint64{1, 6, 3, 7, 5, 8}, 3, 2},
+	}
+
+	for _, test := range tests {
+		added, removed := LineChurn(test.previous, test.current)
+		assert.Equal(t, test.added, added, "added lines of %v to %v", test.previous, test.current)
+		assert.Equal(t, test.removed, removed, "removed lines of %v to %v", test.previous, test.current)
+	}
+}
+
+func TestChurn_LargeChange(t *testing.T) {
+	var previous, current []uint64
+	for i := 0; i < churnMaxDistance; i++ {
+		previous = append(previous, uint64(i))
+		current = append(current, uint64(i+churnMaxDistance))
+	}
+
+	_, ok := editDistance(previous, current, churnMaxDistance)
+	assert.False(t, ok, "distance beyond the maximum should not be searched")
+
+	added, removed := LineChurn(previous, current)
+	assert.Equal(t, churnMaxDistance, added, "rewritten file should count every line as added")
+	assert.Equal(t, churnMaxDistance, removed, "rewritten file should count every line as removed")
+}
@@ -0,0 +1,148 @@
+package inspect
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Name of the file at the root of a repository that holds its tracking policy
+const ProjectConfigName = ".pacerank.yml"
+
+// How much of a project is sent. Without file names the files of a project are not told, anonymous projects do not tell
+// their files, branch or git remote either.
+const (
+	PrivacyFull        = "full"
+	PrivacyNoFilenames = "no_filenames"
+	PrivacyAnonymous   = "anonymous"
+)
+
+// ErrProjectIgnored is returned for files that the .pacerank.yml of their project leaves out
+var ErrProjectIgnored = errors.New(fmt.Sprintf("file is ignored by %s of its project", ProjectConfigName))
+
+// ProjectConfig is the tracking policy a team commits to a repository, as in
+//
+//	name: Client
+//	ignore: ["testdata/**", "*.pb.go"]
+//	tags: [backend, open-source]
+//	privacy: no_filenames
+//	opt_out: false
+//
+// Ignore holds globs in .gitignore syntax, relative to the root of the repository.
+type ProjectConfig struct {
+	Name    string   `yaml:"name"`
+	Ignore  []string `yaml:"ignore"`
+	Tags    []string `yaml:"tags"`
+	Privacy string   `yaml:"privacy"`
+	OptOut  bool     `yaml:"opt_out"`
+
+	ignore []gitignore.Pattern
+}
+
+// Configs by repository root, they are read again when the file changes
+var projectConfigs = struct {
+	mu    sync.Mutex
+	cache map[string]projectConfigState
+}{cache: make(map[string]projectConfigState)}
+
+type projectConfigState struct {
+	modified time.Time
+	config   ProjectConfig
+	err      error
+}
+
+// Read the config of the repository at root, a repository without one has the default config
+func LoadProjectConfig(root string) (ProjectConfig, error) {
+	path := filepath.Join(root, ProjectConfigName)
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return ProjectConfig{Privacy: PrivacyFull}, nil
+	}
+
+	if err != nil {
+		return ProjectConfig{}, err
+	}
+
+	projectConfigs.mu.Lock()
+	defer projectConfigs.mu.Unlock()
+
+	if state, ok := projectConfigs.cache[root]; ok && state.modified.Equal(info.ModTime()) {
+		return state.config, state.err
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ProjectConfig{}, err
+	}
+
+	config, err := ParseProjectConfig(b)
+	projectConfigs.cache[root] = projectConfigState{modified: info.ModTime(), config: config, err: err}
+	return config, err
+}
+
+// Parse a .pacerank.yml, unknown privacy levels are refused so a typo does not send more than intended
+func ParseProjectConfig(b []byte) (ProjectConfig, error) {
+	var config ProjectConfig
+	err := yaml.UnmarshalStrict(b, &config)
+	if err != nil {
+		return ProjectConfig{}, errors.New(fmt.Sprintf("could not parse %s: %s", ProjectConfigName, err))
+	}
+
+	switch config.Privacy {
+	case "":
+		config.Privacy = PrivacyFull
+	case PrivacyFull, PrivacyNoFilenames, PrivacyAnonymous:
+	default:
+		return ProjectConfig{}, errors.New(fmt.Sprintf("unknown privacy %q in %s", config.Privacy, ProjectConfigName))
+	}
+
+	for _, glob := range config.Ignore {
+		config.ignore = append(config.ignore, gitignore.ParsePattern(glob, nil))
+	}
+
+	return config, nil
+}
+
+// Tell if a file of the project is left out, relative is the path from the root of the repository
+func (c ProjectConfig) Ignored(relative string) bool {
+	if c.OptOut {
+		return true
+	}
+
+	parts := strings.Split(strings.Trim(filepath.ToSlash(relative), "/"), "/")
+	for _, pattern := range c.ignore {
+		if pattern.Match(parts, false) == gitignore.Exclude {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Apply the config to the project of a file
+func (c ProjectConfig) apply(pi *ProjectInfo) {
+	if c.Name != "" {
+		pi.Project = c.Name
+	}
+
+	pi.Tags = c.Tags
+	pi.Privacy = c.Privacy
+
+	switch c.Privacy {
+	case PrivacyAnonymous:
+		pi.Git = ""
+		pi.Branch = ""
+		fallthrough
+	case PrivacyNoFilenames:
+		pi.FilePath = ""
+		pi.FileName = ""
+	}
+}
@@ -0,0 +1,83 @@
+package inspect
+
+import (
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfig_Parse(t *testing.T) {
+	config, err := ParseProjectConfig([]byte(`
+name: Client
+ignore: ["testdata/", "*.pb.go"]
+tags: [backend, open-source]
+privacy: no_filenames
+`))
+	assert.NoError(t, err, "parsing config should not result in error")
+	assert.Equal(t, "Client", config.Name)
+	assert.Equal(t, []string{"backend", "open-source"}, config.Tags)
+	assert.Equal(t, PrivacyNoFilenames, config.Privacy)
+	assert.False(t, config.OptOut)
+
+	assert.True(t, config.Ignored("/api/service.pb.go"), "file matching a glob should be ignored")
+	assert.True(t, config.Ignored("/testdata/input.go"), "file in an ignored directory should be ignored")
+	assert.False(t, config.Ignored("/api/service.go"), "other files should not be ignored")
+
+	config, err = ParseProjectConfig([]byte("opt_out: true\n"))
+	assert.NoError(t, err, "parsing opt out should not result in error")
+	assert.Equal(t, PrivacyFull, config.Privacy, "privacy should be full by default")
+	assert.True(t, config.Ignored("/main.go"), "every file of an opted out project should be ignored")
+
+	_, err = ParseProjectConfig([]byte("privacy: secret\n"))
+	assert.Error(t, err, "unknown privacy should result in error")
+
+	_, err = ParseProjectConfig([]byte("opt-out: true\n"))
+	assert.Error(t, err, "unknown setting should result in error")
+}
+
+func TestConfig_Project(t *testing.T) {
+	root := t.TempDir()
+	_, err := git.PlainInit(root, false)
+	assert.NoError(t, err, "creating repository should not result in error")
+
+	path := filepath.Join(root, "api", "main.go")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755), "creating directory should not result in error")
+
+	writeConfig := func(content string) {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(root, ProjectConfigName), []byte(content), 0644),
+			"writing config should not result in error")
+
+		// The config is cached by modification time, which may not have moved on a fast file system
+		projectConfigs.mu.Lock()
+		delete(projectConfigs.cache, root)
+		projectConfigs.mu.Unlock()
+	}
+
+	pi, err := Project(path, root)
+	assert.NoError(t, err, "project without config should not result in error")
+	assert.Equal(t, filepath.Base(root), pi.Project)
+	assert.Equal(t, PrivacyFull, pi.Privacy)
+
+	writeConfig("name: Client\ntags: [backend]\nprivacy: anonymous\nignore: [\"*.pb.go\"]\n")
+
+	pi, err = Project(path, root)
+	assert.NoError(t, err, "project with config should not result in error")
+	assert.Equal(t, "Client", pi.Project, "name should be overridden")
+	assert.Equal(t, []string{"backend"}, pi.Tags)
+	assert.Empty(t, pi.FilePath, "anonymous project should not tell its files")
+	assert.Empty(t, pi.Branch, "anonymous project should not tell its branch")
+
+	_, err = Project(filepath.Join(root, "api", "service.pb.go"), root)
+	assert.Equal(t, ErrProjectIgnored, err, "ignored file should be left out")
+
+	writeConfig("opt_out: true\n")
+	_, err = Project(path, root)
+	assert.Equal(t, ErrProjectIgnored, err, "opted out project should be left out")
+
+	writeConfig("privacy: [\n")
+	_, err = Project(path, root)
+	assert.Error(t, err, "broken config should leave the project out")
+}
@@ -1 +1,325 @@
 package inspect
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/pacerank/client/pkg/system"
+	"github.com/rs/zerolog/log"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Editors that are known out of the box
+//
+//go:embed editors.json
+var embeddedEditors []byte
+
+// Name of the file in ~/.pacerank that extends or overrides the known editors
+const editorsFileName = "editors.json"
+
+// EditorDefinition describes how an editor is recognised. Executables are base names of the executable and patterns
+// are regular expressions matched against the whole base name, both per operating system as in runtime.GOOS.
+// Applications are ids of the installed application, like the desktop entry id, window class or Flatpak app id.
+// Aliases are other names the editor is known by, like the name an editor plugin reports. Version tells how the
+// version of the editor is found, see EditorVersion. Workspaces are paths of the files where the editor keeps the
+// projects it has open, they may start with ~ and hold environment variables and wildcards, see Workspaces. Titles are
+// regular expressions matched against the whole window title, with the named groups file, project and directory, see
+// EditorTitle.
+type EditorDefinition struct {
+	Name         string              `json:"name"`
+	Aliases      []string            `json:"aliases"`
+	Executables  map[string][]string `json:"executables"`
+	Patterns     map[string][]string `json:"patterns"`
+	Applications map[string][]string `json:"applications"`
+	Version      map[string]string   `json:"version"`
+	Workspaces   map[string][]string `json:"workspaces"`
+	Titles       []string            `json:"titles"`
+	Disabled     bool                `json:"disabled"`
+}
+
+type editorDefinitions struct {
+	Editors []EditorDefinition `json:"editors"`
+}
+
+// EditorRegistry recognises editors from their executable for one operating system
+type EditorRegistry struct {
+	goos    string
+	editors []registryEditor
+}
+
+type registryEditor struct {
+	definition EditorDefinition
+	patterns   []*regexp.Regexp
+	titles     []*regexp.Regexp
+}
+
+var (
+	defaultRegistryOnce sync.Once
+	defaultRegistry     *EditorRegistry
+)
+
+// Find the editor that given executable belongs to, using the editors of ~/.pacerank/editors.json before the ones
+// that are known out of the box. The application is used first when it is known, as packaged editors often run from
+// wrappers and bundled runtimes.
+func Editor(executable string, application *system.Application) (string, bool) {
+	registry := editorRegistry()
+
+	if application != nil {
+		if editor, ok := registry.MatchApplication(application); ok {
+			return editor, true
+		}
+	}
+
+	return registry.Match(executable)
+}
+
+// Find the name of an editor from one of its names or aliases
+func EditorByAlias(alias string) (string, bool) {
+	return editorRegistry().Alias(alias)
+}
+
+func editorRegistry() *EditorRegistry {
+	defaultRegistryOnce.Do(func() {
+		var overrides []byte
+
+		usr, err := user.Current()
+		if err == nil {
+			overrides, err = ioutil.ReadFile(path.Join(usr.HomeDir, ".pacerank", editorsFileName))
+		}
+
+		if err != nil && !os.IsNotExist(err) {
+			log.Error().Err(err).Msg("could not read editor overrides")
+		}
+
+		defaultRegistry, err = NewEditorRegistry(runtime.GOOS, embeddedEditors, overrides)
+		if err != nil {
+			log.Error().Err(err).Msg("could not load editor overrides, only known editors are used")
+
+			defaultRegistry, err = NewEditorRegistry(runtime.GOOS, embeddedEditors, nil)
+			if err != nil {
+				log.Fatal().Err(err).Msg("could not load known editors")
+			}
+		}
+	})
+
+	return defaultRegistry
+}
+
+// Create a registry from the known editors and the overrides of the user. An override with the same name or alias as
+// a known editor replaces it, and removes it when disabled. Overrides are matched first.
+func NewEditorRegistry(goos string, known []byte, overrides []byte) (*EditorRegistry, error) {
+	var knownDefinitions editorDefinitions
+	err := json.Unmarshal(known, &knownDefinitions)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("could not parse known editors: %s", err))
+	}
+
+	var overrideDefinitions editorDefinitions
+	if len(overrides) > 0 {
+		err = json.Unmarshal(overrides, &overrideDefinitions)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("could not parse %s: %s", editorsFileName, err))
+		}
+	}
+
+	registry := &EditorRegistry{goos: goos}
+
+	var definitions []EditorDefinition
+	for _, definition := range overrideDefinitions.Editors {
+		if definition.Name == "" {
+			return nil, errors.New(fmt.Sprintf("editor in %s is missing a name", editorsFileName))
+		}
+
+		definitions = append(definitions, definition)
+	}
+
+	for _, definition := range knownDefinitions.Editors {
+		overridden := false
+		for _, override := range overrideDefinitions.Editors {
+			if sameEditor(definition, override) {
+				overridden = true
+				break
+			}
+		}
+
+		if !overridden {
+			definitions = append(definitions, definition)
+		}
+	}
+
+	for _, definition := range definitions {
+		if definition.Disabled {
+			continue
+		}
+
+		editor := registryEditor{definition: definition}
+		for _, pattern := range definition.Patterns[goos] {
+			expression, err := regexp.Compile("^(?:" + pattern + ")$")
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("invalid pattern %q for %s: %s", pattern, definition.Name, err))
+			}
+
+			editor.patterns = append(editor.patterns, expression)
+		}
+
+		for _, title := range definition.Titles {
+			expression, err := regexp.Compile("^(?:" + title + ")$")
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("invalid title %q for %s: %s", title, definition.Name, err))
+			}
+
+			if expression.SubexpIndex("file") < 0 {
+				return nil, errors.New(fmt.Sprintf("title %q for %s is missing the file group", title, definition.Name))
+			}
+
+			editor.titles = append(editor.titles, expression)
+		}
+
+		registry.editors = append(registry.editors, editor)
+	}
+
+	return registry, nil
+}
+
+// Find the editor of an executable by its base name. Names are case insensitive on Windows, like the file system.
+func (r *EditorRegistry) Match(executable string) (string, bool) {
+	name := executable[strings.LastIndexAny(executable, `/\`)+1:]
+	if name == "" {
+		return "", false
+	}
+
+	for _, editor := range r.editors {
+		for _, candidate := range editor.definition.Executables[r.goos] {
+			if candidate == name || (r.goos == "windows" && strings.EqualFold(candidate, name)) {
+				return editor.definition.Name, true
+			}
+		}
+
+		for _, pattern := range editor.patterns {
+			if pattern.MatchString(name) {
+				return editor.definition.Name, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// Find the editor of an installed application by its ids, or by its name when it is the name of an editor
+func (r *EditorRegistry) MatchApplication(application *system.Application) (string, bool) {
+	ids := []string{application.ID, application.WMClass, application.Flatpak, application.Snap}
+
+	for _, editor := range r.editors {
+		for _, candidate := range editor.definition.Applications[r.goos] {
+			for _, id := range ids {
+				if id != "" && strings.EqualFold(candidate, id) {
+					return editor.definition.Name, true
+				}
+			}
+		}
+	}
+
+	return r.Alias(application.Name)
+}
+
+// Find the editor by its name or one of its aliases, ignoring case
+func (r *EditorRegistry) Alias(alias string) (string, bool) {
+	for _, editor := range r.editors {
+		for _, name := range editorNames(editor.definition) {
+			if strings.EqualFold(name, alias) {
+				return editor.definition.Name, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// Tell how the version of an editor is found, empty when it is not known
+func (r *EditorRegistry) VersionMethod(name string) string {
+	for _, editor := range r.editors {
+		if editor.definition.Name == name {
+			return editor.definition.Version[r.goos]
+		}
+	}
+
+	return ""
+}
+
+// Paths of the files where an editor keeps the projects it has open
+func (r *EditorRegistry) WorkspaceFiles(name string) []string {
+	for _, editor := range r.editors {
+		if editor.definition.Name == name {
+			return editor.definition.Workspaces[r.goos]
+		}
+	}
+
+	return nil
+}
+
+// Names of all editors, overrides first
+func (r *EditorRegistry) Names() []string {
+	var result []string
+	for _, editor := range r.editors {
+		result = append(result, editor.definition.Name)
+	}
+
+	return result
+}
+
+// Read the file and project from the window title of an editor, the first title expression that matches is used
+func (r *EditorRegistry) ParseTitle(name string, title string) (WindowTitle, bool) {
+	for _, editor := range r.editors {
+		if editor.definition.Name != name {
+			continue
+		}
+
+		for _, expression := range editor.titles {
+			match := expression.FindStringSubmatch(title)
+			if match == nil {
+				continue
+			}
+
+			group := func(name string) string {
+				if i := expression.SubexpIndex(name); i >= 0 {
+					return strings.TrimSpace(match[i])
+				}
+
+				return ""
+			}
+
+			result := WindowTitle{File: group("file"), Project: group("project"), Directory: group("directory")}
+			if result.File == "" {
+				continue
+			}
+
+			return result, true
+		}
+	}
+
+	return WindowTitle{}, false
+}
+
+func sameEditor(a EditorDefinition, b EditorDefinition) bool {
+	for _, x := range editorNames(a) {
+		for _, y := range editorNames(b) {
+			if strings.EqualFold(x, y) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func editorNames(definition EditorDefinition) []string {
+	return append([]string{definition.Name}, definition.Aliases...)
+}
@@ -0,0 +1,91 @@
+package inspect
+
+import (
+	"github.com/pacerank/client/pkg/system"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestEditor_KnownEditors(t *testing.T) {
+	tests := []struct {
+		goos       string
+		executable string
+		editor     string
+	}{
+		{"linux", "/usr/share/code/code", "Visual Studio Code"},
+		{"linux", "/usr/bin/nvim", "Neovim"},
+		{"linux", "/usr/bin/vim.basic", "VIM"},
+		{"linux", "/usr/bin/emacs-27.1", "Emacs"},
+		{"linux", "/home/atom/projects/bin/bash", ""},
+		{"linux", "/usr/bin/vimdiff", ""},
+		{"windows", `C:\Program Files\JetBrains\GoLand\bin\goland64.exe`, "GoLand"},
+		{"windows", `C:\Program Files\Microsoft VS Code\code.exe`, "Visual Studio Code"},
+		{"windows", `C:\tools\neovim\bin\nvim.exe`, "Neovim"},
+		{"windows", `C:\Users\atom\AppData\Local\explorer.exe`, ""},
+	}
+
+	registries := map[string]*EditorRegistry{}
+	for _, goos := range []string{"linux", "windows"} {
+		registry, err := NewEditorRegistry(goos, embeddedEditors, nil)
+		assert.NoError(t, err, "loading known editors should not result in error")
+		registries[goos] = registry
+	}
+
+	for _, test := range tests {
+		editor, ok := registries[test.goos].Match(test.executable)
+		assert.Equal(t, test.editor, editor, test.executable)
+		assert.Equal(t, test.editor != "", ok, test.executable)
+	}
+}
+
+func TestEditor_Overrides(t *testing.T) {
+	overrides := []byte(`{
+		"editors": [
+			{"name": "Kakoune", "aliases": ["kak"], "executables": {"linux": ["kak"]}},
+			{"name": "Code", "aliases": ["vscode"], "executables": {"linux": ["code"]}, "patterns": {"linux": ["code-\\d+"]}},
+			{"name": "Atom", "disabled": true}
+		]
+	}`)
+
+	registry, err := NewEditorRegistry("linux", embeddedEditors, overrides)
+	assert.NoError(t, err, "loading overrides should not result in error")
+
+	editor, _ := registry.Match("/usr/bin/kak")
+	assert.Equal(t, "Kakoune", editor, "new editor should be added")
+
+	editor, _ = registry.Match("/opt/code-2/code-2")
+	assert.Equal(t, "Code", editor, "override should replace the known editor with the same alias")
+
+	_, ok := registry.Match("/usr/share/code/code-oss")
+	assert.False(t, ok, "replaced editor should not match its old executables")
+
+	_, ok = registry.Match("/usr/bin/atom")
+	assert.False(t, ok, "disabled editor should not match")
+
+	editor, _ = registry.Alias("KAK")
+	assert.Equal(t, "Kakoune", editor, "alias should be found ignoring case")
+
+	_, err = NewEditorRegistry("linux", embeddedEditors, []byte(`{"editors": [{"name": "Broken", "patterns": {"linux": ["("]}}]}`))
+	assert.Error(t, err, "invalid pattern should result in error")
+}
+
+func TestEditor_MatchApplication(t *testing.T) {
+	registry, err := NewEditorRegistry("linux", embeddedEditors, nil)
+	assert.NoError(t, err, "loading known editors should not result in error")
+
+	tests := []struct {
+		application system.Application
+		editor      string
+	}{
+		{system.Application{ID: "jetbrains-goland-1a2b3c", Name: "GoLand 2020.2", WMClass: "jetbrains-goland"}, "GoLand"},
+		{system.Application{ID: "com.visualstudio.code", Name: "Visual Studio Code", Flatpak: "com.visualstudio.code"}, "Visual Studio Code"},
+		{system.Application{ID: "code_code", Name: "Visual Studio Code", Snap: "code"}, "Visual Studio Code"},
+		{system.Application{ID: "org.gnome.Terminal", Name: "Terminal"}, ""},
+		{system.Application{ID: "my-vim", Name: "VIM"}, "VIM"},
+	}
+
+	for _, test := range tests {
+		editor, _ := registry.MatchApplication(&test.application)
+		assert.Equal(t, test.editor, editor, test.application.ID)
+	}
+}
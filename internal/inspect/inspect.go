@@ -2,56 +2,241 @@ package inspect
 
 import (
 	"errors"
+	"fmt"
 	"github.com/go-enry/go-enry/v2"
+	"io"
 	"io/ioutil"
+	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
-func AnalyzeFile(path string, filename string) (string, error) {
-	b, err := ioutil.ReadFile(path)
+// Files are analyzed from their first bytes only, enough for shebangs, modelines and the classifier
+const analyzePrefixSize = 64 * 1024
+
+// DefaultMaxFileSize is the size of the largest file that is analyzed unless SetMaxFileSize tells otherwise
+const DefaultMaxFileSize = 4 * 1024 * 1024
+
+// How many analyzed files are remembered, the cache starts over when it is full
+const analyzeCacheSize = 4096
+
+var maxFileSize int64 = DefaultMaxFileSize
+
+// Languages and roles by path, size and modification time of the file, failures are cached as well
+var analyzed = struct {
+	mu    sync.Mutex
+	cache map[string]analyzeResult
+}{cache: make(map[string]analyzeResult)}
+
+type analyzeResult struct {
+	language string
+	role     string
+	err      error
+}
+
+// Set the size of the largest file that is analyzed, larger files are skipped without being read
+func SetMaxFileSize(size int64) {
+	atomic.StoreInt64(&maxFileSize, size)
+}
+
+// Find the language and role of a saved file. Files are skipped by their path before they are read, and only the start
+// of a file is read. The result is cached until the size or modification time of the file changes.
+func AnalyzeFile(path string, filename string) (string, string, error) {
+	if enry.IsImage(path) {
+		return "", "", errors.New("file is image, skip")
+	}
+
+	err := skipPath(path, filename)
 	if err != nil {
-		return "", err
+		return "", "", err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	if !info.Mode().IsRegular() {
+		return "", "", errors.New("file is not a regular file, skip")
+	}
+
+	if info.Size() > atomic.LoadInt64(&maxFileSize) {
+		return "", "", errors.New(fmt.Sprintf("file is larger than %d bytes, skip", atomic.LoadInt64(&maxFileSize)))
+	}
+
+	key := fmt.Sprintf("%s\x00%d\x00%d", path, info.Size(), info.ModTime().UnixNano())
+
+	analyzed.mu.Lock()
+	result, ok := analyzed.cache[key]
+	analyzed.mu.Unlock()
+
+	if ok {
+		return result.language, result.role, result.err
+	}
+
+	result.language, result.role, result.err = analyzeContent(path, filename)
+	if result.err != nil && os.IsNotExist(result.err) {
+		return result.language, result.role, result.err
+	}
+
+	analyzed.mu.Lock()
+	if len(analyzed.cache) >= analyzeCacheSize {
+		analyzed.cache = make(map[string]analyzeResult)
+	}
+
+	analyzed.cache[key] = result
+	analyzed.mu.Unlock()
+
+	return result.language, result.role, result.err
+}
+
+func analyzeContent(path string, filename string) (string, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	defer file.Close()
+
+	b, err := ioutil.ReadAll(io.LimitReader(file, analyzePrefixSize))
+	if err != nil {
+		return "", "", err
+	}
+
+	if enry.IsBinary(b) {
+		return "", "", errors.New("file is binary, skip")
+	}
+
+	lang := enry.GetLanguage(filename, b)
+	role := FileRole(path, lang, b)
+
+	err = checkLanguage(lang, role)
+	if err != nil {
+		return "", "", err
+	}
+
+	return strings.ToLower(lang), role, nil
+}
+
+// Plain text is only counted when it is documentation, like a README or a changelog
+func checkLanguage(lang string, role string) error {
+	if lang == "Text" && role != RoleDocumentation {
+		return errors.New("file is text, skip")
+	}
+
+	if lang == "" {
+		return errors.New("could not determine language, skip")
 	}
 
+	return nil
+}
+
+// Find the language of a file from its name only, for files that are being edited but not saved yet
+func LanguageByName(path string, filename string) (string, error) {
 	if enry.IsImage(path) {
 		return "", errors.New("file is image, skip")
 	}
 
-	if enry.IsBinary(b) {
-		return "", errors.New("file is binary, skip")
+	err := skipPath(path, filename)
+	if err != nil {
+		return "", err
 	}
 
-	if enry.IsConfiguration(path) {
-		return "", errors.New("file is configuration, skip")
+	lang, ok := enry.GetLanguageByFilename(filename)
+	if !ok {
+		lang = languageByExtension(filename)
 	}
 
-	if enry.IsDocumentation(path) {
-		return "", errors.New("file is documentation, skip")
+	if lang == "" {
+		return "", errors.New("could not determine language from name, skip")
 	}
 
-	if enry.IsDotFile(path) {
-		return "", errors.New("file is dotfile, skip")
+	err = checkLanguage(lang, FileRole(path, lang, nil))
+	if err != nil {
+		return "", err
 	}
 
-	if enry.IsVendor(path) {
-		return "", errors.New("file is vendor, skip")
+	return strings.ToLower(lang), nil
+}
+
+// Without content the most common language for the extension is picked. XML shares the extension of languages like
+// Rust and TypeScript, an editor is more likely to have main.rs open as Rust so XML is left out of the guess.
+func languageByExtension(filename string) string {
+	candidates := enry.GetLanguagesByExtension(filename, nil, nil)
+
+	var written []string
+	for _, candidate := range candidates {
+		if candidate != "XML" {
+			written = append(written, candidate)
+		}
 	}
 
-	if IsIgnored(path, filename) {
-		return "", errors.New("matches file ignore pattern, skip")
+	if len(written) == 0 || len(written) == len(candidates) {
+		return enry.GetLanguage(filename, nil)
 	}
 
-	lang := strings.ToLower(enry.GetLanguage(filename, b))
+	lang, _ := enry.GetLanguageByClassifier(nil, written)
+	return lang
+}
 
-	if lang == "Text" {
-		return lang, errors.New("file is text, skip")
+// Find the language of a file from the file type an editor gave it, like the filetype of Vim or the major mode of
+// Emacs without -mode. The name of the file is used when the file type is not the alias of a language.
+func LanguageByFileType(path string, filename string, fileType string) (string, error) {
+	lang, ok := enry.GetLanguageByAlias(fileType)
+	if fileType == "" || !ok || lang == "Text" {
+		return LanguageByName(path, filename)
 	}
 
-	if lang == "" {
-		return lang, errors.New("could not determine language, skip")
+	err := skipPath(path, filename)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ToLower(lang), nil
+}
+
+// Find the language of a file an editor plugin names the language of. The file is skipped like it is by
+// LanguageByName, the language of the plugin only replaces the language found from the name.
+func LanguageByPlugin(path string, filename string, language string) (string, error) {
+	if language == "" {
+		return LanguageByName(path, filename)
+	}
+
+	if enry.IsImage(path) {
+		return "", errors.New("file is image, skip")
+	}
+
+	err := skipPath(path, filename)
+	if err != nil {
+		return "", err
+	}
+
+	lang := language
+	if alias, ok := enry.GetLanguageByAlias(language); ok {
+		lang = alias
+	}
+
+	err = checkLanguage(lang, FileRole(path, lang, nil))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ToLower(language), nil
+}
+
+// Skip files that are not written in the project from their path alone, documentation and configuration are given
+// their role instead
+func skipPath(path string, filename string) error {
+	if enry.IsVendor(path) {
+		return errors.New("file is vendor, skip")
+	}
+
+	if IsIgnored(path, filename) {
+		return errors.New("matches file ignore pattern, skip")
 	}
 
-	return lang, nil
+	return nil
 }
 
 func IsIgnored(path, filename string) bool {
@@ -59,7 +244,8 @@ func IsIgnored(path, filename string) bool {
 		return true
 	}
 
-	if filename[len(filename):] == "~" {
+	// Backup files of editors like Emacs and Vim
+	if strings.HasSuffix(filename, "~") {
 		return true
 	}
 
@@ -0,0 +1,126 @@
+package inspect
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInspect_AnalyzeFile(t *testing.T) {
+	directory := t.TempDir()
+
+	write := func(name string, content string) string {
+		path := filepath.Join(directory, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755), "creating directory should not result in error")
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644), "writing file should not result in error")
+		return path
+	}
+
+	path := write("main.go", "package main\n\nfunc main() {}\n")
+	language, role, err := AnalyzeFile(path, "main.go")
+	assert.NoError(t, err, "analyzing go file should not result in error")
+	assert.Equal(t, "go", language)
+	assert.Equal(t, RoleSource, role)
+
+	path = write("run", "#!/usr/bin/env python3\nprint('hello')\n")
+	language, _, err = AnalyzeFile(path, "run")
+	assert.NoError(t, err, "analyzing script should not result in error")
+	assert.Equal(t, "python", language, "language should be found from the shebang")
+
+	path = write("data.bin", "\x00\x01\x02\x03")
+	_, _, err = AnalyzeFile(path, "data.bin")
+	assert.Error(t, err, "binary file should be skipped")
+
+	path = write("README.md", "# Client\n")
+	language, role, err = AnalyzeFile(path, "README.md")
+	assert.NoError(t, err, "analyzing documentation should not result in error")
+	assert.Equal(t, "markdown", language)
+	assert.Equal(t, RoleDocumentation, role)
+
+	path = write("notes.txt", "remember the milk\n")
+	_, _, err = AnalyzeFile(path, "notes.txt")
+	assert.Error(t, err, "text that is not documentation should be skipped")
+
+	_, _, err = AnalyzeFile(filepath.Join(directory, "vendor", "missing.go"), "missing.go")
+	assert.Error(t, err, "vendored file should be skipped before it is read")
+	assert.False(t, os.IsNotExist(err), "vendored file should be skipped by its path")
+
+	_, _, err = AnalyzeFile(filepath.Join(directory, "main.go~"), "main.go~")
+	assert.Error(t, err, "backup file should be skipped before it is read")
+	assert.False(t, os.IsNotExist(err), "backup file should be skipped by its name")
+
+	_, _, err = AnalyzeFile(filepath.Join(directory, "missing.go"), "missing.go")
+	assert.True(t, os.IsNotExist(err), "missing file should result in error")
+}
+
+func TestInspect_AnalyzeFileMaxSize(t *testing.T) {
+	defer SetMaxFileSize(DefaultMaxFileSize)
+
+	path := filepath.Join(t.TempDir(), "large.go")
+	assert.NoError(t, ioutil.WriteFile(path, []byte("package large\n"+strings.Repeat("// comment\n", 100)), 0644),
+		"writing file should not result in error")
+
+	SetMaxFileSize(100)
+	_, _, err := AnalyzeFile(path, "large.go")
+	assert.Error(t, err, "file larger than the maximum should be skipped")
+
+	SetMaxFileSize(DefaultMaxFileSize)
+	language, _, err := AnalyzeFile(path, "large.go")
+	assert.NoError(t, err, "file smaller than the maximum should not result in error")
+	assert.Equal(t, "go", language)
+}
+
+func TestInspect_AnalyzeFileCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script")
+	modified := time.Now().Add(-time.Hour)
+
+	assert.NoError(t, ioutil.WriteFile(path, []byte("#!/bin/bash\necho 1\n"), 0644), "writing file should not result in error")
+	assert.NoError(t, os.Chtimes(path, modified, modified), "setting time should not result in error")
+
+	language, _, err := AnalyzeFile(path, "script")
+	assert.NoError(t, err, "analyzing script should not result in error")
+	assert.Equal(t, "shell", language)
+
+	// Same size and time, so the file is not read again
+	assert.NoError(t, ioutil.WriteFile(path, []byte("#!/bin/perl\nwarn 1\n"), 0644), "writing file should not result in error")
+	assert.NoError(t, os.Chtimes(path, modified, modified), "setting time should not result in error")
+
+	language, _, _ = AnalyzeFile(path, "script")
+	assert.Equal(t, "shell", language, "language should be cached by size and time")
+
+	modified = modified.Add(time.Minute)
+	assert.NoError(t, os.Chtimes(path, modified, modified), "setting time should not result in error")
+
+	language, _, _ = AnalyzeFile(path, "script")
+	assert.Equal(t, "perl", language, "changed file should be analyzed again")
+}
+
+func TestInspect_LanguageByPlugin(t *testing.T) {
+	language, err := LanguageByPlugin("/home/user/client/lib.rs", "lib.rs", "Rust")
+	assert.NoError(t, err, "file with language of plugin should not result in error")
+	assert.Equal(t, "rust", language, "language of the plugin should be used")
+
+	language, err = LanguageByPlugin("/home/user/client/main.go", "main.go", "")
+	assert.NoError(t, err, "file without language of plugin should not result in error")
+	assert.Equal(t, "go", language, "language should be found from the name")
+
+	tests := []struct {
+		path     string
+		language string
+	}{
+		{"/home/user/client/node_modules/left-pad/index.js", "JavaScript"},
+		{"/home/user/client/vendor/github.com/pkg/errors/errors.go", "Go"},
+		{"/home/user/client/logo.png", "Image"},
+		{"/home/user/client/main.go~", "Go"},
+		{"/home/user/client/notes.txt", "Text"},
+	}
+
+	for _, test := range tests {
+		_, err := LanguageByPlugin(test.path, filepath.Base(test.path), test.language)
+		assert.Error(t, err, "%s should be skipped even when the plugin names its language", test.path)
+	}
+}
@@ -16,6 +16,10 @@ type ProjectInfo struct {
 	Branch   string
 	FileName string
 	FilePath string
+
+	// Set from the .pacerank.yml of the repository
+	Tags    []string
+	Privacy string
 }
 
 func Project(filePath, watcherPath string) (ProjectInfo, error) {
@@ -40,6 +44,18 @@ func Project(filePath, watcherPath string) (ProjectInfo, error) {
 			pi.Id = base64.StdEncoding.EncodeToString([]byte(filePath))
 			pi.FilePath = strings.Replace(originalFilePath, filePath, "", 1)
 			pi.FileName = filepath.Base(originalFilePath)
+
+			var config ProjectConfig
+			config, err = LoadProjectConfig(filePath)
+			if err != nil {
+				return pi, err
+			}
+
+			if config.Ignored(pi.FilePath) {
+				return pi, ErrProjectIgnored
+			}
+
+			config.apply(&pi)
 			break
 		}
 
@@ -0,0 +1,106 @@
+package inspect
+
+import (
+	"bytes"
+	"github.com/go-enry/go-enry/v2"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// The part a file plays in a project, writing tests or documentation is its own kind of activity
+const (
+	RoleSource         = "source"
+	RoleTest           = "test"
+	RoleDocumentation  = "documentation"
+	RoleConfiguration  = "configuration"
+	RoleInfrastructure = "infrastructure"
+	RoleGenerated      = "generated"
+	RoleNotebook       = "notebook"
+)
+
+// Test files of conventions that enry does not know
+var testPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(^|/)__tests__/`),
+	regexp.MustCompile(`(^|/)[^/]+_test\.(py|exs|dart|c|cc|cpp)$`),
+	regexp.MustCompile(`(^|/)[^/]+[._]spec\.(js|jsx|ts|tsx)$`),
+	regexp.MustCompile(`(^|/)[^/]+Tests?\.(java|kt|swift)$`),
+	regexp.MustCompile(`(^|/)tests/[^/]+\.rs$`),
+}
+
+// Generated files of conventions that enry does not know, or only knows from their content
+var generatedPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\.pb(\.gw)?\.go$`),
+	regexp.MustCompile(`_pb2(_grpc)?\.py$`),
+	regexp.MustCompile(`\.min\.(js|css)$`),
+	regexp.MustCompile(`\.(g|freezed)\.dart$`),
+	regexp.MustCompile(`(^|/)zz_generated[^/]*\.go$`),
+}
+
+// Files that set up where and how a project runs
+var infrastructureNames = map[string]bool{
+	"dockerfile":          true,
+	"containerfile":       true,
+	"docker-compose.yml":  true,
+	"docker-compose.yaml": true,
+	"chart.yaml":          true,
+	"kustomization.yaml":  true,
+	"kustomization.yml":   true,
+}
+
+var infrastructureLanguages = map[string]bool{
+	"Dockerfile": true,
+	"HCL":        true,
+}
+
+// Find the role of a file from its path and language, the content tells generated files and Kubernetes manifests apart
+// and may be nil when the file is not saved.
+func FileRole(path string, language string, content []byte) string {
+	path = filepath.ToSlash(path)
+	name := strings.ToLower(filepath.Base(path))
+
+	// Languages are given in lower case, enry knows them by their alias
+	if lang, ok := enry.GetLanguageByAlias(language); ok {
+		language = lang
+	}
+
+	switch {
+	case language == "Jupyter Notebook" || strings.HasSuffix(name, ".ipynb"):
+		return RoleNotebook
+	case enry.IsGenerated(path, content) || matchAny(generatedPatterns, path):
+		return RoleGenerated
+	case enry.IsTest(path) || matchAny(testPatterns, path):
+		return RoleTest
+	case infrastructureLanguages[language] || infrastructureNames[name] || strings.HasPrefix(name, "dockerfile."):
+		return RoleInfrastructure
+	case (language == "YAML" || strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml")) && isKubernetes(content):
+		return RoleInfrastructure
+	case enry.IsDocumentation(path) || (enry.GetLanguageType(language) == enry.Prose && language != "Text"):
+		return RoleDocumentation
+	case enry.IsConfiguration(path) || enry.IsDotFile(path):
+		return RoleConfiguration
+	}
+
+	return RoleSource
+}
+
+// Kubernetes manifests name the version of their API and their kind at the top level
+func isKubernetes(content []byte) bool {
+	var version, kind bool
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		version = version || bytes.HasPrefix(line, []byte("apiVersion:"))
+		kind = kind || bytes.HasPrefix(line, []byte("kind:"))
+	}
+
+	return version && kind
+}
+
+func matchAny(patterns []*regexp.Regexp, path string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(path) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,46 @@
+package inspect
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRole_FileRole(t *testing.T) {
+	cases := []struct {
+		path     string
+		language string
+		content  string
+		role     string
+	}{
+		{"/src/client/main.go", "go", "", RoleSource},
+		{"/src/client/main_test.go", "go", "", RoleTest},
+		{"/src/web/app.spec.ts", "typescript", "", RoleTest},
+		{"/src/web/__tests__/app.js", "javascript", "", RoleTest},
+		{"/src/tools/test_parse.py", "python", "", RoleTest},
+		{"/src/api/service.pb.go", "go", "", RoleGenerated},
+		{"/src/api/models.go", "go", "// Code generated by sqlc. DO NOT EDIT.\n\npackage api\n", RoleGenerated},
+		{"/src/client/README.md", "markdown", "", RoleDocumentation},
+		{"/src/client/docs/install.rst", "restructuredtext", "", RoleDocumentation},
+		{"/src/client/config.json", "json", "", RoleConfiguration},
+		{"/src/client/.eslintrc", "", "", RoleConfiguration},
+		{"/src/client/Dockerfile", "dockerfile", "", RoleInfrastructure},
+		{"/src/client/deploy/main.tf", "hcl", "", RoleInfrastructure},
+		{"/src/client/deploy/service.yaml", "yaml", "apiVersion: v1\nkind: Service\n", RoleInfrastructure},
+		{"/src/client/settings.yaml", "yaml", "debug: true\n", RoleConfiguration},
+		{"/src/analysis/explore.ipynb", "jupyter notebook", "", RoleNotebook},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.role, FileRole(c.path, c.language, []byte(c.content)), c.path)
+	}
+}
+
+func TestRole_LanguageByName(t *testing.T) {
+	language, err := LanguageByName("/src/client/main.rs", "main.rs")
+	assert.NoError(t, err, "finding language by name should not result in error")
+	assert.Equal(t, "rust", language, "programming language should be picked over data with the same extension")
+
+	language, err = LanguageByName("/src/client/CHANGELOG.md", "CHANGELOG.md")
+	assert.NoError(t, err, "finding language of documentation should not result in error")
+	assert.Equal(t, "markdown", language)
+}
@@ -0,0 +1,337 @@
+package inspect
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// This is a minimal read-only SQLite reader, it only implements what is needed to read every row of a table, like the
+// key-value table VS Code keeps its state in. Only the main database file is read, changes that are still in a
+// write-ahead log are not seen.
+// https://www.sqlite.org/fileformat.html
+
+// B-tree page types
+const (
+	sqliteInteriorTable = 0x05
+	sqliteLeafTable     = 0x0d
+)
+
+var errSQLiteCorrupt = errors.New("sqlite database is corrupt")
+
+type sqliteDB struct {
+	data     []byte
+	pageSize int
+	usable   int
+}
+
+func openSQLite(data []byte) (*sqliteDB, error) {
+	if len(data) < 100 || string(data[:16]) != "SQLite format 3\x00" {
+		return nil, errors.New("file is not a sqlite database")
+	}
+
+	pageSize := int(binary.BigEndian.Uint16(data[16:]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, errSQLiteCorrupt
+	}
+
+	if encoding := binary.BigEndian.Uint32(data[56:]); encoding > 1 {
+		return nil, errors.New(fmt.Sprintf("sqlite text encoding %d is not supported", encoding))
+	}
+
+	return &sqliteDB{
+		data:     data,
+		pageSize: pageSize,
+		usable:   pageSize - int(data[20]),
+	}, nil
+}
+
+// Find the root page of a table in the schema table, which is rooted at the first page
+func (db *sqliteDB) table(name string) (uint32, error) {
+	var root uint32
+
+	err := db.rows(1, func(values []interface{}) bool {
+		if len(values) < 4 || values[0] != "table" || values[1] != name {
+			return true
+		}
+
+		if page, ok := values[3].(int64); ok && page > 0 && page <= math.MaxUint32 {
+			root = uint32(page)
+		}
+
+		return false
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if root == 0 {
+		return 0, errors.New(fmt.Sprintf("sqlite database does not have table %s", name))
+	}
+
+	return root, nil
+}
+
+// Walk the rows of a table in order, until the callback returns false. Values are nil, int64, float64, string or
+// []byte.
+func (db *sqliteDB) rows(root uint32, c func(values []interface{}) bool) error {
+	_, err := db.walk(root, make(map[uint32]bool), c)
+	return err
+}
+
+// Every page of the tree is visited once, a page that is seen again means the pages refer to each other
+func (db *sqliteDB) walk(number uint32, visited map[uint32]bool, c func(values []interface{}) bool) (bool, error) {
+	if visited[number] {
+		return false, errSQLiteCorrupt
+	}
+
+	visited[number] = true
+
+	page, header, err := db.page(number)
+	if err != nil {
+		return false, err
+	}
+
+	if len(page) < header+8 {
+		return false, errSQLiteCorrupt
+	}
+
+	kind := page[header]
+	cells := int(binary.BigEndian.Uint16(page[header+3:]))
+
+	pointers := header + 8
+	if kind == sqliteInteriorTable {
+		pointers = header + 12
+	}
+
+	if len(page) < pointers+cells*2 {
+		return false, errSQLiteCorrupt
+	}
+
+	for i := 0; i < cells; i++ {
+		offset := int(binary.BigEndian.Uint16(page[pointers+i*2:]))
+		if offset >= db.usable {
+			return false, errSQLiteCorrupt
+		}
+
+		switch kind {
+		case sqliteInteriorTable:
+			if offset+4 > len(page) {
+				return false, errSQLiteCorrupt
+			}
+
+			more, err := db.walk(binary.BigEndian.Uint32(page[offset:]), visited, c)
+			if err != nil || !more {
+				return more, err
+			}
+		case sqliteLeafTable:
+			payload, err := db.payload(page[:db.usable], offset)
+			if err != nil {
+				return false, err
+			}
+
+			values, err := sqliteRecord(payload)
+			if err != nil {
+				return false, err
+			}
+
+			if !c(values) {
+				return false, nil
+			}
+		default:
+			return false, errors.New(fmt.Sprintf("sqlite page %d is not a table page", number))
+		}
+	}
+
+	if kind == sqliteInteriorTable {
+		return db.walk(binary.BigEndian.Uint32(page[header+8:]), visited, c)
+	}
+
+	return true, nil
+}
+
+// Get a page by its number, counted from 1, and where its b-tree header starts. The first page starts with the header
+// of the database.
+func (db *sqliteDB) page(number uint32) ([]byte, int, error) {
+	start := (int(number) - 1) * db.pageSize
+	if number == 0 || start+db.pageSize > len(db.data) {
+		return nil, 0, errSQLiteCorrupt
+	}
+
+	header := 0
+	if number == 1 {
+		header = 100
+	}
+
+	return db.data[start : start+db.pageSize], header, nil
+}
+
+// Read the payload of a table leaf cell, the part that does not fit in the page is in a chain of overflow pages
+func (db *sqliteDB) payload(page []byte, offset int) ([]byte, error) {
+	size, n := sqliteVarint(page[offset:])
+	if n == 0 {
+		return nil, errSQLiteCorrupt
+	}
+
+	offset += n
+
+	// Skip the rowid
+	_, n = sqliteVarint(page[offset:])
+	if n == 0 {
+		return nil, errSQLiteCorrupt
+	}
+
+	offset += n
+
+	if size > uint64(len(db.data)) {
+		return nil, errSQLiteCorrupt
+	}
+
+	total := int(size)
+	local := db.localPayload(total)
+	if offset+local > len(page) {
+		return nil, errSQLiteCorrupt
+	}
+
+	result := append(make([]byte, 0, total), page[offset:offset+local]...)
+	if local == total {
+		return result, nil
+	}
+
+	if offset+local+4 > len(page) {
+		return nil, errSQLiteCorrupt
+	}
+
+	next := binary.BigEndian.Uint32(page[offset+local:])
+	for len(result) < total {
+		overflow, _, err := db.page(next)
+		if err != nil {
+			return nil, err
+		}
+
+		chunk := overflow[4:db.usable]
+		if remaining := total - len(result); len(chunk) > remaining {
+			chunk = chunk[:remaining]
+		}
+
+		result = append(result, chunk...)
+		next = binary.BigEndian.Uint32(overflow)
+	}
+
+	return result, nil
+}
+
+// How much of a payload of the given size is kept in a table leaf page
+func (db *sqliteDB) localPayload(size int) int {
+	max := db.usable - 35
+	if size <= max {
+		return size
+	}
+
+	min := (db.usable-12)*32/255 - 23
+	local := min + (size-min)%(db.usable-4)
+	if local > max {
+		return min
+	}
+
+	return local
+}
+
+// Decode a record, a header of serial types followed by the values
+func sqliteRecord(payload []byte) ([]interface{}, error) {
+	headerSize, n := sqliteVarint(payload)
+	if n == 0 || headerSize < uint64(n) || headerSize > uint64(len(payload)) {
+		return nil, errSQLiteCorrupt
+	}
+
+	header := payload[n:headerSize]
+	body := payload[headerSize:]
+
+	var values []interface{}
+	for len(header) > 0 {
+		serial, n := sqliteVarint(header)
+		if n == 0 {
+			return nil, errSQLiteCorrupt
+		}
+
+		header = header[n:]
+
+		size := sqliteSerialSize(serial)
+		if size > len(body) {
+			return nil, errSQLiteCorrupt
+		}
+
+		value := body[:size]
+		body = body[size:]
+
+		switch {
+		case serial == 0:
+			values = append(values, nil)
+		case serial >= 1 && serial <= 6:
+			// Sign extend the big-endian integer
+			var v int64
+			if value[0]&0x80 != 0 {
+				v = -1
+			}
+
+			for _, b := range value {
+				v = v<<8 | int64(b)
+			}
+
+			values = append(values, v)
+		case serial == 7:
+			values = append(values, math.Float64frombits(binary.BigEndian.Uint64(value)))
+		case serial == 8 || serial == 9:
+			values = append(values, int64(serial-8))
+		case serial >= 12 && serial%2 == 0:
+			values = append(values, append([]byte(nil), value...))
+		case serial >= 13:
+			values = append(values, string(value))
+		default:
+			return nil, errSQLiteCorrupt
+		}
+	}
+
+	return values, nil
+}
+
+func sqliteSerialSize(serial uint64) int {
+	switch {
+	case serial >= 1 && serial <= 4:
+		return int(serial)
+	case serial == 5:
+		return 6
+	case serial == 6 || serial == 7:
+		return 8
+	case serial >= 12 && serial < math.MaxInt32:
+		return int(serial-12) / 2
+	}
+
+	return 0
+}
+
+// Read a variable length integer, returns the number of bytes read or 0 when b is too short
+func sqliteVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 9; i++ {
+		if i >= len(b) {
+			return 0, 0
+		}
+
+		if i == 8 {
+			return v<<8 | uint64(b[i]), 9
+		}
+
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+
+	return v, 9
+}
@@ -0,0 +1,108 @@
+package inspect
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+// WindowTitle is what an editor tells about the file being edited in its window title. The file can be a name or a
+// path, relative to the directory when the editor shows one.
+type WindowTitle struct {
+	File      string
+	Project   string
+	Directory string
+}
+
+// TitleActivity is the project, language and role of the file in a window title
+type TitleActivity struct {
+	ProjectInfo
+	Language string
+	Role     string
+}
+
+// Read the file and project from the window title of an editor
+func EditorTitle(editor string, title string) (WindowTitle, bool) {
+	return editorRegistry().ParseTitle(editor, title)
+}
+
+// Find the project and language of the file in a window title. The file does not need to be saved or be in a watched
+// folder.
+func TitleProject(title WindowTitle) (TitleActivity, error) {
+	var result TitleActivity
+
+	filePath := title.File
+	if title.Directory != "" && !isAbsolute(filePath) {
+		filePath = filepath.Join(title.Directory, filePath)
+	}
+
+	filePath = expandHome(filePath)
+
+	language, err := LanguageByName(filePath, filepath.Base(filePath))
+	if err != nil {
+		return result, err
+	}
+
+	result.Language = language
+	result.Role = FileRole(filePath, language, nil)
+	result.ProjectInfo, err = FileProject(filePath, title.Project)
+	return result, err
+}
+
+// ErrProjectUnknown is returned for files whose repository can not be looked up, like files that are named without
+// their directory. The .pacerank.yml of their project can not be read, so they are left out rather than reported.
+var ErrProjectUnknown = errors.New("repository of file can not be looked up to read its project configuration")
+
+// Find the project of a file that is named by an editor, from the repository on disk. A file outside of a repository is
+// known by given project name.
+func FileProject(filePath string, project string) (ProjectInfo, error) {
+	filePath = expandHome(filePath)
+
+	if !filepath.IsAbs(filePath) {
+		return ProjectInfo{}, ErrProjectUnknown
+	}
+
+	root := filepath.VolumeName(filePath) + string(os.PathSeparator)
+	pi, err := Project(filePath, root)
+	if err != nil || pi.Id != "no_project" {
+		return pi, err
+	}
+
+	// Files that are not on this machine, like files in a container, may well be in a repository
+	if _, err := os.Stat(filepath.Dir(filePath)); err != nil {
+		return ProjectInfo{}, ErrProjectUnknown
+	}
+
+	if project == "" {
+		return pi, nil
+	}
+
+	return ProjectInfo{
+		Id:       base64.StdEncoding.EncodeToString([]byte(project)),
+		Project:  project,
+		FileName: filepath.Base(filePath),
+		FilePath: filePath,
+		Privacy:  PrivacyFull,
+	}, nil
+}
+
+func isAbsolute(path string) bool {
+	return filepath.IsAbs(path) || path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`)
+}
+
+// Replace a leading ~ with the home directory of the user, as editors shorten paths in titles that way
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path
+	}
+
+	usr, err := user.Current()
+	if err != nil {
+		return path
+	}
+
+	return filepath.Join(usr.HomeDir, path[1:])
+}
@@ -0,0 +1,93 @@
+package inspect
+
+import (
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestTitle_ParseTitle(t *testing.T) {
+	tests := []struct {
+		editor string
+		title  string
+		parsed WindowTitle
+	}{
+		{"Visual Studio Code", "main.go - client - Visual Studio Code", WindowTitle{File: "main.go", Project: "client"}},
+		{"Visual Studio Code", "● watcher.go - client (Workspace) - Visual Studio Code", WindowTitle{File: "watcher.go", Project: "client"}},
+		{"Visual Studio Code", "main.go - Code - OSS", WindowTitle{File: "main.go"}},
+		{"IntelliJ IDEA", "client – watcher.go [client] – IntelliJ", WindowTitle{File: "watcher.go", Project: "client"}},
+		{"GoLand", "client – main.go", WindowTitle{File: "main.go", Project: "client"}},
+		{"GoLand", "client [~/src/client] - .../internal/watcher/watcher.go [client] - GoLand", WindowTitle{File: "internal/watcher/watcher.go", Project: "client", Directory: "~/src/client"}},
+		{"Sublime Text", "~/src/client/main.go • (client) - Sublime Text", WindowTitle{File: "~/src/client/main.go", Project: "client"}},
+		{"VIM", "watcher.go + (~/src/client/internal/watcher) - VIM", WindowTitle{File: "watcher.go", Directory: "~/src/client/internal/watcher"}},
+		{"Neovim", "main.go (~/src/client) - Nvim", WindowTitle{File: "main.go", Directory: "~/src/client"}},
+		{"Emacs", "main.go - GNU Emacs at workstation", WindowTitle{File: "main.go"}},
+	}
+
+	registry, err := NewEditorRegistry("linux", embeddedEditors, nil)
+	assert.NoError(t, err, "loading known editors should not result in error")
+
+	for _, test := range tests {
+		parsed, ok := registry.ParseTitle(test.editor, test.title)
+		assert.True(t, ok, test.title)
+		assert.Equal(t, test.parsed, parsed, test.title)
+	}
+
+	_, ok := registry.ParseTitle("Visual Studio Code", "Mozilla Firefox")
+	assert.False(t, ok, "title of another application should not be parsed")
+
+	_, ok = registry.ParseTitle("nano", "main.go")
+	assert.False(t, ok, "editor without titles should not be parsed")
+}
+
+func TestTitle_InvalidTitle(t *testing.T) {
+	_, err := NewEditorRegistry("linux", embeddedEditors, []byte(`{"editors": [{"name": "Kate", "titles": ["(?P<name>.+) — Kate"]}]}`))
+	assert.Error(t, err, "title without file group should result in error")
+}
+
+func TestTitle_TitleProject(t *testing.T) {
+	dir := t.TempDir()
+
+	activity, err := TitleProject(WindowTitle{File: "watcher.go", Project: "client", Directory: dir})
+	assert.NoError(t, err, "attributing title with project should not result in error")
+	assert.Equal(t, "go", activity.Language)
+	assert.Equal(t, "client", activity.Project)
+	assert.Equal(t, "watcher.go", activity.FileName)
+	assert.NotEmpty(t, activity.Id, "title project should have an id")
+
+	_, err = TitleProject(WindowTitle{File: "watcher.go", Project: "client"})
+	assert.Equal(t, ErrProjectUnknown, err, "title without a directory should be left out")
+
+	_, err = TitleProject(WindowTitle{File: "/remote/client/watcher.go", Project: "client"})
+	assert.Equal(t, ErrProjectUnknown, err, "file that is not on this machine should be left out")
+	activity, err = TitleProject(WindowTitle{File: "main.rs", Directory: dir})
+	assert.NoError(t, err, "attributing title with directory should not result in error")
+	assert.Equal(t, "rust", activity.Language)
+	assert.Equal(t, "no_project", activity.Id, "directory outside of a repository should not have a project")
+	assert.Equal(t, dir, filepath.Clean(activity.FilePath))
+
+	_, err = TitleProject(WindowTitle{File: "Welcome", Project: "client"})
+	assert.Error(t, err, "title without a language should result in error")
+
+	_, err = TitleProject(WindowTitle{File: "main.go"})
+	assert.Error(t, err, "title without project or directory should result in error")
+}
+
+func TestTitle_OptedOutProject(t *testing.T) {
+	root := t.TempDir()
+	_, err := git.PlainInit(root, false)
+	assert.NoError(t, err, "creating repository should not result in error")
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, ProjectConfigName), []byte("opt_out: true\n"), 0644),
+		"writing config should not result in error")
+
+	_, err = TitleProject(WindowTitle{File: "src/main.go", Project: filepath.Base(root)})
+	assert.Error(t, err, "relative file of an opted out project should be left out")
+
+	_, err = TitleProject(WindowTitle{File: "src/main.go", Project: filepath.Base(root), Directory: root})
+	assert.Equal(t, ErrProjectIgnored, err, "file of an opted out project should be left out")
+
+	_, err = FileProject("main.go", filepath.Base(root))
+	assert.Error(t, err, "heartbeat of a relative file should be left out")
+}
@@ -0,0 +1,183 @@
+package inspect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/pacerank/client/pkg/system"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// ErrNoEditorVersion is returned when there is no way to find the version of an editor
+var ErrNoEditorVersion = errors.New("version of editor can not be found")
+
+// Ways to find the version of an editor. JetBrains IDEs have product-info.json in the install root, Electron editors
+// have the package.json of the app in resources/app, and the rest print it when run with --version.
+const (
+	versionProductInfo = "product-info"
+	versionPackageJSON = "package-json"
+	versionCommand     = "command"
+)
+
+// How far up from the executable the version files are looked for, bundled runtimes are a few levels down
+const versionSearchDepth = 4
+
+// How long an editor may take to print its version
+const versionCommandTimeout = time.Second * 2
+
+// How long to wait before the version of an editor is looked for again after it failed, like when the version command
+// timed out on a cold start
+const versionRetryInterval = time.Minute
+
+var versionPattern = regexp.MustCompile(`(\d+(?:\.\d+)+)|Build (\d+)`)
+
+var errVersionRetry = errors.New("version of editor could not be found, it is looked for again later")
+
+// Versions by editor and checksum of the executable, an upgraded executable gets a new checksum
+var versions = struct {
+	mu    sync.Mutex
+	cache map[string]versionState
+}{cache: make(map[string]versionState)}
+
+// An empty version without a retry time means the editor does not tell its version
+type versionState struct {
+	version string
+	retry   time.Time
+}
+
+// Find the version of an editor that runs in given process
+func EditorVersion(editor string, process *system.Process) (string, error) {
+	method := editorRegistry().VersionMethod(editor)
+	if method == "" {
+		return "", ErrNoEditorVersion
+	}
+
+	checksum, err := process.Checksum()
+	if err != nil {
+		return "", err
+	}
+
+	return cachedVersion(editor+"\x00"+checksum, time.Now(), func() (string, error) {
+		return findVersion(method, process.Executable)
+	})
+}
+
+// Find a version once. An editor that does not tell its version is remembered for good, other failures are tried
+// again after versionRetryInterval, so a version command is not run on every key press.
+func cachedVersion(key string, now time.Time, find func() (string, error)) (string, error) {
+	versions.mu.Lock()
+	state, ok := versions.cache[key]
+	versions.mu.Unlock()
+
+	if ok && state.version != "" {
+		return state.version, nil
+	}
+
+	if ok && state.retry.IsZero() {
+		return "", ErrNoEditorVersion
+	}
+
+	if ok && now.Before(state.retry) {
+		return "", errVersionRetry
+	}
+
+	// The lock is not held while the version is found, a version command can take a while
+	version, err := find()
+
+	switch {
+	case err == nil:
+		state = versionState{version: version}
+	case err == ErrNoEditorVersion:
+		state = versionState{}
+	default:
+		state = versionState{retry: now.Add(versionRetryInterval)}
+	}
+
+	versions.mu.Lock()
+	versions.cache[key] = state
+	versions.mu.Unlock()
+
+	if err != nil {
+		return "", err
+	}
+
+	return version, nil
+}
+
+func findVersion(method string, executable string) (string, error) {
+	switch method {
+	case versionProductInfo:
+		return versionFromFile(executable, "product-info.json")
+	case versionPackageJSON:
+		return versionFromFile(executable, filepath.Join("resources", "app", "package.json"))
+	case versionCommand:
+		return versionFromCommand(executable)
+	}
+
+	return "", errors.New(fmt.Sprintf("unknown version method %s", method))
+}
+
+// Look for a JSON file with a version key in the directory of the executable and the directories above it
+func versionFromFile(executable string, name string) (string, error) {
+	directory := filepath.Dir(executable)
+	for i := 0; i < versionSearchDepth; i++ {
+		b, err := ioutil.ReadFile(filepath.Join(directory, name))
+		if err == nil {
+			var info struct {
+				Version string `json:"version"`
+			}
+
+			err = json.Unmarshal(b, &info)
+			if err != nil {
+				return "", err
+			}
+
+			if info.Version == "" {
+				return "", ErrNoEditorVersion
+			}
+
+			return info.Version, nil
+		}
+
+		parent := filepath.Dir(directory)
+		if parent == directory {
+			break
+		}
+
+		directory = parent
+	}
+
+	return "", ErrNoEditorVersion
+}
+
+// Run the executable with --version and take the first version number it prints
+func versionFromCommand(executable string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), versionCommandTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, executable, "--version").Output()
+	if err != nil {
+		return "", err
+	}
+
+	return parseVersion(output)
+}
+
+func parseVersion(output []byte) (string, error) {
+	match := versionPattern.FindSubmatch(output)
+	if match == nil {
+		return "", ErrNoEditorVersion
+	}
+
+	if len(match[1]) > 0 {
+		return string(match[1]), nil
+	}
+
+	return string(match[2]), nil
+}
@@ -0,0 +1,97 @@
+package inspect
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestVersion_FromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pacerank-version")
+	assert.NoError(t, err, "creating temporary directory should not result in error")
+	defer os.RemoveAll(dir)
+
+	goland := filepath.Join(dir, "GoLand")
+	code := filepath.Join(dir, "code")
+	for _, directory := range []string{filepath.Join(goland, "bin"), filepath.Join(code, "resources", "app")} {
+		assert.NoError(t, os.MkdirAll(directory, 0755), "creating directory should not result in error")
+	}
+
+	productInfo := []byte(`{"name": "GoLand", "version": "2020.3.2", "buildNumber": "203.6682.164"}`)
+	packageJSON := []byte(`{"name": "code-oss-dev", "version": "1.52.1"}`)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(goland, "product-info.json"), productInfo, 0644), "writing product info should not result in error")
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(code, "resources", "app", "package.json"), packageJSON, 0644), "writing package should not result in error")
+
+	version, err := findVersion(versionProductInfo, filepath.Join(goland, "bin", "goland.sh"))
+	assert.NoError(t, err, "reading product info should not result in error")
+	assert.Equal(t, "2020.3.2", version)
+
+	version, err = findVersion(versionPackageJSON, filepath.Join(code, "code"))
+	assert.NoError(t, err, "reading package should not result in error")
+	assert.Equal(t, "1.52.1", version)
+
+	_, err = findVersion(versionProductInfo, filepath.Join(code, "code"))
+	assert.Equal(t, ErrNoEditorVersion, err)
+}
+
+func TestVersion_ParseVersion(t *testing.T) {
+	tests := []struct {
+		output  string
+		version string
+	}{
+		{"VIM - Vi IMproved 8.2 (2019 Dec 12, compiled Oct 01 2021 01:51:08)\nIncluded patches: 1-2434", "8.2"},
+		{"NVIM v0.5.1\nBuild type: Release\nLuaJIT 2.1.0-beta3", "0.5.1"},
+		{"Sublime Text Build 4113", "4113"},
+		{"GNU Emacs 27.1\nCopyright (C) 2020 Free Software Foundation, Inc.", "27.1"},
+		{"helix 22.03 (d4e45fd4)", "22.03"},
+		{"no version here", ""},
+	}
+
+	for _, test := range tests {
+		version, err := parseVersion([]byte(test.output))
+		assert.Equal(t, test.version, version, test.output)
+		assert.Equal(t, test.version == "", err == ErrNoEditorVersion, test.output)
+	}
+}
+
+func TestVersion_Cache(t *testing.T) {
+	now := time.Now()
+
+	var calls int
+	find := func(version string, err error) func() (string, error) {
+		return func() (string, error) {
+			calls++
+			return version, err
+		}
+	}
+
+	version, err := cachedVersion("test\x00found", now, find("1.62.0", nil))
+	assert.NoError(t, err, "finding version should not result in error")
+	assert.Equal(t, "1.62.0", version)
+
+	version, _ = cachedVersion("test\x00found", now, find("", errors.New("should not run")))
+	assert.Equal(t, "1.62.0", version, "found version should be cached")
+
+	_, err = cachedVersion("test\x00none", now, find("", ErrNoEditorVersion))
+	assert.Equal(t, ErrNoEditorVersion, err)
+
+	_, err = cachedVersion("test\x00none", now.Add(versionRetryInterval*10), find("1.0", nil))
+	assert.Equal(t, ErrNoEditorVersion, err, "editor without version should be remembered for good")
+
+	// A version command that timed out is tried again later, not on every call
+	_, err = cachedVersion("test\x00slow", now, find("", errors.New("signal: killed")))
+	assert.Error(t, err, "timed out version command should result in error")
+
+	_, err = cachedVersion("test\x00slow", now.Add(time.Second), find("1.62.0", nil))
+	assert.Equal(t, errVersionRetry, err, "failed version should not be looked for again right away")
+
+	version, err = cachedVersion("test\x00slow", now.Add(versionRetryInterval), find("1.62.0", nil))
+	assert.NoError(t, err, "failed version should be looked for again later")
+	assert.Equal(t, "1.62.0", version)
+
+	assert.Equal(t, 4, calls, "version should only be looked for when it is not cached")
+}
@@ -0,0 +1,474 @@
+package inspect
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Names of the files editors keep their open projects in. JetBrains IDEs mark the open projects in recentProjects.xml
+// of their config directory, VS Code keeps the state of its windows in storage.json and the folders that were opened
+// recently in the SQLite database state.vscdb beside it.
+const (
+	workspaceJetBrains   = "recentProjects.xml"
+	workspaceVSCode      = "storage.json"
+	workspaceVSCodeState = "state.vscdb"
+)
+
+// Recently opened folders of VS Code that are suggested, like the Open Recent menu of VS Code shows
+const vscodeRecentLimit = 10
+
+// Roots by workspace file, they are read again when the file changes
+var workspaces = struct {
+	mu    sync.Mutex
+	cache map[string]workspaceState
+}{cache: make(map[string]workspaceState)}
+
+type workspaceState struct {
+	modified time.Time
+	roots    []string
+}
+
+// Find the project roots that are open in an editor, from the state the editor keeps on disk. When an editor keeps its
+// state in more than one place, like a config directory per version, the file that changed last is used. Roots that
+// no longer exist are left out.
+func Workspaces(editor string) ([]string, error) {
+	usr, err := user.Current()
+	if err != nil {
+		return nil, err
+	}
+
+	latest, modified, err := workspaceFile(editor)
+	if err != nil || latest == "" {
+		return nil, err
+	}
+
+	workspaces.mu.Lock()
+	defer workspaces.mu.Unlock()
+
+	if state, ok := workspaces.cache[latest]; ok && state.modified.Equal(modified) {
+		return state.roots, nil
+	}
+
+	roots, err := readWorkspaces(latest, usr.HomeDir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := existingDirectories(roots)
+	workspaces.cache[latest] = workspaceState{modified: modified, roots: result}
+	return result, nil
+}
+
+// Find the project roots an editor opened recently, most of them are not open anymore. Only VS Code keeps them, in
+// state.vscdb beside storage.json. Roots that no longer exist are left out.
+func RecentWorkspaces(editor string) ([]string, error) {
+	latest, _, err := workspaceFile(editor)
+	if err != nil || filepath.Base(latest) != workspaceVSCode {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(filepath.Dir(latest), workspaceVSCodeState))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	roots, err := vscodeRecentWorkspaces(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return existingDirectories(roots), nil
+}
+
+// Find the file that changed last of the ones an editor keeps its state in, an empty path when there is none
+func workspaceFile(editor string) (string, time.Time, error) {
+	var (
+		latest   string
+		modified time.Time
+	)
+
+	for _, pattern := range editorRegistry().WorkspaceFiles(editor) {
+		matches, err := filepath.Glob(expandHome(os.ExpandEnv(pattern)))
+		if err != nil {
+			return "", time.Time{}, err
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil || info.IsDir() {
+				continue
+			}
+
+			if latest == "" || info.ModTime().After(modified) {
+				latest, modified = match, info.ModTime()
+			}
+		}
+	}
+
+	return latest, modified, nil
+}
+
+func existingDirectories(paths []string) []string {
+	var result []string
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			result = append(result, path)
+		}
+	}
+
+	return result
+}
+
+// Find the project roots that are open in any known editor, by editor
+func OpenWorkspaces() map[string][]string {
+	result := make(map[string][]string)
+	for _, editor := range editorRegistry().Names() {
+		roots, err := Workspaces(editor)
+		if err != nil || len(roots) == 0 {
+			continue
+		}
+
+		result[editor] = roots
+	}
+
+	return result
+}
+
+// Find the project roots that were opened recently in any known editor, by editor
+func RecentlyOpenedWorkspaces() map[string][]string {
+	result := make(map[string][]string)
+	for _, editor := range editorRegistry().Names() {
+		roots, err := RecentWorkspaces(editor)
+		if err != nil {
+			log.Debug().Err(err).Msgf("could not read recently opened folders of %s", editor)
+			continue
+		}
+
+		if len(roots) > 0 {
+			result[editor] = roots
+		}
+	}
+
+	return result
+}
+
+// Find the open workspace a file in a window title belongs to, by the project name of the title or as the only one
+// that is open
+func WorkspaceOf(roots []string, project string) (string, bool) {
+	for _, root := range roots {
+		if project != "" && filepath.Base(root) == project {
+			return root, true
+		}
+	}
+
+	if len(roots) == 1 && (project == "" || project == filepath.Base(roots[0])) {
+		return roots[0], true
+	}
+
+	return "", false
+}
+
+func readWorkspaces(path string, home string) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch filepath.Base(path) {
+	case workspaceJetBrains:
+		return jetbrainsWorkspaces(data, home)
+	case workspaceVSCode:
+		return vscodeWorkspaces(data)
+	}
+
+	return nil, errors.New(fmt.Sprintf("unknown workspace file %s", path))
+}
+
+type jetbrainsApplication struct {
+	Components []struct {
+		Name    string            `xml:"name,attr"`
+		Options []jetbrainsOption `xml:"option"`
+	} `xml:"component"`
+}
+
+type jetbrainsOption struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+	Map   struct {
+		Entries []struct {
+			Key   string `xml:"key,attr"`
+			Value struct {
+				Info struct {
+					Opened  bool              `xml:"opened,attr"`
+					Options []jetbrainsOption `xml:"option"`
+				} `xml:"RecentProjectMetaInfo"`
+			} `xml:"value"`
+		} `xml:"entry"`
+	} `xml:"map"`
+	List struct {
+		Options []jetbrainsOption `xml:"option"`
+	} `xml:"list"`
+}
+
+// Read the open projects of a JetBrains IDE. Since 2020 they are marked as opened in additionalInfo, earlier versions
+// list them in openPaths. Paths in the home directory start with $USER_HOME$.
+func jetbrainsWorkspaces(data []byte, home string) ([]string, error) {
+	var application jetbrainsApplication
+	err := xml.Unmarshal(data, &application)
+	if err != nil {
+		return nil, err
+	}
+
+	type opened struct {
+		path      string
+		activated string
+	}
+
+	var projects []opened
+	for _, component := range application.Components {
+		if component.Name != "RecentProjectsManager" && component.Name != "RecentDirectoryProjectsManager" {
+			continue
+		}
+
+		for _, option := range component.Options {
+			switch option.Name {
+			case "additionalInfo":
+				for _, entry := range option.Map.Entries {
+					if !entry.Value.Info.Opened {
+						continue
+					}
+
+					project := opened{path: entry.Key}
+					for _, info := range entry.Value.Info.Options {
+						if info.Name == "activationTimestamp" {
+							project.activated = info.Value
+						}
+					}
+
+					projects = append(projects, project)
+				}
+			case "openPaths":
+				for _, path := range option.List.Options {
+					projects = append(projects, opened{path: path.Value})
+				}
+			}
+		}
+	}
+
+	// The project that was activated last comes first
+	sort.SliceStable(projects, func(i, j int) bool {
+		if len(projects[i].activated) != len(projects[j].activated) {
+			return len(projects[i].activated) > len(projects[j].activated)
+		}
+
+		return projects[i].activated > projects[j].activated
+	})
+
+	var result []string
+	for _, project := range projects {
+		path := strings.Replace(project.path, "$USER_HOME$", home, 1)
+		result = appendUnique(result, filepath.Clean(filepath.FromSlash(path)))
+	}
+
+	return result, nil
+}
+
+type vscodeStorage struct {
+	WindowsState struct {
+		LastActiveWindow vscodeWindow   `json:"lastActiveWindow"`
+		OpenedWindows    []vscodeWindow `json:"openedWindows"`
+	} `json:"windowsState"`
+}
+
+// Windows have a folder, or a workspace of several folders. Before 1.40 the folder was called folderUri.
+type vscodeWindow struct {
+	Folder              string `json:"folder"`
+	FolderUri           string `json:"folderUri"`
+	WorkspaceIdentifier struct {
+		ConfigURIPath string `json:"configURIPath"`
+	} `json:"workspaceIdentifier"`
+}
+
+type vscodeWorkspace struct {
+	Folders []struct {
+		Path string `json:"path"`
+		Uri  string `json:"uri"`
+	} `json:"folders"`
+}
+
+// Read the folders of the open windows of VS Code, the last active window comes first
+func vscodeWorkspaces(data []byte) ([]string, error) {
+	var storage vscodeStorage
+	err := json.Unmarshal(data, &storage)
+	if err != nil {
+		return nil, err
+	}
+
+	windows := append([]vscodeWindow{storage.WindowsState.LastActiveWindow}, storage.WindowsState.OpenedWindows...)
+
+	var result []string
+	for _, window := range windows {
+		folder := window.Folder
+		if folder == "" {
+			folder = window.FolderUri
+		}
+
+		if path, ok := FileURIPath(folder); ok {
+			result = appendUnique(result, path)
+		}
+
+		if path, ok := FileURIPath(window.WorkspaceIdentifier.ConfigURIPath); ok {
+			for _, root := range vscodeWorkspaceFolders(path) {
+				result = appendUnique(result, root)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+type vscodeRecent struct {
+	Entries []struct {
+		FolderUri string `json:"folderUri"`
+		Workspace struct {
+			ConfigPath string `json:"configPath"`
+		} `json:"workspace"`
+	} `json:"entries"`
+}
+
+// Read the folders VS Code opened recently from its state database, the most recent comes first. Recently opened files
+// are left out.
+func vscodeRecentWorkspaces(data []byte) ([]string, error) {
+	db, err := openSQLite(data)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := db.table("ItemTable")
+	if err != nil {
+		return nil, err
+	}
+
+	var value []byte
+	err = db.rows(root, func(values []interface{}) bool {
+		if len(values) < 2 || values[0] != "history.recentlyOpenedPathsList" {
+			return true
+		}
+
+		switch v := values[1].(type) {
+		case string:
+			value = []byte(v)
+		case []byte:
+			value = v
+		}
+
+		return false
+	})
+	if err != nil || value == nil {
+		return nil, err
+	}
+
+	var recent vscodeRecent
+	err = json.Unmarshal(value, &recent)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, entry := range recent.Entries {
+		if len(result) >= vscodeRecentLimit {
+			break
+		}
+
+		if path, ok := FileURIPath(entry.FolderUri); ok {
+			result = appendUnique(result, path)
+		}
+
+		if path, ok := FileURIPath(entry.Workspace.ConfigPath); ok {
+			for _, root := range vscodeWorkspaceFolders(path) {
+				result = appendUnique(result, root)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Read the folders of a .code-workspace file, relative paths are relative to the file. Workspace files may hold
+// comments, when the file can not be read its directory is used.
+func vscodeWorkspaceFolders(path string) []string {
+	directory := filepath.Dir(path)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return []string{directory}
+	}
+
+	var workspace vscodeWorkspace
+	err = json.Unmarshal(data, &workspace)
+	if err != nil {
+		return []string{directory}
+	}
+
+	var result []string
+	for _, folder := range workspace.Folders {
+		if folder.Uri != "" {
+			if root, ok := FileURIPath(folder.Uri); ok {
+				result = appendUnique(result, root)
+			}
+
+			continue
+		}
+
+		root := filepath.FromSlash(folder.Path)
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(directory, root)
+		}
+
+		result = appendUnique(result, filepath.Clean(root))
+	}
+
+	return result
+}
+
+// FileURIPath turns a file URI into a path, like file:///home/user/client or file:///c%3A/Users/user/client on Windows. Remote
+// folders are not on this machine and are left out.
+func FileURIPath(uri string) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" || u.Path == "" {
+		return "", false
+	}
+
+	path := u.Path
+	if len(path) >= 3 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+
+	return filepath.Clean(filepath.FromSlash(path)), true
+}
+
+func appendUnique(list []string, value string) []string {
+	for _, item := range list {
+		if item == value {
+			return list
+		}
+	}
+
+	return append(list, value)
+}
@@ -0,0 +1,178 @@
+package inspect
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+const testRecentProjects = `<application>
+  <component name="RecentProjectsManager">
+    <option name="additionalInfo">
+      <map>
+        <entry key="$USER_HOME$/projects/client">
+          <value>
+            <RecentProjectMetaInfo opened="true" frameTitle="client – main.go">
+              <option name="activationTimestamp" value="1634000000000" />
+            </RecentProjectMetaInfo>
+          </value>
+        </entry>
+        <entry key="$USER_HOME$/projects/digest">
+          <value>
+            <RecentProjectMetaInfo opened="true">
+              <option name="activationTimestamp" value="1635000000000" />
+            </RecentProjectMetaInfo>
+          </value>
+        </entry>
+        <entry key="/srv/closed">
+          <value>
+            <RecentProjectMetaInfo>
+              <option name="activationTimestamp" value="1636000000000" />
+            </RecentProjectMetaInfo>
+          </value>
+        </entry>
+      </map>
+    </option>
+  </component>
+</application>`
+
+func TestWorkspace_JetBrains(t *testing.T) {
+	roots, err := jetbrainsWorkspaces([]byte(testRecentProjects), "/home/user")
+	assert.NoError(t, err, "reading recent projects should not result in error")
+	assert.Equal(t, []string{
+		filepath.FromSlash("/home/user/projects/digest"),
+		filepath.FromSlash("/home/user/projects/client"),
+	}, roots, "open projects should be read, the last activated first")
+
+	roots, err = jetbrainsWorkspaces([]byte(`<application>
+  <component name="RecentDirectoryProjectsManager">
+    <option name="openPaths">
+      <list>
+        <option value="$USER_HOME$/projects/legacy" />
+      </list>
+    </option>
+  </component>
+</application>`), "/home/user")
+	assert.NoError(t, err, "reading recent projects of older versions should not result in error")
+	assert.Equal(t, []string{filepath.FromSlash("/home/user/projects/legacy")}, roots)
+
+	_, err = jetbrainsWorkspaces([]byte("<application"), "/home/user")
+	assert.Error(t, err, "broken recent projects should result in error")
+}
+
+func TestWorkspace_VSCode(t *testing.T) {
+	directory := t.TempDir()
+	workspace := filepath.Join(directory, "work.code-workspace")
+	err := ioutil.WriteFile(workspace, []byte(`{"folders": [{"path": "api"}, {"path": "/srv/web"}]}`), 0644)
+	assert.NoError(t, err, "writing workspace should not result in error")
+
+	storage := `{
+		"windowsState": {
+			"lastActiveWindow": {"folder": "file:///home/user/projects/client"},
+			"openedWindows": [
+				{"folder": "file:///home/user/projects/client"},
+				{"folderUri": "file:///home/user/projects/old%20style"},
+				{"folder": "vscode-remote://ssh-remote%2Bserver/srv/remote"},
+				{"workspaceIdentifier": {"id": "1", "configURIPath": "file://` + filepath.ToSlash(workspace) + `"}}
+			]
+		}
+	}`
+
+	roots, err := vscodeWorkspaces([]byte(storage))
+	assert.NoError(t, err, "reading storage should not result in error")
+	assert.Equal(t, []string{
+		filepath.FromSlash("/home/user/projects/client"),
+		filepath.FromSlash("/home/user/projects/old style"),
+		filepath.Join(directory, "api"),
+		filepath.FromSlash("/srv/web"),
+	}, roots, "folders of open windows and workspaces should be read")
+}
+
+// testdata/state.vscdb has small pages, so the recently opened list spans overflow pages and the table has interior
+// pages
+func TestWorkspace_VSCodeRecent(t *testing.T) {
+	state, err := ioutil.ReadFile(filepath.Join("testdata", "state.vscdb"))
+	assert.NoError(t, err, "reading state database should not result in error")
+
+	expected := []string{
+		filepath.FromSlash("/home/user/projects/client"),
+		filepath.FromSlash("/home/user/work"),
+	}
+	for i := 0; i < 8; i++ {
+		expected = append(expected, filepath.FromSlash(fmt.Sprintf("/home/user/projects/old%d", i)))
+	}
+
+	roots, err := vscodeRecentWorkspaces(state)
+	assert.NoError(t, err, "reading recently opened folders should not result in error")
+	assert.Equal(t, expected, roots, "recently opened folders should be read up to the limit")
+
+	_, err = vscodeRecentWorkspaces([]byte(`{"not": "sqlite"}`))
+	assert.Error(t, err, "file that is not a database should result in error")
+
+	_, err = vscodeRecentWorkspaces(state[:512*3])
+	assert.Error(t, err, "truncated database should result in error")
+
+	// The header of the record with the recently opened list starts 4 bytes before its key, a header size that is
+	// smaller than its own varint is what a record that is being written can look like
+	key := bytes.Index(state, []byte("history.recentlyOpenedPathsList"))
+	assert.Equal(t, byte(4), state[key-4], "record header should come before the key")
+
+	corrupt := append([]byte(nil), state...)
+	corrupt[key-4] = 0
+	_, err = vscodeRecentWorkspaces(corrupt)
+	assert.Equal(t, errSQLiteCorrupt, err, "record with too small header size should be corrupt")
+
+	_, err = sqliteRecord([]byte{0x80, 0x01, 0x00})
+	assert.Equal(t, errSQLiteCorrupt, err, "record with header size inside its own varint should be corrupt")
+
+	// Recently opened folders are not open, they are not read as the workspaces of storage.json beside the database
+	directory := t.TempDir()
+	err = ioutil.WriteFile(filepath.Join(directory, workspaceVSCode),
+		[]byte(`{"windowsState": {"lastActiveWindow": {"folder": "file:///home/user/projects/old3"}}}`), 0644)
+	assert.NoError(t, err, "writing storage should not result in error")
+	err = ioutil.WriteFile(filepath.Join(directory, workspaceVSCodeState), state, 0644)
+	assert.NoError(t, err, "writing state database should not result in error")
+
+	roots, err = readWorkspaces(filepath.Join(directory, workspaceVSCode), "/home/user")
+	assert.NoError(t, err, "reading workspaces should not result in error")
+	assert.Equal(t, []string{filepath.FromSlash("/home/user/projects/old3")}, roots, "only the open window should be read")
+}
+
+func TestWorkspace_FileURIPath(t *testing.T) {
+	path, ok := FileURIPath("file:///c%3A/Users/user/client")
+	assert.True(t, ok, "windows path should be read")
+	assert.Equal(t, filepath.FromSlash("c:/Users/user/client"), path)
+
+	_, ok = FileURIPath("vscode-remote://wsl%2Bubuntu/home/user")
+	assert.False(t, ok, "remote folders should be left out")
+}
+
+func TestWorkspace_WorkspaceOf(t *testing.T) {
+	roots := []string{"/home/user/client", "/home/user/digest"}
+
+	root, ok := WorkspaceOf(roots, "digest")
+	assert.True(t, ok, "workspace should be found by project name")
+	assert.Equal(t, "/home/user/digest", root)
+
+	_, ok = WorkspaceOf(roots, "")
+	assert.False(t, ok, "file without project should not be placed when several workspaces are open")
+
+	root, ok = WorkspaceOf(roots[:1], "")
+	assert.True(t, ok, "file should be placed in the only open workspace")
+	assert.Equal(t, "/home/user/client", root)
+
+	_, ok = WorkspaceOf(roots[:1], "other")
+	assert.False(t, ok, "file of another project should not be placed")
+}
+
+func TestWorkspace_KnownEditors(t *testing.T) {
+	registry, err := NewEditorRegistry("linux", embeddedEditors, nil)
+	assert.NoError(t, err, "loading known editors should not result in error")
+
+	assert.Contains(t, registry.WorkspaceFiles("GoLand"), "~/.config/JetBrains/GoLand*/options/recentProjects.xml")
+	assert.Contains(t, registry.WorkspaceFiles("Visual Studio Code"), "~/.config/Code/User/globalStorage/storage.json")
+	assert.Empty(t, registry.WorkspaceFiles("nano"), "editors without projects should not have workspace files")
+}
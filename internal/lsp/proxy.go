@@ -0,0 +1,262 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/pacerank/client/internal/inspect"
+	"github.com/pacerank/client/internal/watcher"
+	"github.com/rs/zerolog/log"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The proxy sits between an editor and a language server, and passes every message through as it is. Messages from
+// the editor about open documents are observed, only the number of changes per document is kept.
+// https://microsoft.github.io/language-server-protocol/specifications/specification-current/
+const (
+	// How often the observed activity is sent to the client
+	flushInterval = time.Second * 30
+
+	// Largest message that is read, language servers get whole documents so this is generous
+	maxMessageLength = 1 << 28
+)
+
+// Send is given the activity of the documents since the last time
+type Send func(heartbeats []watcher.Heartbeat) error
+
+// Run a language server, pass the messages of the editor on in to the server and the messages of the server on to out.
+// It returns when the server exits.
+func Run(command []string, in io.Reader, out io.Writer, send Send) error {
+	if len(command) == 0 {
+		return errors.New("missing language server command")
+	}
+
+	server := exec.Command(command[0], command[1:]...)
+	server.Stdout = out
+	server.Stderr = os.Stderr
+
+	serverIn, err := server.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	err = server.Start()
+	if err != nil {
+		return err
+	}
+
+	tracker := newTracker(strings.Join(command, " "))
+	done := make(chan struct{})
+
+	go func() {
+		err := forward(in, serverIn, tracker.observe)
+		if err != nil && err != io.EOF {
+			log.Error().Err(err).Msg("could not forward messages to language server")
+		}
+
+		_ = serverIn.Close()
+	}()
+
+	go func() {
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				tracker.flush(send, time.Now())
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	err = server.Wait()
+	close(done)
+
+	tracker.flush(send, time.Now())
+	return err
+}
+
+// Copy messages from in to out as they are, and hand the content of every message to observe
+func forward(in io.Reader, out io.Writer, observe func(content []byte)) error {
+	reader := bufio.NewReader(in)
+	for {
+		header, content, err := readMessage(reader)
+		if len(header) > 0 {
+			_, werr := out.Write(append(header, content...))
+			if werr != nil {
+				return werr
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+
+		observe(content)
+	}
+}
+
+// Read the header and content of a message, the header ends with an empty line and tells the length of the content
+func readMessage(reader *bufio.Reader) ([]byte, []byte, error) {
+	var header []byte
+	length := -1
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		header = append(header, line...)
+		if err != nil {
+			if err == io.EOF && len(header) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+
+			return header, nil, err
+		}
+
+		field := strings.TrimSpace(string(line))
+		if field == "" {
+			break
+		}
+
+		colon := strings.Index(field, ":")
+		if colon < 0 || !strings.EqualFold(strings.TrimSpace(field[:colon]), "Content-Length") {
+			continue
+		}
+
+		length, err = strconv.Atoi(strings.TrimSpace(field[colon+1:]))
+		if err != nil || length < 0 || length > maxMessageLength {
+			return header, nil, errors.New(fmt.Sprintf("invalid content length %q", field[colon+1:]))
+		}
+	}
+
+	if length < 0 {
+		return header, nil, errors.New("message is missing the content length")
+	}
+
+	content := make([]byte, length)
+	_, err := io.ReadFull(reader, content)
+	return header, content, err
+}
+
+type message struct {
+	Method string `json:"method"`
+	Params struct {
+		TextDocument struct {
+			Uri        string `json:"uri"`
+			LanguageId string `json:"languageId"`
+		} `json:"textDocument"`
+
+		// Only the number of changes is kept, not what they are
+		ContentChanges []struct{} `json:"contentChanges"`
+	} `json:"params"`
+}
+
+type document struct {
+	language string
+	changes  int
+	saved    bool
+}
+
+// tracker keeps the activity of the documents until it is flushed
+type tracker struct {
+	mu        sync.Mutex
+	userAgent string
+	documents map[string]*document
+	languages map[string]string
+}
+
+func newTracker(server string) *tracker {
+	return &tracker{
+		userAgent: "pacerank-lsp-proxy (" + server + ")",
+		documents: make(map[string]*document),
+		languages: make(map[string]string),
+	}
+}
+
+func (t *tracker) observe(content []byte) {
+	var m message
+	if json.Unmarshal(content, &m) != nil {
+		return
+	}
+
+	uri := m.Params.TextDocument.Uri
+	if uri == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch m.Method {
+	case "textDocument/didOpen":
+		t.languages[uri] = m.Params.TextDocument.LanguageId
+		t.document(uri)
+	case "textDocument/didChange":
+		t.document(uri).changes += len(m.Params.ContentChanges)
+	case "textDocument/didSave":
+		t.document(uri).saved = true
+	case "textDocument/didClose":
+		delete(t.languages, uri)
+	}
+}
+
+func (t *tracker) document(uri string) *document {
+	d, ok := t.documents[uri]
+	if !ok {
+		d = &document{language: t.languages[uri]}
+		t.documents[uri] = d
+	}
+
+	return d
+}
+
+// Send the activity of the documents as heartbeats, activity that could not be sent is dropped
+func (t *tracker) flush(send Send, now time.Time) {
+	t.mu.Lock()
+	documents := t.documents
+	t.documents = make(map[string]*document)
+	t.mu.Unlock()
+
+	var heartbeats []watcher.Heartbeat
+	for uri, d := range documents {
+		path, ok := inspect.FileURIPath(uri)
+		if !ok {
+			continue
+		}
+
+		heartbeat := watcher.Heartbeat{
+			Entity:    path,
+			Type:      "file",
+			Category:  "coding",
+			Time:      float64(now.UnixNano()) / float64(time.Second),
+			IsWrite:   d.saved,
+			UserAgent: t.userAgent,
+			Changes:   d.changes,
+		}
+
+		language, err := inspect.LanguageByFileType(path, filepath.Base(path), d.language)
+		if err == nil {
+			heartbeat.Language = language
+		}
+
+		heartbeats = append(heartbeats, heartbeat)
+	}
+
+	if len(heartbeats) == 0 {
+		return
+	}
+
+	err := send(heartbeats)
+	if err != nil {
+		log.Debug().Err(err).Msg("could not send language server activity to the client")
+	}
+}
@@ -0,0 +1,108 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/pacerank/client/internal/watcher"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func frame(content string) string {
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(content), content)
+}
+
+func TestProxy_ReadMessage(t *testing.T) {
+	input := "Content-Length: 2\r\nContent-Type: application/vscode-jsonrpc; charset=utf-8\r\n\r\n{}" + frame(`{"id":1}`)
+	reader := bufio.NewReader(strings.NewReader(input))
+
+	header, content, err := readMessage(reader)
+	assert.NoError(t, err, "reading message should not result in error")
+	assert.Equal(t, "{}", string(content))
+	assert.True(t, strings.HasSuffix(string(header), "charset=utf-8\r\n\r\n"), "header should be kept as it is")
+
+	_, content, err = readMessage(reader)
+	assert.NoError(t, err, "reading message should not result in error")
+	assert.Equal(t, `{"id":1}`, string(content))
+
+	_, _, err = readMessage(reader)
+	assert.Equal(t, io.EOF, err, "end of input should be told")
+
+	_, _, err = readMessage(bufio.NewReader(strings.NewReader("Content-Length: x\r\n\r\n")))
+	assert.Error(t, err, "invalid length should result in error")
+
+	_, _, err = readMessage(bufio.NewReader(strings.NewReader("Content-Length: 10\r\n\r\n{}")))
+	assert.Error(t, err, "truncated message should result in error")
+}
+
+func TestProxy_Tracker(t *testing.T) {
+	tracker := newTracker("gopls")
+
+	messages := []string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"rootUri":"file:///home/user/client"}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///home/user/client/main.go","languageId":"go","version":1,"text":"package main"}}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"file:///home/user/client/main.go","version":2},"contentChanges":[{"text":"a"},{"text":"b"}]}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"file:///home/user/client/main.go","version":3},"contentChanges":[{"text":"c"}]}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didSave","params":{"textDocument":{"uri":"file:///home/user/client/main.go"}}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"untitled:Untitled-1","languageId":"go"}}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///home/user/client/README.md","languageId":"markdown"}}}`,
+		`not json`,
+	}
+
+	for _, m := range messages {
+		tracker.observe([]byte(m))
+	}
+
+	var sent []watcher.Heartbeat
+	send := func(heartbeats []watcher.Heartbeat) error {
+		sent = append(sent, heartbeats...)
+		return nil
+	}
+
+	tracker.flush(send, time.Unix(1609459200, 0))
+	sort.Slice(sent, func(i, j int) bool { return sent[i].Entity < sent[j].Entity })
+
+	assert.Len(t, sent, 2, "documents that are not files should be left out")
+	assert.Equal(t, "/home/user/client/README.md", sent[0].Entity)
+	assert.Equal(t, 0, sent[0].Changes, "opened document should not have changes")
+	assert.Equal(t, "/home/user/client/main.go", sent[1].Entity)
+	assert.Equal(t, 3, sent[1].Changes, "changes should be counted")
+	assert.True(t, sent[1].IsWrite, "saved document should be a write")
+	assert.Equal(t, "go", sent[1].Language, "language should be found from the language id")
+	assert.Equal(t, float64(1609459200), sent[1].Time)
+
+	sent = nil
+	tracker.flush(send, time.Now())
+	assert.Empty(t, sent, "activity should only be sent once")
+
+	tracker.observe([]byte(messages[3]))
+	tracker.flush(send, time.Now())
+	assert.Len(t, sent, 1, "activity after a flush should be sent")
+	assert.Equal(t, "go", sent[0].Language, "language of an open document should be remembered")
+}
+
+func TestProxy_Run(t *testing.T) {
+	input := frame(`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///tmp/main.go","languageId":"go"}}}`) +
+		frame(`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"file:///tmp/main.go"},"contentChanges":[{"text":"x"}]}}`)
+
+	var sent []watcher.Heartbeat
+	var out bytes.Buffer
+
+	// cat echoes the messages back, like a language server that answers with what it was sent
+	err := Run([]string{"cat"}, strings.NewReader(input), &out, func(heartbeats []watcher.Heartbeat) error {
+		sent = append(sent, heartbeats...)
+		return nil
+	})
+	assert.NoError(t, err, "running language server should not result in error")
+	assert.Equal(t, input, out.String(), "messages should be passed through unchanged")
+	assert.Len(t, sent, 1, "activity should be sent when the server exits")
+	assert.Equal(t, 1, sent[0].Changes)
+
+	err = Run(nil, strings.NewReader(""), &out, nil)
+	assert.Error(t, err, "missing command should result in error")
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/pacerank/client/pkg/system"
 	"github.com/rs/zerolog/log"
 	"net/http"
+	"os"
 	"time"
 )
 
@@ -51,7 +52,8 @@ func AuthorizationFlow(apiClient *api.Api, storage *store.Store) error {
 
 	err = system.OpenBrowser(endpointReply.AuthorizationUrl)
 	if err != nil {
-		log.Info().Msgf("could not open browser automatically, please authorize this client here: %s", endpointReply.AuthorizationUrl)
+		log.Info().Err(err).Msg("could not open browser automatically, authorize this client from another device")
+		devicePrompt(os.Stdout, endpointReply)
 	}
 
 	for {
@@ -53,6 +53,19 @@ func Setup() error {
 		return err
 	}
 
+	logTo(os.Stdout)
+
+	zerolog.SetGlobalLevel(zerolog.InfoLevel)
+
+	return err
+}
+
+// Log to stderr instead of stdout, for commands that speak a protocol over stdout
+func LogToStderr() {
+	logTo(os.Stderr)
+}
+
+func logTo(console *os.File) {
 	fileWriter := zerolog.ConsoleWriter{
 		Out:           file,
 		NoColor:       true,
@@ -60,22 +73,18 @@ func Setup() error {
 		FormatMessage: formatMessage,
 	}
 
-	stdoutWriter := zerolog.ConsoleWriter{
-		Out:           os.Stdout,
+	consoleWriter := zerolog.ConsoleWriter{
+		Out:           console,
 		NoColor:       runtime.GOOS != "linux",
 		TimeFormat:    "2006-01-02T15:04:05Z",
 		FormatMessage: formatMessage,
 	}
 
 	// Make a writer to both log types
-	mw := io.MultiWriter(fileWriter, stdoutWriter)
+	mw := io.MultiWriter(fileWriter, consoleWriter)
 
 	// Pretty log
 	log.Logger = log.Output(mw)
-
-	zerolog.SetGlobalLevel(zerolog.InfoLevel)
-
-	return err
 }
 
 func EnableDebug() {
@@ -0,0 +1,70 @@
+package operation
+
+import (
+	"fmt"
+	"github.com/pacerank/client/pkg/api"
+	"github.com/rs/zerolog/log"
+	"io"
+	"rsc.io/qr"
+	"strings"
+)
+
+// Print how to authorize the client from another device. The server gives a short user code and an url to enter it
+// on, when it does not the authorization url is shown instead. The QR code leads to the same url.
+func devicePrompt(w io.Writer, reply *api.InitializeAuthorizationFlowReply) {
+	url := reply.AuthorizationUrl
+	if reply.UserCode != "" && reply.VerificationUrl != "" {
+		url = reply.VerificationUrl
+	}
+
+	var b strings.Builder
+
+	b.WriteString("\nTo authorize this client, open this address on any device:\n\n")
+	b.WriteString(fmt.Sprintf("    %s\n\n", url))
+
+	if reply.UserCode != "" && reply.VerificationUrl != "" {
+		b.WriteString(fmt.Sprintf("and enter the code:\n\n    %s\n\n", reply.UserCode))
+	}
+
+	code, err := qr.Encode(url, qr.L)
+	if err != nil {
+		log.Debug().Err(err).Msg("could not create qr code")
+	} else {
+		b.WriteString("or scan the QR code:\n\n")
+		b.WriteString(qrText(code))
+		b.WriteString("\n")
+	}
+
+	_, _ = io.WriteString(w, b.String())
+}
+
+// Size of the light border around the QR code that scanners need to find it
+const qrQuietZone = 2
+
+// Render the QR code with half block characters, so two rows fit in one line of text. Dark modules are drawn as
+// spaces on a light background, which scans on both light and dark terminals.
+func qrText(code *qr.Code) string {
+	var b strings.Builder
+
+	start, end := -qrQuietZone, code.Size+qrQuietZone
+	for y := start; y < end; y += 2 {
+		for x := start; x < end; x++ {
+			top, bottom := code.Black(x, y), code.Black(x, y+1)
+
+			switch {
+			case top && bottom:
+				b.WriteString(" ")
+			case top:
+				b.WriteString("▄")
+			case bottom:
+				b.WriteString("▀")
+			default:
+				b.WriteString("█")
+			}
+		}
+
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
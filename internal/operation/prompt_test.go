@@ -0,0 +1,40 @@
+package operation
+
+import (
+	"bytes"
+	"github.com/pacerank/client/pkg/api"
+	"github.com/stretchr/testify/assert"
+	"rsc.io/qr"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestPrompt_DevicePrompt(t *testing.T) {
+	var b bytes.Buffer
+	devicePrompt(&b, &api.InitializeAuthorizationFlowReply{
+		AuthorizationUrl: "https://pacerank.io/authorize/0b9d4a8c",
+		UserCode:         "WDJB-MJHT",
+		VerificationUrl:  "https://pacerank.io/device",
+	})
+
+	assert.Contains(t, b.String(), "https://pacerank.io/device", "verification url should be shown")
+	assert.Contains(t, b.String(), "WDJB-MJHT", "user code should be shown")
+	assert.NotContains(t, b.String(), "0b9d4a8c", "authorization url is not needed with a user code")
+
+	b.Reset()
+	devicePrompt(&b, &api.InitializeAuthorizationFlowReply{AuthorizationUrl: "https://pacerank.io/authorize/0b9d4a8c"})
+	assert.Contains(t, b.String(), "https://pacerank.io/authorize/0b9d4a8c", "authorization url should be shown without a user code")
+}
+
+func TestPrompt_QRText(t *testing.T) {
+	code, err := qr.Encode("https://pacerank.io/device", qr.L)
+	assert.NoError(t, err, "encoding qr code should not result in error")
+
+	lines := strings.Split(strings.TrimSuffix(qrText(code), "\n"), "\n")
+	assert.Len(t, lines, (code.Size+qrQuietZone*2+1)/2, "two rows should fit in a line")
+
+	for _, line := range lines {
+		assert.Equal(t, code.Size+qrQuietZone*2, utf8.RuneCountInString(line), "every line should be as wide as the code")
+	}
+}
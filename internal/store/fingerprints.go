@@ -0,0 +1,150 @@
+package store
+
+import (
+	"encoding/binary"
+	"github.com/boltdb/bolt"
+	"os"
+	"time"
+)
+
+// Fingerprints are the line hashes of files, the source itself is never stored. When each fingerprint was saved is kept
+// in a bucket of its own, so fingerprints of files that are no longer edited can be pruned.
+
+// Get the fingerprint of a file from the last time it was saved, false when the file has not been seen
+func (s *Store) Fingerprint(path string) ([]uint64, bool, error) {
+	var (
+		result []uint64
+		found  bool
+	)
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("fingerprints"))
+		if b == nil {
+			return nil
+		}
+
+		v := b.Get([]byte(path))
+		if v == nil {
+			return nil
+		}
+
+		found = true
+		result = make([]uint64, len(v)/8)
+		for i := range result {
+			result[i] = binary.BigEndian.Uint64(v[i*8:])
+		}
+
+		return nil
+	})
+
+	return result, found, err
+}
+
+// Save the fingerprint of a file, replacing the one before
+func (s *Store) SaveFingerprint(path string, fingerprint []uint64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("fingerprints"))
+		if err != nil {
+			return err
+		}
+
+		saved, err := tx.CreateBucketIfNotExists([]byte("fingerprints_saved"))
+		if err != nil {
+			return err
+		}
+
+		v := make([]byte, len(fingerprint)*8)
+		for i, hash := range fingerprint {
+			binary.BigEndian.PutUint64(v[i*8:], hash)
+		}
+
+		err = b.Put([]byte(path), v)
+		if err != nil {
+			return err
+		}
+
+		t := make([]byte, 8)
+		binary.BigEndian.PutUint64(t, uint64(time.Now().Unix()))
+		return saved.Put([]byte(path), t)
+	})
+}
+
+// Delete the fingerprint of a file, it is not an error if the file has none
+func (s *Store) DeleteFingerprint(path string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return deleteFingerprint(tx, []byte(path))
+	})
+}
+
+// Delete the fingerprints saved before the given time, and the ones of files that no longer exist. Returns how many
+// fingerprints were deleted.
+func (s *Store) PruneFingerprints(before time.Time) (int, error) {
+	var pruned int
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("fingerprints"))
+		if b == nil {
+			return nil
+		}
+
+		saved := tx.Bucket([]byte("fingerprints_saved"))
+
+		var paths [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			if !fingerprintExpired(saved, k, before) {
+				if _, err := os.Stat(string(k)); !os.IsNotExist(err) {
+					return nil
+				}
+			}
+
+			paths = append(paths, append([]byte(nil), k...))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Keys can not be deleted while iterating the bucket
+		for _, path := range paths {
+			err = deleteFingerprint(tx, path)
+			if err != nil {
+				return err
+			}
+		}
+
+		pruned = len(paths)
+		return nil
+	})
+
+	return pruned, err
+}
+
+// Fingerprints without a saved time were saved before the time was kept, and are treated as expired
+func fingerprintExpired(saved *bolt.Bucket, path []byte, before time.Time) bool {
+	if saved == nil {
+		return true
+	}
+
+	t := saved.Get(path)
+	if len(t) != 8 {
+		return true
+	}
+
+	return time.Unix(int64(binary.BigEndian.Uint64(t)), 0).Before(before)
+}
+
+func deleteFingerprint(tx *bolt.Tx, path []byte) error {
+	for _, name := range []string{"fingerprints", "fingerprints_saved"} {
+		b := tx.Bucket([]byte(name))
+		if b == nil {
+			continue
+		}
+
+		err := b.Delete(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package store
+
+import (
+	"github.com/boltdb/bolt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testStore(t *testing.T) *Store {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "cache"), 0600, nil)
+	if !assert.NoError(t, err, "opening database should not result in error") {
+		t.FailNow()
+	}
+
+	t.Cleanup(func() { _ = db.Close() })
+	return &Store{db: db}
+}
+
+func TestFingerprints_Prune(t *testing.T) {
+	s := testStore(t)
+	dir := t.TempDir()
+
+	kept := filepath.Join(dir, "kept.go")
+	removed := filepath.Join(dir, "removed.go")
+	for _, path := range []string{kept, removed} {
+		assert.NoError(t, ioutil.WriteFile(path, []byte("package main\n"), 0600), "writing file should not result in error")
+		assert.NoError(t, s.SaveFingerprint(path, []uint64{1, 2}), "saving fingerprint should not result in error")
+	}
+
+	assert.NoError(t, os.Remove(removed), "removing file should not result in error")
+
+	pruned, err := s.PruneFingerprints(time.Now().Add(-time.Hour))
+	assert.NoError(t, err, "pruning fingerprints should not result in error")
+	assert.Equal(t, 1, pruned, "only the fingerprint of the removed file should be pruned")
+
+	fingerprint, found, err := s.Fingerprint(kept)
+	assert.NoError(t, err, "getting fingerprint should not result in error")
+	assert.True(t, found, "fingerprint of existing file should be kept")
+	assert.Equal(t, []uint64{1, 2}, fingerprint, "kept fingerprint should not change")
+
+	_, found, err = s.Fingerprint(removed)
+	assert.NoError(t, err, "getting fingerprint should not result in error")
+	assert.False(t, found, "fingerprint of removed file should be pruned")
+
+	pruned, err = s.PruneFingerprints(time.Now().Add(time.Hour))
+	assert.NoError(t, err, "pruning fingerprints should not result in error")
+	assert.Equal(t, 1, pruned, "fingerprint saved before the given time should be pruned")
+
+	_, found, err = s.Fingerprint(kept)
+	assert.NoError(t, err, "getting fingerprint should not result in error")
+	assert.False(t, found, "old fingerprint should be pruned")
+}
+
+func TestFingerprints_Delete(t *testing.T) {
+	s := testStore(t)
+
+	assert.NoError(t, s.DeleteFingerprint("/nowhere/main.go"), "deleting missing fingerprint should not result in error")
+	assert.NoError(t, s.SaveFingerprint("/nowhere/main.go", []uint64{1}), "saving fingerprint should not result in error")
+	assert.NoError(t, s.DeleteFingerprint("/nowhere/main.go"), "deleting fingerprint should not result in error")
+
+	_, found, err := s.Fingerprint("/nowhere/main.go")
+	assert.NoError(t, err, "getting fingerprint should not result in error")
+	assert.False(t, found, "deleted fingerprint should not be found")
+}
@@ -40,6 +40,13 @@ type InHeap struct {
 	FileName string
 	Project  string
 	Git      string
+	Tags     []string
+	Role     string
+
+	// Changes made to the files and lines added and removed, they are added up
+	Changes      uint64
+	LinesAdded   uint64
+	LinesRemoved uint64
 }
 
 func (s *Store) AddHeap(heap InHeap) error {
@@ -62,9 +69,27 @@ func (s *Store) AddHeap(heap InHeap) error {
 			return err
 		}
 
-		err = pb.Put([]byte("files"), appendToBytes(pb.Get([]byte("files")), heap.FileName))
-		if err != nil {
-			return err
+		// Projects that do not tell their files have no file name
+		if heap.FileName != "" {
+			err = pb.Put([]byte("files"), appendToBytes(pb.Get([]byte("files")), heap.FileName))
+			if err != nil {
+				return err
+			}
+		}
+
+		// Activity that is not about a saved or named file has no role
+		if heap.Role != "" {
+			err = pb.Put([]byte("roles"), appendToBytes(pb.Get([]byte("roles")), heap.Role))
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, tag := range heap.Tags {
+			err = pb.Put([]byte("tags"), appendToBytes(pb.Get([]byte("tags")), tag))
+			if err != nil {
+				return err
+			}
 		}
 
 		err = pb.Put([]byte("project"), []byte(heap.Project))
@@ -82,6 +107,28 @@ func (s *Store) AddHeap(heap InHeap) error {
 			return err
 		}
 
+		counters := map[string]uint64{
+			"changes":       heap.Changes,
+			"lines_added":   heap.LinesAdded,
+			"lines_removed": heap.LinesRemoved,
+		}
+
+		for key, value := range counters {
+			if value == 0 {
+				continue
+			}
+
+			var count uint64
+			if v := pb.Get([]byte(key)); v != nil {
+				count = binary.BigEndian.Uint64(v)
+			}
+
+			err = pb.Put([]byte(key), itob(count+value))
+			if err != nil {
+				return err
+			}
+		}
+
 		return nil
 	})
 }
@@ -90,9 +137,15 @@ type OutHeap struct {
 	Id        string
 	Languages []string
 	Files     []string
+	Tags      []string
+	Roles     []string
 	Project   string
 	Git       string
 	Branch    string
+
+	Changes      uint64
+	LinesAdded   uint64
+	LinesRemoved uint64
 }
 
 func (s *Store) HeapById(id string) (OutHeap, error) {
@@ -117,9 +170,35 @@ func (s *Store) HeapById(id string) (OutHeap, error) {
 		heap.Project = string(pb.Get([]byte("project")))
 		heap.Git = string(pb.Get([]byte("git")))
 		heap.Branch = string(pb.Get([]byte("branch")))
-		err = json.NewDecoder(bytes.NewBuffer(pb.Get([]byte("files")))).Decode(&heap.Files)
-		if err != nil {
-			return err
+		for key, value := range map[string]*uint64{
+			"changes":       &heap.Changes,
+			"lines_added":   &heap.LinesAdded,
+			"lines_removed": &heap.LinesRemoved,
+		} {
+			if v := pb.Get([]byte(key)); v != nil {
+				*value = binary.BigEndian.Uint64(v)
+			}
+		}
+
+		if v := pb.Get([]byte("files")); v != nil {
+			err = json.NewDecoder(bytes.NewBuffer(v)).Decode(&heap.Files)
+			if err != nil {
+				return err
+			}
+		}
+
+		if v := pb.Get([]byte("tags")); v != nil {
+			err = json.NewDecoder(bytes.NewBuffer(v)).Decode(&heap.Tags)
+			if err != nil {
+				return err
+			}
+		}
+
+		if v := pb.Get([]byte("roles")); v != nil {
+			err = json.NewDecoder(bytes.NewBuffer(v)).Decode(&heap.Roles)
+			if err != nil {
+				return err
+			}
 		}
 
 		return json.NewDecoder(bytes.NewBuffer(pb.Get([]byte("languages")))).Decode(&heap.Languages)
@@ -34,8 +34,20 @@ func (s *Store) NewSession() error {
 	})
 }
 
+// TypingActivity is a key press in an editor, the class of the key is counted so the share of editing and navigation
+// can be reported. The editor version is only stored when it is known. Activity without a key class, like a heartbeat
+// of an editor plugin, is not counted as a key press.
+type TypingActivity struct {
+	Editor        string
+	EditorVersion string
+	KeyClass      string
+}
+
+// Prefix of the keypress counter of each key class
+const keyClassCountPrefix = "keypress_count_"
+
 // This function will react on keypress and update the meta state
-func (s *Store) MetaTypingActivity(editor string) error {
+func (s *Store) MetaTypingActivity(activity TypingActivity) error {
 	return s.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte("meta"))
 		if b == nil {
@@ -49,23 +61,45 @@ func (s *Store) MetaTypingActivity(editor string) error {
 			}
 		}
 
-		var count uint64
-		v := b.Get([]byte("keypress_count"))
-		if v == nil {
+		if activity.KeyClass != "" {
+			var count uint64
+			v := b.Get([]byte("keypress_count"))
+			if v == nil {
+				count = 0
+			} else {
+				count = binary.BigEndian.Uint64(v)
+			}
+
+			// Increment keypress
+			err := b.Put([]byte("keypress_count"), itob(count+1))
+			if err != nil {
+				return err
+			}
+
+			key := []byte(keyClassCountPrefix + activity.KeyClass)
+
 			count = 0
-		} else {
-			count = binary.BigEndian.Uint64(v)
+			if v := b.Get(key); v != nil {
+				count = binary.BigEndian.Uint64(v)
+			}
+
+			err = b.Put(key, itob(count+1))
+			if err != nil {
+				return err
+			}
 		}
 
-		// Increment keypress
-		err := b.Put([]byte("keypress_count"), itob(count+1))
+		err := b.Put([]byte("editors"), appendToBytes(b.Get([]byte("editors")), activity.Editor))
 		if err != nil {
 			return err
 		}
 
-		err = b.Put([]byte("editors"), appendToBytes(b.Get([]byte("editors")), editor))
-		if err != nil {
-			return err
+		if activity.EditorVersion != "" {
+			version := activity.Editor + " " + activity.EditorVersion
+			err = b.Put([]byte("editor_versions"), appendToBytes(b.Get([]byte("editor_versions")), version))
+			if err != nil {
+				return err
+			}
 		}
 
 		err = b.Put([]byte("heap_added_to_queue"), []byte("false"))
@@ -98,7 +132,9 @@ func (s *Store) SentToQueue() error {
 type Meta struct {
 	SessionId        string
 	KeypressCount    uint64
+	KeyClassCounts   map[string]uint64
 	Editors          []string
+	EditorVersions   []string
 	HeapAddedToQueue bool
 	FirstActivity    time.Time
 	LastActivity     time.Time
@@ -121,6 +157,13 @@ func (s *Store) Meta() (Meta, error) {
 		if kpc != nil {
 			result.KeypressCount = binary.BigEndian.Uint64(kpc)
 		}
+
+		result.KeyClassCounts = make(map[string]uint64)
+		c := b.Cursor()
+		prefix := []byte(keyClassCountPrefix)
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			result.KeyClassCounts[string(bytes.TrimPrefix(k, prefix))] = binary.BigEndian.Uint64(v)
+		}
 		result.HeapAddedToQueue, _ = strconv.ParseBool(string(b.Get([]byte("heap_added_to_queue"))))
 
 		v := b.Get([]byte("editors"))
@@ -131,6 +174,14 @@ func (s *Store) Meta() (Meta, error) {
 			}
 		}
 
+		v = b.Get([]byte("editor_versions"))
+		if v != nil {
+			err = json.NewDecoder(bytes.NewBuffer(v)).Decode(&result.EditorVersions)
+			if err != nil {
+				return err
+			}
+		}
+
 		v = b.Get([]byte("first_activity"))
 		if v != nil {
 			result.FirstActivity, err = time.Parse(time.RFC3339, string(v))
@@ -0,0 +1,70 @@
+package watcher
+
+import (
+	"github.com/pacerank/client/internal/inspect"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Buffers that keep changing are reported at most this often, entering and writing a buffer is always reported
+const bufferThrottleInterval = time.Second * 10
+
+// bufferThrottle turns the stream of buffer events of an editor into bursts
+type bufferThrottle struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+func newBufferThrottle() *bufferThrottle {
+	return &bufferThrottle{last: make(map[string]time.Time)}
+}
+
+// Tell if a change to a buffer should be reported
+func (t *bufferThrottle) allow(path string, always bool, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if last, ok := t.last[path]; ok && !always && now.Sub(last) < bufferThrottleInterval {
+		return false
+	}
+
+	// Buffers that have not changed in a while are forgotten
+	for key, last := range t.last {
+		if now.Sub(last) > bufferThrottleInterval*6 {
+			delete(t.last, key)
+		}
+	}
+
+	t.last[path] = now
+	return true
+}
+
+// Attribute a buffer of an editor to a project and language, the file does not need to be saved or be in a watched
+// folder
+func bufferCodeEvent(path string, fileType string) (CodeEvent, error) {
+	fileName := filepath.Base(path)
+
+	language, err := inspect.LanguageByFileType(path, fileName, fileType)
+	if err != nil {
+		return CodeEvent{}, err
+	}
+
+	pi, err := inspect.FileProject(path, "")
+	if err != nil {
+		return CodeEvent{}, err
+	}
+
+	return CodeEvent{
+		Id:       pi.Id,
+		FilePath: pi.FilePath,
+		FileName: fileName,
+		Language: language,
+		Project:  pi.Project,
+		Git:      pi.Git,
+		Branch:   pi.Branch,
+		Tags:     pi.Tags,
+		Role:     inspect.FileRole(path, language, nil),
+		Privacy:  pi.Privacy,
+	}, nil
+}
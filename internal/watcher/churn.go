@@ -0,0 +1,41 @@
+package watcher
+
+import (
+	"github.com/pacerank/client/internal/inspect"
+	"github.com/pacerank/client/internal/store"
+)
+
+// Count the lines added and removed in a saved file since it was saved before. The first time a file is seen only its
+// fingerprint is kept, as there is nothing to compare with. Files of projects that keep their file names private are
+// not fingerprinted at all.
+func LineChurn(storage *store.Store, event CodeEvent) (uint64, uint64, error) {
+	if event.Path == "" {
+		return 0, 0, nil
+	}
+
+	if event.Privacy == inspect.PrivacyNoFilenames || event.Privacy == inspect.PrivacyAnonymous {
+		return 0, 0, storage.DeleteFingerprint(event.Path)
+	}
+
+	current, err := inspect.Fingerprint(event.Path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	previous, found, err := storage.Fingerprint(event.Path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = storage.SaveFingerprint(event.Path, current)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if !found {
+		return 0, 0, nil
+	}
+
+	added, removed := inspect.LineChurn(previous, current)
+	return uint64(added), uint64(removed), nil
+}
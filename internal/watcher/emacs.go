@@ -0,0 +1,337 @@
+package watcher
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Emacs is followed through its server socket, the same way emacsclient evaluates expressions. The server can not push
+// to a client, so hooks are installed that collect buffer events and the client collects them every emacsPollInterval.
+// https://www.gnu.org/software/emacs/manual/html_node/emacs/Emacs-Server.html
+const (
+	emacsPollInterval = time.Second * 2
+
+	// How long evaluating an expression may take
+	emacsTimeout = time.Second * 5
+)
+
+// Installs the hooks once. Entering a buffer is seen from post-command-hook, editing from the modification tick and
+// writing from after-save-hook. At most 100 events are kept, in case the client stops collecting them.
+const emacsInstall = `(unless (fboundp 'pacerank--post-command)
+  (defvar pacerank--events nil)
+  (defvar pacerank--buffer nil)
+  (defvar pacerank--tick nil)
+  (defun pacerank--push (event)
+    (when buffer-file-name
+      (push (list event buffer-file-name (symbol-name major-mode)) pacerank--events)
+      (when (> (length pacerank--events) 100)
+        (setcdr (nthcdr 99 pacerank--events) nil))))
+  (defun pacerank--post-command ()
+    (cond ((not (eq pacerank--buffer (current-buffer)))
+           (setq pacerank--buffer (current-buffer) pacerank--tick (buffer-modified-tick))
+           (pacerank--push "enter"))
+          ((not (eql pacerank--tick (buffer-modified-tick)))
+           (setq pacerank--tick (buffer-modified-tick))
+           (pacerank--push "change"))))
+  (defun pacerank--after-save ()
+    (pacerank--push "write"))
+  (add-hook 'post-command-hook #'pacerank--post-command)
+  (add-hook 'after-save-hook #'pacerank--after-save))`
+
+// Hands over the events collected since the last time, oldest first
+const emacsCollect = `(prog1 (reverse pacerank--events) (setq pacerank--events nil))`
+
+// Follow the buffers of every Emacs server of the user, and report the file of a buffer when it is entered, written
+// or edited
+func Emacs(c CodeCallback) {
+	throttle := newBufferThrottle()
+
+	for {
+		for _, socket := range emacsSockets(os.Getenv, os.Getuid(), filepath.Glob) {
+			events, err := emacsEvents(socket)
+			if err != nil {
+				log.Debug().Err(err).Str("socket", socket).Msg("could not collect emacs buffers")
+				continue
+			}
+
+			now := time.Now()
+			for _, event := range events {
+				if !throttle.allow(event.path, event.event != "change", now) {
+					continue
+				}
+
+				code, err := bufferCodeEvent(event.path, emacsFileType(event.mode))
+				if err != nil {
+					log.Debug().Err(err).Str("path", event.path).Msg("could not attribute emacs buffer")
+					continue
+				}
+
+				c(code)
+			}
+		}
+
+		time.Sleep(emacsPollInterval)
+	}
+}
+
+type emacsEvent struct {
+	event string
+	path  string
+	mode  string
+}
+
+// Find the server sockets of the user, Emacs 27 and later put them in $XDG_RUNTIME_DIR/emacs and earlier versions in
+// /tmp/emacs<uid>. Servers on Windows listen on TCP and are not supported.
+func emacsSockets(env func(string) string, uid int, glob func(string) ([]string, error)) []string {
+	if uid < 0 {
+		return nil
+	}
+
+	var patterns []string
+	if socket := env("EMACS_SOCKET_NAME"); filepath.IsAbs(socket) {
+		patterns = append(patterns, socket)
+	}
+
+	if runtime := env("XDG_RUNTIME_DIR"); runtime != "" {
+		patterns = append(patterns, filepath.Join(runtime, "emacs", "*"))
+	}
+
+	temp := env("TMPDIR")
+	if temp == "" {
+		temp = "/tmp"
+	}
+
+	patterns = append(patterns, filepath.Join(temp, "emacs"+strconv.Itoa(uid), "*"))
+
+	var result []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		matches, err := glob(pattern)
+		if err != nil {
+			continue
+		}
+
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				result = append(result, match)
+			}
+		}
+	}
+
+	return result
+}
+
+// Collect the buffer events of an Emacs server, the hooks are installed the first time
+func emacsEvents(socket string) ([]emacsEvent, error) {
+	output, err := emacsEval(socket, "(progn "+emacsInstall+" "+emacsCollect+")")
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := parseSexp(output)
+	if err != nil {
+		return nil, err
+	}
+
+	list, _ := value.([]interface{})
+
+	var events []emacsEvent
+	for _, item := range list {
+		fields, ok := item.([]interface{})
+		if !ok || len(fields) < 3 {
+			continue
+		}
+
+		event, _ := fields[0].(string)
+		path, _ := fields[1].(string)
+		mode, _ := fields[2].(string)
+		if path == "" {
+			continue
+		}
+
+		events = append(events, emacsEvent{event: event, path: path, mode: mode})
+	}
+
+	return events, nil
+}
+
+// Evaluate an expression in an Emacs server and return what it printed
+func emacsEval(socket string, expression string) (string, error) {
+	conn, err := net.DialTimeout("unix", socket, time.Second)
+	if err != nil {
+		return "", err
+	}
+
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(emacsTimeout))
+
+	_, err = conn.Write([]byte("-eval " + emacsQuote(expression) + "\n"))
+	if err != nil {
+		return "", err
+	}
+
+	return readEmacsReply(bufio.NewReader(conn))
+}
+
+// The server answers with a command per line, the result of an expression is printed in parts
+func readEmacsReply(reader *bufio.Reader) (string, error) {
+	var output strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+
+		command := strings.TrimRight(line, "\n")
+		argument := ""
+		if space := strings.Index(command, " "); space >= 0 {
+			command, argument = command[:space], command[space+1:]
+		}
+
+		switch command {
+		case "-print", "-print-nonl":
+			output.WriteString(emacsUnquote(argument))
+		case "-error":
+			return "", errors.New(fmt.Sprintf("emacs could not evaluate: %s", strings.TrimSpace(emacsUnquote(argument))))
+		}
+
+		// The server closes the connection when the expression is evaluated
+		if err == io.EOF {
+			return output.String(), nil
+		}
+
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// Quote an argument as emacsclient does
+func emacsQuote(s string) string {
+	s = strings.NewReplacer("&", "&&", " ", "&_", "\n", "&n").Replace(s)
+	if strings.HasPrefix(s, "-") {
+		s = "&" + s
+	}
+
+	return s
+}
+
+func emacsUnquote(s string) string {
+	var result strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '&' || i+1 == len(s) {
+			result.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		switch s[i] {
+		case '_':
+			result.WriteByte(' ')
+		case 'n':
+			result.WriteByte('\n')
+		default:
+			result.WriteByte(s[i])
+		}
+	}
+
+	return result.String()
+}
+
+// Turn a major mode into a file type, go-mode and go-ts-mode both become go
+func emacsFileType(mode string) string {
+	mode = strings.TrimSuffix(mode, "-mode")
+	return strings.TrimSuffix(mode, "-ts")
+}
+
+// Parse printed lisp data, only lists, strings and symbols are understood. Lists are []interface{}, strings are
+// string and symbols are emacsSymbol, nil is an empty list.
+func parseSexp(s string) (interface{}, error) {
+	value, rest, err := parseSexpValue(strings.TrimSpace(s))
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(rest) != "" {
+		return nil, errors.New("unexpected data after lisp value")
+	}
+
+	return value, nil
+}
+
+type emacsSymbol string
+
+func parseSexpValue(s string) (interface{}, string, error) {
+	s = strings.TrimLeft(s, " \t\n")
+	if s == "" {
+		return nil, "", errors.New("unexpected end of lisp value")
+	}
+
+	switch s[0] {
+	case '(':
+		list := []interface{}{}
+		s = s[1:]
+		for {
+			s = strings.TrimLeft(s, " \t\n")
+			if s == "" {
+				return nil, "", errors.New("unterminated lisp list")
+			}
+
+			if s[0] == ')' {
+				return list, s[1:], nil
+			}
+
+			value, rest, err := parseSexpValue(s)
+			if err != nil {
+				return nil, "", err
+			}
+
+			list = append(list, value)
+			s = rest
+		}
+	case '"':
+		var result strings.Builder
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+				if i == len(s) {
+					return nil, "", errors.New("unterminated lisp string")
+				}
+
+				if s[i] == 'n' {
+					result.WriteByte('\n')
+				} else {
+					result.WriteByte(s[i])
+				}
+			case '"':
+				return result.String(), s[i+1:], nil
+			default:
+				result.WriteByte(s[i])
+			}
+		}
+
+		return nil, "", errors.New("unterminated lisp string")
+	case ')':
+		return nil, "", errors.New("unexpected end of lisp list")
+	}
+
+	end := strings.IndexAny(s, " \t\n()\"")
+	if end < 0 {
+		end = len(s)
+	}
+
+	if s[:end] == "nil" {
+		return []interface{}{}, s[end:], nil
+	}
+
+	return emacsSymbol(s[:end]), s[end:], nil
+}
@@ -0,0 +1,122 @@
+package watcher
+
+import (
+	"bufio"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Serve a single expression the way an Emacs server does, printing the reply in parts
+func fakeEmacs(t *testing.T, socket string, replies ...string) chan string {
+	listener, err := net.Listen("unix", socket)
+	assert.NoError(t, err, "listening should not result in error")
+
+	expressions := make(chan string, 1)
+	go func() {
+		defer listener.Close()
+
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		expressions <- emacsUnquote(strings.TrimPrefix(strings.TrimSuffix(line, "\n"), "-eval "))
+
+		_, _ = conn.Write([]byte("-emacs-pid 4242\n"))
+		for _, reply := range replies {
+			_, _ = conn.Write([]byte(reply + "\n"))
+		}
+	}()
+
+	return expressions
+}
+
+func TestEmacs_Events(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "server")
+	expressions := fakeEmacs(t, socket,
+		"-print "+emacsQuote(`(("enter" "/home/user/client/main.go" "go-ts-mode")`),
+		"-print-nonl "+emacsQuote(` ("write" "/home/user/client/README.md" "markdown-mode") ("change" nil "fundamental-mode"))`))
+
+	events, err := emacsEvents(socket)
+	assert.NoError(t, err, "collecting events should not result in error")
+	assert.Equal(t, []emacsEvent{
+		{event: "enter", path: "/home/user/client/main.go", mode: "go-ts-mode"},
+		{event: "write", path: "/home/user/client/README.md", mode: "markdown-mode"},
+	}, events)
+
+	expression := <-expressions
+	assert.True(t, strings.HasPrefix(expression, "(progn (unless (fboundp 'pacerank--post-command)"),
+		"hooks should be installed")
+	assert.True(t, strings.HasSuffix(expression, emacsCollect+")"), "events should be collected")
+
+	socket = filepath.Join(t.TempDir(), "server")
+	fakeEmacs(t, socket, "-error "+emacsQuote("void-variable foo"))
+
+	_, err = emacsEvents(socket)
+	assert.Error(t, err, "failing expression should result in error")
+}
+
+func TestEmacs_Quote(t *testing.T) {
+	tests := []string{"(progn 1)", "-flag", "a & b\nc", "&_"}
+
+	for _, test := range tests {
+		quoted := emacsQuote(test)
+		assert.False(t, strings.ContainsAny(quoted, " \n"), "quoted %q should be a single word", test)
+		assert.False(t, strings.HasPrefix(quoted, "-"), "quoted %q should not look like a command", test)
+		assert.Equal(t, test, emacsUnquote(quoted))
+	}
+}
+
+func TestEmacs_ParseSexp(t *testing.T) {
+	value, err := parseSexp(`(("enter" "/tmp/a \"b\".go" go-mode) nil)`)
+	assert.NoError(t, err, "parsing should not result in error")
+	assert.Equal(t, []interface{}{
+		[]interface{}{"enter", `/tmp/a "b".go`, emacsSymbol("go-mode")},
+		[]interface{}{},
+	}, value)
+
+	value, err = parseSexp("nil\n")
+	assert.NoError(t, err, "parsing nil should not result in error")
+	assert.Equal(t, []interface{}{}, value)
+
+	for _, invalid := range []string{"", "(a", `("a`, ")", "(a) b"} {
+		_, err = parseSexp(invalid)
+		assert.Error(t, err, "parsing %q should result in error", invalid)
+	}
+}
+
+func TestEmacs_FileType(t *testing.T) {
+	assert.Equal(t, "go", emacsFileType("go-mode"))
+	assert.Equal(t, "python", emacsFileType("python-ts-mode"))
+	assert.Equal(t, "emacs-lisp", emacsFileType("emacs-lisp-mode"))
+}
+
+func TestEmacs_Sockets(t *testing.T) {
+	env := map[string]string{
+		"EMACS_SOCKET_NAME": "/run/user/1000/emacs/server",
+		"XDG_RUNTIME_DIR":   "/run/user/1000",
+	}
+
+	glob := func(pattern string) ([]string, error) {
+		switch pattern {
+		case "/run/user/1000/emacs/server":
+			return []string{pattern}, nil
+		case "/run/user/1000/emacs/*":
+			return []string{"/run/user/1000/emacs/server", "/run/user/1000/emacs/work"}, nil
+		case "/tmp/emacs1000/*":
+			return []string{"/tmp/emacs1000/server"}, nil
+		}
+
+		return nil, nil
+	}
+
+	sockets := emacsSockets(func(key string) string { return env[key] }, 1000, glob)
+	assert.Equal(t, []string{"/run/user/1000/emacs/server", "/run/user/1000/emacs/work", "/tmp/emacs1000/server"}, sockets)
+	assert.Empty(t, emacsSockets(func(key string) string { return env[key] }, -1, glob), "windows should not have sockets")
+}
@@ -0,0 +1,374 @@
+package watcher
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/pacerank/client/internal/inspect"
+	"github.com/rs/zerolog/log"
+	uuid "github.com/satori/go.uuid"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// Editor plugins reach the client on this address, e.g. by setting api_url = http://127.0.0.1:35872/api/v1 in
+	// ~/.wakatime.cfg for WakaTime plugins
+	HeartbeatAddress = "127.0.0.1:35872"
+
+	// Names of the socket and the token in ~/.pacerank
+	heartbeatSocketName = "heartbeat.sock"
+	heartbeatTokenName  = "heartbeat.token"
+
+	// Bulk requests of WakaTime plugins hold at most 25 heartbeats, this is plenty
+	heartbeatMaxBody = 1 << 20
+
+	// Changes a heartbeat can count, more is not typing and is counted as this many
+	heartbeatMaxChanges = 10000
+)
+
+// Heartbeat is an editor plugin telling which file is being worked on. The fields follow the WakaTime heartbeat schema,
+// so WakaTime plugins can be pointed at the client.
+// https://wakatime.com/developers#heartbeats
+type Heartbeat struct {
+	Entity         string  `json:"entity"`
+	Type           string  `json:"type"`
+	Category       string  `json:"category,omitempty"`
+	Time           float64 `json:"time"`
+	Project        string  `json:"project,omitempty"`
+	Branch         string  `json:"branch,omitempty"`
+	Language       string  `json:"language,omitempty"`
+	IsWrite        bool    `json:"is_write"`
+	Lines          int     `json:"lines,omitempty"`
+	LineNumber     int     `json:"lineno,omitempty"`
+	CursorPosition int     `json:"cursorpos,omitempty"`
+	UserAgent      string  `json:"user_agent,omitempty"`
+
+	// Changes made to the file since the last heartbeat, this is not part of the WakaTime schema
+	Changes int `json:"changes,omitempty"`
+}
+
+// HeartbeatEvent is a heartbeat together with the editor that sent it. Code is only set when the heartbeat is about a
+// file that could be attributed to a project and language.
+type HeartbeatEvent struct {
+	Heartbeat     Heartbeat
+	Editor        string
+	EditorVersion string
+	Code          *CodeEvent
+	Err           error
+}
+
+type HeartbeatCallback func(event HeartbeatEvent)
+
+type heartbeatHandler struct {
+	token string
+	c     HeartbeatCallback
+}
+
+type heartbeatResponse struct {
+	Data heartbeatData `json:"data"`
+}
+
+type heartbeatData struct {
+	Id string `json:"id"`
+	Heartbeat
+}
+
+// Serve heartbeats of editor plugins on a unix socket in ~/.pacerank and on HeartbeatAddress. Requests must carry the
+// token of ~/.pacerank/heartbeat.token, as a bearer token or base64 encoded as basic authorization like WakaTime does.
+func Heartbeats(c HeartbeatCallback) {
+	usr, err := user.Current()
+	if err != nil {
+		c(HeartbeatEvent{Err: err})
+		return
+	}
+
+	directory := filepath.Join(usr.HomeDir, ".pacerank")
+
+	token, err := heartbeatToken(filepath.Join(directory, heartbeatTokenName))
+	if err != nil {
+		c(HeartbeatEvent{Err: err})
+		return
+	}
+
+	server := &http.Server{
+		Handler:      &heartbeatHandler{token: token, c: c},
+		ReadTimeout:  time.Second * 10,
+		WriteTimeout: time.Second * 10,
+	}
+
+	socket := filepath.Join(directory, heartbeatSocketName)
+	_ = os.Remove(socket)
+
+	unixListener, err := net.Listen("unix", socket)
+	if err != nil {
+		log.Error().Err(err).Msg("could not listen for heartbeats on unix socket")
+	} else {
+		_ = os.Chmod(socket, 0600)
+
+		go func() {
+			err := server.Serve(unixListener)
+			if err != nil && err != http.ErrServerClosed {
+				c(HeartbeatEvent{Err: err})
+			}
+		}()
+	}
+
+	tcpListener, err := net.Listen("tcp", HeartbeatAddress)
+	if err != nil {
+		c(HeartbeatEvent{Err: err})
+		return
+	}
+
+	err = server.Serve(tcpListener)
+	if err != nil && err != http.ErrServerClosed {
+		c(HeartbeatEvent{Err: err})
+	}
+}
+
+// Send heartbeats to the client over its unix socket, for commands that run next to the client
+func SendHeartbeats(heartbeats []Heartbeat) error {
+	usr, err := user.Current()
+	if err != nil {
+		return err
+	}
+
+	directory := filepath.Join(usr.HomeDir, ".pacerank")
+
+	token, err := ioutil.ReadFile(filepath.Join(directory, heartbeatTokenName))
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(heartbeats)
+	if err != nil {
+		return err
+	}
+
+	socket := filepath.Join(directory, heartbeatSocketName)
+	client := &http.Client{
+		Timeout: time.Second * 5,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+
+	request, err := http.NewRequest(http.MethodPost, "http://pacerank/api/v1/users/current/heartbeats.bulk", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusCreated {
+		return errors.New(fmt.Sprintf("client refused heartbeats with status %d", response.StatusCode))
+	}
+
+	return nil
+}
+
+// Read the token from file, a random token is created the first time. It is a UUID as WakaTime plugins refuse api keys
+// in any other format.
+func heartbeatToken(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err == nil && len(strings.TrimSpace(string(b))) > 0 {
+		return strings.TrimSpace(string(b)), nil
+	}
+
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	token := uuid.NewV4().String()
+	err = ioutil.WriteFile(path, []byte(token), 0600)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (h *heartbeatHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	bulk := strings.HasSuffix(r.URL.Path, "/users/current/heartbeats.bulk")
+	if !bulk && !strings.HasSuffix(r.URL.Path, "/users/current/heartbeats") {
+		http.NotFound(w, r)
+		return
+	}
+
+	if !h.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var heartbeats []Heartbeat
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, heartbeatMaxBody))
+
+	var err error
+	if bulk {
+		err = decoder.Decode(&heartbeats)
+	} else {
+		var heartbeat Heartbeat
+		err = decoder.Decode(&heartbeat)
+		heartbeats = append(heartbeats, heartbeat)
+	}
+
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid heartbeat: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	for i := range heartbeats {
+		if heartbeats[i].Changes < 0 {
+			http.Error(w, "invalid heartbeat: changes can not be negative", http.StatusBadRequest)
+			return
+		}
+
+		if heartbeats[i].Changes > heartbeatMaxChanges {
+			heartbeats[i].Changes = heartbeatMaxChanges
+		}
+	}
+
+	var responses [][]interface{}
+	for _, heartbeat := range heartbeats {
+		if heartbeat.UserAgent == "" {
+			heartbeat.UserAgent = r.UserAgent()
+		}
+
+		h.c(heartbeatEvent(heartbeat))
+
+		response := heartbeatResponse{Data: heartbeatData{Id: uuid.NewV4().String(), Heartbeat: heartbeat}}
+		responses = append(responses, []interface{}{response, http.StatusCreated})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+
+	if bulk {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"responses": responses})
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(responses[0][0])
+}
+
+// Accept the token as a bearer token, or as basic authorization where WakaTime sends the api key base64 encoded
+func (h *heartbeatHandler) authorized(r *http.Request) bool {
+	authorization := r.Header.Get("Authorization")
+
+	var token string
+	switch {
+	case strings.HasPrefix(authorization, "Bearer "):
+		token = strings.TrimPrefix(authorization, "Bearer ")
+	case strings.HasPrefix(authorization, "Basic "):
+		b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(authorization, "Basic "))
+		if err != nil {
+			return false
+		}
+
+		token = string(b)
+	default:
+		token = r.URL.Query().Get("api_key")
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+// Turn a heartbeat into an event, the editor is found from the user agent of the plugin
+func heartbeatEvent(heartbeat Heartbeat) HeartbeatEvent {
+	event := HeartbeatEvent{Heartbeat: heartbeat}
+	event.Editor, event.EditorVersion = heartbeatEditor(heartbeat.UserAgent)
+
+	code, err := heartbeatCode(heartbeat)
+	if err != nil {
+		log.Debug().Err(err).Str("entity", heartbeat.Entity).Msg("could not attribute heartbeat")
+		return event
+	}
+
+	event.Code = &code
+	return event
+}
+
+// WakaTime plugins tell the editor in the user agent, as in
+// "wakatime/v1.35.4 (linux-5.10.0-x86_64) go1.17 vscode/1.62.0 vscode-wakatime/17.1.0"
+func heartbeatEditor(userAgent string) (string, string) {
+	for _, field := range strings.Fields(userAgent) {
+		slash := strings.Index(field, "/")
+		if slash <= 0 {
+			continue
+		}
+
+		if editor, ok := inspect.EditorByAlias(field[:slash]); ok {
+			return editor, field[slash+1:]
+		}
+	}
+
+	return "", ""
+}
+
+// Find the project and language of the file in a heartbeat, the language of the plugin is used when it is given
+func heartbeatCode(heartbeat Heartbeat) (CodeEvent, error) {
+	if heartbeat.Type != "" && heartbeat.Type != "file" {
+		return CodeEvent{}, errors.New(fmt.Sprintf("heartbeat of type %s is not about a file", heartbeat.Type))
+	}
+
+	if heartbeat.Entity == "" {
+		return CodeEvent{}, errors.New("heartbeat is missing the entity")
+	}
+
+	fileName := filepath.Base(heartbeat.Entity)
+
+	language, err := inspect.LanguageByPlugin(heartbeat.Entity, fileName, heartbeat.Language)
+	if err != nil {
+		return CodeEvent{}, err
+	}
+
+	pi, err := inspect.FileProject(heartbeat.Entity, heartbeat.Project)
+	if err != nil {
+		return CodeEvent{}, err
+	}
+
+	// Anonymous projects do not tell their branch, not even the one of the plugin
+	branch := pi.Branch
+	if heartbeat.Branch != "" && pi.Privacy != inspect.PrivacyAnonymous {
+		branch = heartbeat.Branch
+	}
+
+	return CodeEvent{
+		Id:       pi.Id,
+		FilePath: pi.FilePath,
+		FileName: fileName,
+		Language: language,
+		Project:  pi.Project,
+		Git:      pi.Git,
+		Branch:   branch,
+		Tags:     pi.Tags,
+		Role:     inspect.FileRole(heartbeat.Entity, language, nil),
+		Privacy:  pi.Privacy,
+	}, nil
+}
@@ -0,0 +1,134 @@
+package watcher
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testUserAgent = "wakatime/v1.35.4 (linux-5.10.0-x86_64) go1.17 vscode/1.62.0 vscode-wakatime/17.1.0"
+
+func TestHeartbeat_Handler(t *testing.T) {
+	var events []HeartbeatEvent
+	handler := &heartbeatHandler{token: "secret", c: func(event HeartbeatEvent) {
+		events = append(events, event)
+	}}
+
+	post := func(path string, authorization string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		request.Header.Set("Authorization", authorization)
+		request.Header.Set("User-Agent", testUserAgent)
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// Files are attributed from their repository, they need to be on disk with their directory
+	dir := t.TempDir()
+	entity := func(name string) string {
+		b, _ := json.Marshal(filepath.Join(dir, name))
+		return string(b)
+	}
+
+	basic := "Basic " + base64.StdEncoding.EncodeToString([]byte("secret"))
+
+	response := post("/api/v1/users/current/heartbeats", "Basic "+base64.StdEncoding.EncodeToString([]byte("wrong")), `{}`)
+	assert.Equal(t, http.StatusUnauthorized, response.Code, "wrong token should be refused")
+	assert.Empty(t, events, "refused heartbeat should not be reported")
+
+	response = post("/api/v1/users/current/heartbeats", basic,
+		`{"entity": `+entity("main.go")+`, "type": "file", "time": 1609459200.5, "project": "client", "is_write": true, "lines": 120, "lineno": 42}`)
+	assert.Equal(t, http.StatusCreated, response.Code, "heartbeat should be created")
+
+	var single struct {
+		Data struct {
+			Id     string `json:"id"`
+			Entity string `json:"entity"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &single), "decoding response should not result in error")
+	assert.NotEmpty(t, single.Data.Id, "created heartbeat should have an id")
+	assert.Equal(t, filepath.Join(dir, "main.go"), single.Data.Entity, "created heartbeat should be echoed")
+
+	assert.Len(t, events, 1, "heartbeat should be reported")
+	assert.Equal(t, "Visual Studio Code", events[0].Editor, "editor should be found from the user agent")
+	assert.Equal(t, "1.62.0", events[0].EditorVersion, "editor version should be found from the user agent")
+	assert.Equal(t, 42, events[0].Heartbeat.LineNumber)
+	assert.True(t, events[0].Heartbeat.IsWrite)
+	assert.NotNil(t, events[0].Code, "heartbeat of a file should be attributed")
+	assert.Equal(t, "client", events[0].Code.Project)
+	assert.Equal(t, "go", events[0].Code.Language)
+
+	response = post("/users/current/heartbeats.bulk", "Bearer secret",
+		`[{"entity": `+entity("lib.rs")+`, "type": "file", "project": "engine", "language": "Rust"}, {"entity": "github.com", "type": "domain"}, `+
+			`{"entity": "main.go", "type": "file", "project": "client"}]`)
+	assert.Equal(t, http.StatusCreated, response.Code, "bulk heartbeats should be created")
+
+	var bulk struct {
+		Responses [][]json.RawMessage `json:"responses"`
+	}
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &bulk), "decoding bulk response should not result in error")
+	assert.Len(t, bulk.Responses, 3, "every heartbeat should have a response")
+	assert.Equal(t, "201", string(bulk.Responses[0][1]), "every heartbeat should be created")
+
+	assert.Len(t, events, 4, "bulk heartbeats should be reported one by one")
+	assert.Equal(t, "rust", events[1].Code.Language, "language of the plugin should be used")
+	assert.Nil(t, events[2].Code, "heartbeat of a domain should not be attributed")
+	assert.Nil(t, events[3].Code, "heartbeat of a file without its directory should not be attributed")
+
+	response = post("/api/v1/users/current/heartbeats", basic, `{"entity": `+entity("main.go")+`, "type": "file", "changes": -1}`)
+	assert.Equal(t, http.StatusBadRequest, response.Code, "negative changes should be refused")
+	assert.Len(t, events, 4, "refused heartbeat should not be reported")
+
+	response = post("/api/v1/users/current/heartbeats", basic, `{"entity": `+entity("main.go")+`, "type": "file", "changes": 99999999}`)
+	assert.Equal(t, http.StatusCreated, response.Code, "heartbeat with many changes should be created")
+	assert.Equal(t, heartbeatMaxChanges, events[4].Heartbeat.Changes, "changes should be capped")
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "node_modules", "left-pad"), 0755),
+		"creating directory should not result in error")
+	response = post("/api/v1/users/current/heartbeats", basic,
+		`{"entity": `+entity("node_modules/left-pad/index.js")+`, "type": "file", "language": "JavaScript"}`)
+	assert.Equal(t, http.StatusCreated, response.Code, "heartbeat of a dependency should be created")
+	assert.Nil(t, events[5].Code, "dependency should not be attributed even when the plugin names its language")
+
+	response = post("/api/v1/users/current/summaries", basic, `{}`)
+	assert.Equal(t, http.StatusNotFound, response.Code, "other endpoints should not be served")
+}
+
+func TestHeartbeat_Token(t *testing.T) {
+	path := filepath.Join(t.TempDir(), heartbeatTokenName)
+
+	token, err := heartbeatToken(path)
+	assert.NoError(t, err, "creating token should not result in error")
+	assert.Len(t, token, 36, "token should be a uuid")
+
+	again, err := heartbeatToken(path)
+	assert.NoError(t, err, "reading token should not result in error")
+	assert.Equal(t, token, again, "token should be kept between runs")
+}
+
+func TestHeartbeat_Editor(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		editor    string
+		version   string
+	}{
+		{testUserAgent, "Visual Studio Code", "1.62.0"},
+		{"wakatime/v1.35.4 (linux-5.10.0-x86_64) go1.17 vim/8.2.2434 vim-wakatime/9.0.1", "VIM", "8.2.2434"},
+		{"wakatime/v1.35.4 (linux-5.10.0-x86_64) go1.17 neovim/0.5.1 vim-wakatime/9.0.1", "Neovim", "0.5.1"},
+		{"wakatime/v1.35.4 (windows-10.0.19041-x86_64) go1.17 unknown/1.0 unknown-wakatime/1.0", "", ""},
+	}
+
+	for _, test := range tests {
+		editor, version := heartbeatEditor(test.userAgent)
+		assert.Equal(t, test.editor, editor, test.userAgent)
+		assert.Equal(t, test.version, version, test.userAgent)
+	}
+}
@@ -0,0 +1,215 @@
+package watcher
+
+import (
+	"bytes"
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5/plumbing/format/config"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/rs/zerolog/log"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ignoreRules tells which paths are not code activity, from ignoreDirectories and the gitignore rules of the
+// repository a path is in. Repositories apply their .gitignore files, .git/info/exclude and the excludes files of the
+// git config of the user and the system, like git does. The excludes file of the user defaults to
+// ~/.config/git/ignore.
+type ignoreRules struct {
+	mu           sync.Mutex
+	global       []gitignore.Pattern
+	repositories map[string]gitignore.Matcher
+}
+
+func newIgnoreRules() *ignoreRules {
+	root := osfs.New(string(filepath.Separator))
+
+	var global []gitignore.Pattern
+	usr, err := user.Current()
+	if err != nil {
+		log.Debug().Err(err).Msg("could not find home directory for global git excludes")
+	} else {
+		global = readExclude(userExcludesFile(usr.HomeDir, os.Getenv("XDG_CONFIG_HOME")), nil)
+	}
+
+	system, err := gitignore.LoadSystemPatterns(root)
+	if err != nil {
+		log.Debug().Err(err).Msg("could not read system git excludes")
+	}
+
+	return &ignoreRules{
+		global:       append(system, global...),
+		repositories: make(map[string]gitignore.Matcher),
+	}
+}
+
+// Tell if a path should be ignored
+func (r *ignoreRules) ignored(path string, isDir bool) bool {
+	if ignoreDirectory(path) {
+		return true
+	}
+
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+
+	root, ok := repositoryRoot(path)
+	if !ok {
+		return false
+	}
+
+	relative, err := filepath.Rel(root, path)
+	if err != nil || relative == "." {
+		return false
+	}
+
+	return r.matcher(root).Match(strings.Split(filepath.ToSlash(relative), "/"), isDir)
+}
+
+// Forget the rules of a repository when one of its ignore files changes, they are read again when needed. Returns
+// true when rules were forgotten, so what is watched should be looked at again.
+func (r *ignoreRules) changed(path string) bool {
+	if filepath.Base(path) != ".gitignore" && !strings.HasSuffix(filepath.ToSlash(path), ".git/info/exclude") {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var forgotten bool
+	for root := range r.repositories {
+		if strings.HasPrefix(path, root+string(filepath.Separator)) {
+			delete(r.repositories, root)
+			forgotten = true
+		}
+	}
+
+	return forgotten
+}
+
+func (r *ignoreRules) matcher(root string) gitignore.Matcher {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if matcher, ok := r.repositories[root]; ok {
+		return matcher
+	}
+
+	// Later patterns take priority, so the excludes of the user come first and the .gitignore files last
+	patterns := append([]gitignore.Pattern{}, r.global...)
+	patterns = append(patterns, readExclude(filepath.Join(root, ".git", "info", "exclude"), nil)...)
+	patterns = readIgnoreFiles(root, nil, patterns)
+
+	matcher := gitignore.NewMatcher(patterns)
+	r.repositories[root] = matcher
+	return matcher
+}
+
+// Read the .gitignore files of a repository directory by directory, appended to the patterns read so far. Directories
+// that are ignored already, like dependencies and build output, are not looked into. Like git, files in them can not be
+// un-ignored by a .gitignore of their own. Directories that can not be read are skipped.
+func readIgnoreFiles(root string, domain []string, patterns []gitignore.Pattern) []gitignore.Pattern {
+	directory := filepath.Join(append([]string{root}, domain...)...)
+	patterns = append(patterns, readExclude(filepath.Join(directory, ".gitignore"), domain)...)
+
+	entries, err := ioutil.ReadDir(directory)
+	if err != nil {
+		log.Debug().Err(err).Str("directory", directory).Msg("could not read directory for .gitignore files")
+		return patterns
+	}
+
+	matcher := gitignore.NewMatcher(patterns)
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == ".git" {
+			continue
+		}
+
+		path := append(append([]string{}, domain...), entry.Name())
+		if ignoreDirectory(filepath.Join(directory, entry.Name())) || matcher.Match(path, true) {
+			continue
+		}
+
+		patterns = readIgnoreFiles(root, path, patterns)
+	}
+
+	return patterns
+}
+
+// Read a file of gitignore patterns, the domain is the directory of the file relative to the repository
+func readExclude(path string, domain []string) []gitignore.Pattern {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var result []gitignore.Pattern
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		result = append(result, gitignore.ParsePattern(line, domain))
+	}
+
+	return result
+}
+
+// Find the excludes file of the user like git does, core.excludesFile of the git config of the user or else
+// $XDG_CONFIG_HOME/git/ignore, where XDG_CONFIG_HOME defaults to ~/.config
+func userExcludesFile(home string, xdgConfigHome string) string {
+	if xdgConfigHome == "" {
+		xdgConfigHome = filepath.Join(home, ".config")
+	}
+
+	var excludesFile string
+
+	// ~/.gitconfig is read last, so its setting wins like in git
+	for _, path := range []string{filepath.Join(xdgConfigHome, "git", "config"), filepath.Join(home, ".gitconfig")} {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		raw := config.New()
+		err = config.NewDecoder(bytes.NewReader(b)).Decode(raw)
+		if err != nil {
+			log.Debug().Err(err).Str("path", path).Msg("could not read git config")
+			continue
+		}
+
+		if value := raw.Section("core").Options.Get("excludesfile"); value != "" {
+			excludesFile = value
+		}
+	}
+
+	if excludesFile == "" {
+		return filepath.Join(xdgConfigHome, "git", "ignore")
+	}
+
+	if strings.HasPrefix(excludesFile, "~/") {
+		return filepath.Join(home, excludesFile[2:])
+	}
+
+	return excludesFile
+}
+
+// Find the root of the repository a path is in, the directory that holds .git
+func repositoryRoot(path string) (string, bool) {
+	for directory := path; ; {
+		if _, err := os.Stat(filepath.Join(directory, ".git")); err == nil {
+			return directory, true
+		}
+
+		parent := filepath.Dir(directory)
+		if parent == directory {
+			return "", false
+		}
+
+		directory = parent
+	}
+}
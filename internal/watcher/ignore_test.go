@@ -0,0 +1,159 @@
+package watcher
+
+import (
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testRepository(t *testing.T) string {
+	root := t.TempDir()
+
+	files := map[string]string{
+		".gitignore":          "dist/\n*.pyc\n!keep.pyc\n",
+		".git/info/exclude":   "# local\nsecret/\n",
+		"api/.gitignore":      "build\n",
+		"api/main.go":         "package main\n",
+		"api/build/api":       "",
+		"dist/bundle.js":      "",
+		"secret/notes.go":     "",
+		"web/app.js":          "",
+		"web/node_modules/x":  "",
+		"tools/cache.pyc":     "",
+		"tools/keep.pyc":      "",
+		"tools/build/main.go": "",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755), "creating directory should not result in error")
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644), "writing file should not result in error")
+	}
+
+	return root
+}
+
+func TestWatcher_IgnoreRules(t *testing.T) {
+	root := testRepository(t)
+	rules := newIgnoreRules()
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"api/main.go", false, false},
+		{"api/build", true, true},
+		{"api/build/api", false, true},
+		{"tools/build/main.go", false, false},
+		{"dist", true, true},
+		{"dist/bundle.js", false, true},
+		{"secret/notes.go", false, true},
+		{"web/app.js", false, false},
+		{"web/node_modules", true, true},
+		{"tools/cache.pyc", false, true},
+		{"tools/keep.pyc", false, false},
+	}
+
+	for _, test := range tests {
+		ignored := rules.ignored(filepath.Join(root, filepath.FromSlash(test.path)), test.isDir)
+		assert.Equal(t, test.ignored, ignored, test.path)
+	}
+
+	// Changing a .gitignore is seen the next time
+	path := filepath.Join(root, "web", "app.js")
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, "web", ".gitignore"), []byte("app.js\n"), 0644),
+		"writing .gitignore should not result in error")
+	assert.True(t, rules.changed(filepath.Join(root, "web", ".gitignore")), "changed .gitignore should forget rules")
+	assert.True(t, rules.ignored(path, false), "changed .gitignore should be applied")
+	assert.False(t, rules.changed(path), "changed source file should not forget rules")
+}
+
+func TestWatcher_WatchList(t *testing.T) {
+	root := testRepository(t)
+
+	var relative []string
+	for _, directory := range watchList(root, newIgnoreRules()) {
+		path, err := filepath.Rel(root, directory)
+		assert.NoError(t, err, "relative path should not result in error")
+		relative = append(relative, filepath.ToSlash(path))
+	}
+
+	assert.ElementsMatch(t, []string{".", "api", "tools", "tools/build", "web"}, relative)
+}
+
+func TestWatcher_WatchListChanged(t *testing.T) {
+	root := testRepository(t)
+	rules := newIgnoreRules()
+
+	assert.NotContains(t, watchList(root, rules), filepath.Join(root, "dist"), "ignored directory should not be watched")
+
+	// Un-ignoring a directory while watching makes the rebuilt watch set include it
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, ".gitignore"), []byte("*.pyc\n"), 0644),
+		"writing .gitignore should not result in error")
+	assert.True(t, rules.changed(filepath.Join(root, ".gitignore")), "changed .gitignore should forget rules")
+	assert.Contains(t, watchList(root, rules), filepath.Join(root, "dist"), "un-ignored directory should be watched")
+}
+
+func TestWatcher_UserExcludesFile(t *testing.T) {
+	home := t.TempDir()
+
+	assert.Equal(t, filepath.Join(home, ".config", "git", "ignore"), userExcludesFile(home, ""),
+		"excludes file should default to ~/.config/git/ignore")
+	assert.Equal(t, filepath.Join(home, "xdg", "git", "ignore"), userExcludesFile(home, filepath.Join(home, "xdg")),
+		"excludes file should follow XDG_CONFIG_HOME")
+
+	write := func(name, content string) {
+		path := filepath.Join(home, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755), "creating directory should not result in error")
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644), "writing file should not result in error")
+	}
+
+	write(".config/git/config", "[core]\n\texcludesFile = ~/.xdg-excludes\n")
+	assert.Equal(t, filepath.Join(home, ".xdg-excludes"), userExcludesFile(home, ""),
+		"excludes file should be read from the git config in XDG_CONFIG_HOME")
+
+	write(".gitconfig", "[user]\n\tname = someone\n[core]\n\texcludesfile = ~/.gitignore_global\n")
+	assert.Equal(t, filepath.Join(home, ".gitignore_global"), userExcludesFile(home, ""),
+		"excludes file of ~/.gitconfig should win")
+}
+
+func TestWatcher_ReadIgnoreFiles(t *testing.T) {
+	root := t.TempDir()
+
+	files := map[string]string{
+		".gitignore":               "build/\n",
+		"build/.gitignore":         "!out.js\n",
+		"build/out.js":             "",
+		"node_modules/.gitignore":  "!index.js\n",
+		"node_modules/index.js":    "",
+		"locked/main.go":           "",
+		"src/.gitignore":           "# scratch files\n*.tmp\n",
+		"src/scratch.tmp":          "",
+		"src/deep/nested/.gitkeep": "",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755), "creating directory should not result in error")
+		assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0644), "writing file should not result in error")
+	}
+
+	// Without permission to list it, the directory that comes before src should not stop the walk. The owner can read
+	// anything as root, so the directory is only locked when not running as root.
+	if os.Geteuid() > 0 {
+		assert.NoError(t, os.Chmod(filepath.Join(root, "locked"), 0), "locking directory should not result in error")
+		defer os.Chmod(filepath.Join(root, "locked"), 0755)
+	}
+
+	patterns := readIgnoreFiles(root, nil, nil)
+	assert.Len(t, patterns, 2, "only the .gitignore files outside ignored directories should be read")
+
+	matcher := gitignore.NewMatcher(patterns)
+	assert.True(t, matcher.Match([]string{"build", "out.js"}, false), "file in ignored directory should stay ignored")
+	assert.True(t, matcher.Match([]string{"src", "scratch.tmp"}, false), ".gitignore of a subdirectory should be read")
+	assert.False(t, matcher.Match([]string{"scratch.tmp"}, false), ".gitignore of a subdirectory should only apply there")
+}
@@ -0,0 +1,311 @@
+package watcher
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// This is a minimal MessagePack codec, it only handles what the Neovim RPC API sends and receives.
+// https://github.com/msgpack/msgpack/blob/master/spec.md
+
+// Longest string, array or map that is read, so a broken peer can not make the client allocate without bounds
+const msgpackMaxLength = 1 << 24
+
+// msgpackExt is an extension value, Neovim uses them for buffer, window and tabpage handles
+type msgpackExt struct {
+	Type int8
+	Data []byte
+}
+
+// Append the encoding of a value, integers are written in their smallest form
+func msgpackAppend(b []byte, value interface{}) ([]byte, error) {
+	var err error
+
+	switch v := value.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if v {
+			return append(b, 0xc3), nil
+		}
+
+		return append(b, 0xc2), nil
+	case int:
+		return msgpackAppendInt(b, int64(v)), nil
+	case int64:
+		return msgpackAppendInt(b, v), nil
+	case uint32:
+		return msgpackAppendInt(b, int64(v)), nil
+	case string:
+		return msgpackAppendString(b, v), nil
+	case []byte:
+		return append(msgpackAppendSize(b, len(v), 0xc4, 0xc5, 0xc6), v...), nil
+	case []string:
+		b = msgpackAppendArrayHeader(b, len(v))
+		for _, item := range v {
+			b = msgpackAppendString(b, item)
+		}
+
+		return b, nil
+	case []interface{}:
+		b = msgpackAppendArrayHeader(b, len(v))
+		for _, item := range v {
+			b, err = msgpackAppend(b, item)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return b, nil
+	case map[string]interface{}:
+		if len(v) < 16 {
+			b = append(b, 0x80|byte(len(v)))
+		} else {
+			b = msgpackAppendSize(b, len(v), 0, 0xde, 0xdf)
+		}
+
+		for key, item := range v {
+			b = msgpackAppendString(b, key)
+			b, err = msgpackAppend(b, item)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return b, nil
+	}
+
+	return nil, errors.New(fmt.Sprintf("can not encode %T as msgpack", value))
+}
+
+func msgpackAppendInt(b []byte, v int64) []byte {
+	switch {
+	case v >= -32 && v <= math.MaxInt8:
+		return append(b, byte(v))
+	case v >= math.MinInt8 && v <= math.MaxInt8:
+		return append(b, 0xd0, byte(v))
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		return append(b, 0xd1, byte(v>>8), byte(v))
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		return append(b, 0xd2, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+	}
+
+	b = append(b, 0xd3, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint64(b[len(b)-8:], uint64(v))
+	return b
+}
+
+func msgpackAppendString(b []byte, v string) []byte {
+	if len(v) < 32 {
+		return append(append(b, 0xa0|byte(len(v))), v...)
+	}
+
+	return append(msgpackAppendSize(b, len(v), 0xd9, 0xda, 0xdb), v...)
+}
+
+func msgpackAppendArrayHeader(b []byte, length int) []byte {
+	if length < 16 {
+		return append(b, 0x90|byte(length))
+	}
+
+	return msgpackAppendSize(b, length, 0, 0xdc, 0xdd)
+}
+
+// Append the format with a length of 8, 16 or 32 bits, a zero format means the size is not available
+func msgpackAppendSize(b []byte, length int, size8 byte, size16 byte, size32 byte) []byte {
+	switch {
+	case size8 != 0 && length <= math.MaxUint8:
+		return append(b, size8, byte(length))
+	case length <= math.MaxUint16:
+		return append(b, size16, byte(length>>8), byte(length))
+	}
+
+	return append(b, size32, byte(length>>24), byte(length>>16), byte(length>>8), byte(length))
+}
+
+// Read the next value. Integers are int64, or uint64 when they do not fit, maps are map[string]interface{} and
+// extension values are msgpackExt.
+func msgpackRead(r *bufio.Reader) (interface{}, error) {
+	code, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code >= 0x80 && code <= 0x8f:
+		return msgpackReadMap(r, int(code&0x0f))
+	case code >= 0x90 && code <= 0x9f:
+		return msgpackReadArray(r, int(code&0x0f))
+	case code >= 0xa0 && code <= 0xbf:
+		return msgpackReadString(r, int(code&0x1f))
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		length, err := msgpackReadLength(r, 1<<(code-0xc4))
+		if err != nil {
+			return nil, err
+		}
+
+		return msgpackReadBytes(r, length)
+	case 0xc7, 0xc8, 0xc9:
+		length, err := msgpackReadLength(r, 1<<(code-0xc7))
+		if err != nil {
+			return nil, err
+		}
+
+		return msgpackReadExt(r, length)
+	case 0xca:
+		b, err := msgpackReadBytes(r, 4)
+		if err != nil {
+			return nil, err
+		}
+
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 0xcb:
+		b, err := msgpackReadBytes(r, 8)
+		if err != nil {
+			return nil, err
+		}
+
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		b, err := msgpackReadBytes(r, 1<<(code-0xcc))
+		if err != nil {
+			return nil, err
+		}
+
+		v := msgpackUint(b)
+		if v > math.MaxInt64 {
+			return v, nil
+		}
+
+		return int64(v), nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (code - 0xd0)
+		b, err := msgpackReadBytes(r, size)
+		if err != nil {
+			return nil, err
+		}
+
+		// Sign extend from the size of the value
+		shift := uint(64 - size*8)
+		return int64(msgpackUint(b)<<shift) >> shift, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return msgpackReadExt(r, 1<<(code-0xd4))
+	case 0xd9, 0xda, 0xdb:
+		length, err := msgpackReadLength(r, 1<<(code-0xd9))
+		if err != nil {
+			return nil, err
+		}
+
+		return msgpackReadString(r, length)
+	case 0xdc, 0xdd:
+		length, err := msgpackReadLength(r, 2<<(code-0xdc))
+		if err != nil {
+			return nil, err
+		}
+
+		return msgpackReadArray(r, length)
+	case 0xde, 0xdf:
+		length, err := msgpackReadLength(r, 2<<(code-0xde))
+		if err != nil {
+			return nil, err
+		}
+
+		return msgpackReadMap(r, length)
+	}
+
+	return nil, errors.New(fmt.Sprintf("invalid msgpack format 0x%x", code))
+}
+
+func msgpackUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+
+	return v
+}
+
+func msgpackReadLength(r *bufio.Reader, size int) (int, error) {
+	b, err := msgpackReadBytes(r, size)
+	if err != nil {
+		return 0, err
+	}
+
+	length := msgpackUint(b)
+	if length > msgpackMaxLength {
+		return 0, errors.New(fmt.Sprintf("msgpack value of length %d is too long", length))
+	}
+
+	return int(length), nil
+}
+
+func msgpackReadBytes(r *bufio.Reader, length int) ([]byte, error) {
+	b := make([]byte, length)
+	_, err := io.ReadFull(r, b)
+	return b, err
+}
+
+func msgpackReadString(r *bufio.Reader, length int) (string, error) {
+	b, err := msgpackReadBytes(r, length)
+	return string(b), err
+}
+
+func msgpackReadExt(r *bufio.Reader, length int) (msgpackExt, error) {
+	typ, err := r.ReadByte()
+	if err != nil {
+		return msgpackExt{}, err
+	}
+
+	b, err := msgpackReadBytes(r, length)
+	return msgpackExt{Type: int8(typ), Data: b}, err
+}
+
+func msgpackReadArray(r *bufio.Reader, length int) ([]interface{}, error) {
+	result := make([]interface{}, 0, length)
+	for i := 0; i < length; i++ {
+		v, err := msgpackRead(r)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, v)
+	}
+
+	return result, nil
+}
+
+func msgpackReadMap(r *bufio.Reader, length int) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, length)
+	for i := 0; i < length; i++ {
+		key, err := msgpackRead(r)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := msgpackRead(r)
+		if err != nil {
+			return nil, err
+		}
+
+		result[fmt.Sprint(key)] = value
+	}
+
+	return result, nil
+}
@@ -0,0 +1,73 @@
+package watcher
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestMsgpack_RoundTrip(t *testing.T) {
+	long := strings.Repeat("a", 300)
+
+	tests := []struct {
+		value    interface{}
+		expected interface{}
+	}{
+		{nil, nil},
+		{true, true},
+		{false, false},
+		{0, int64(0)},
+		{-1, int64(-1)},
+		{-33, int64(-33)},
+		{200, int64(200)},
+		{-40000, int64(-40000)},
+		{int64(1) << 40, int64(1) << 40},
+		{int64(-1) << 40, int64(-1) << 40},
+		{uint32(70000), int64(70000)},
+		{"BufEnter", "BufEnter"},
+		{long, long},
+		{[]byte{1, 2, 3}, []byte{1, 2, 3}},
+		{[]string{"a", "b"}, []interface{}{"a", "b"}},
+		{[]interface{}{0, "nvim_command", []interface{}{"echo"}}, []interface{}{int64(0), "nvim_command", []interface{}{"echo"}}},
+		{map[string]interface{}{"version": 1}, map[string]interface{}{"version": int64(1)}},
+	}
+
+	for _, test := range tests {
+		b, err := msgpackAppend(nil, test.value)
+		assert.NoError(t, err, "encoding %v should not result in error", test.value)
+
+		value, err := msgpackRead(bufio.NewReader(bytes.NewReader(b)))
+		assert.NoError(t, err, "decoding %v should not result in error", test.value)
+		assert.Equal(t, test.expected, value)
+	}
+}
+
+func TestMsgpack_Read(t *testing.T) {
+	tests := []struct {
+		data     []byte
+		expected interface{}
+	}{
+		{[]byte{0xcc, 0xff}, int64(255)},
+		{[]byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, uint64(1<<64 - 1)},
+		{[]byte{0xd0, 0x80}, int64(-128)},
+		{[]byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}, 1.5},
+		{[]byte{0xd4, 0x00, 0x01}, msgpackExt{Type: 0, Data: []byte{1}}},
+	}
+
+	for _, test := range tests {
+		value, err := msgpackRead(bufio.NewReader(bytes.NewReader(test.data)))
+		assert.NoError(t, err, "decoding should not result in error")
+		assert.Equal(t, test.expected, value)
+	}
+
+	_, err := msgpackRead(bufio.NewReader(bytes.NewReader([]byte{0xdb, 0xff, 0xff, 0xff, 0xff})))
+	assert.Error(t, err, "too long string should result in error")
+
+	_, err = msgpackRead(bufio.NewReader(bytes.NewReader([]byte{0x92, 0x01})))
+	assert.Error(t, err, "truncated array should result in error")
+
+	_, err = msgpackAppend(nil, 1.5)
+	assert.Error(t, err, "encoding unsupported type should result in error")
+}
@@ -0,0 +1,385 @@
+package watcher
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/pacerank/client/pkg/system"
+	"github.com/rs/zerolog/log"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Neovim is followed over its msgpack-RPC API, see :help rpc and :help api in Neovim
+const (
+	// How often running Neovim instances are looked for
+	neovimDiscoveryInterval = time.Second * 10
+
+	// How long a call to Neovim may take
+	neovimCallTimeout = time.Second * 5
+
+	// Method of the notifications Neovim sends on buffer events, and the autocommand group that sends them
+	neovimMethod  = "pacerank_buffer"
+	neovimAugroup = "pacerank"
+)
+
+// Autocommand events that are subscribed to. Entering and writing a buffer is always reported, editing is throttled.
+var (
+	neovimEvents     = []string{"BufEnter", "BufWritePost", "TextChanged", "InsertLeave"}
+	neovimEditEvents = map[string]bool{"TextChanged": true, "InsertLeave": true}
+)
+
+// msgpack-RPC message types
+const (
+	rpcRequest      = 0
+	rpcResponse     = 1
+	rpcNotification = 2
+)
+
+type neovimResult struct {
+	value interface{}
+	err   error
+}
+
+type neovimClient struct {
+	conn    io.ReadWriteCloser
+	mu      sync.Mutex
+	msgid   uint32
+	pending map[uint32]chan neovimResult
+	notify  func(method string, params []interface{})
+	done    chan struct{}
+}
+
+// Follow the buffers of every Neovim instance that can be reached, and report the file of a buffer when it is entered,
+// written or edited
+func Neovim(sys system.System, c CodeCallback) {
+	throttle := newBufferThrottle()
+	clients := make(map[string]*neovimClient)
+
+	notify := func(method string, params []interface{}) {
+		neovimBuffer(throttle, c, method, params, time.Now())
+	}
+
+	for {
+		processes, err := sys.Processes()
+		if err != nil {
+			log.Debug().Err(err).Msg("could not list processes to find neovim")
+		}
+
+		for _, address := range neovimAddresses(os.Getenv, processes, filepath.Glob) {
+			if _, ok := clients[address]; ok {
+				continue
+			}
+
+			client, err := dialNeovim(address, notify)
+			if err != nil {
+				log.Debug().Err(err).Str("address", address).Msg("could not connect to neovim")
+				continue
+			}
+
+			err = client.subscribe()
+			if err != nil {
+				log.Debug().Err(err).Str("address", address).Msg("could not subscribe to neovim buffers")
+				_ = client.Close()
+				continue
+			}
+
+			log.Debug().Str("address", address).Msg("following neovim buffers")
+			clients[address] = client
+		}
+
+		for address, client := range clients {
+			select {
+			case <-client.done:
+				delete(clients, address)
+			default:
+			}
+		}
+
+		time.Sleep(neovimDiscoveryInterval)
+	}
+}
+
+// Find the addresses Neovim listens on. Inside its terminals Neovim sets $NVIM, it listens on the address given with
+// --listen, and since 0.8 it always listens on nvim.<pid>.0 in the run directory.
+func neovimAddresses(env func(string) string, processes []*system.Process, glob func(string) ([]string, error)) []string {
+	var result []string
+	seen := make(map[string]bool)
+
+	add := func(address string) {
+		if address != "" && !seen[address] {
+			seen[address] = true
+			result = append(result, address)
+		}
+	}
+
+	add(env("NVIM"))
+	add(env("NVIM_LISTEN_ADDRESS"))
+
+	temp := env("TMPDIR")
+	if temp == "" {
+		temp = "/tmp"
+	}
+
+	for _, process := range processes {
+		if strings.TrimSuffix(strings.ToLower(process.FileName), ".exe") != "nvim" {
+			continue
+		}
+
+		for i, arg := range process.CommandLine {
+			if arg == "--listen" && i+1 < len(process.CommandLine) {
+				add(process.CommandLine[i+1])
+			}
+		}
+
+		socket := "nvim." + strconv.FormatInt(process.ProcessID, 10) + ".0"
+
+		patterns := []string{filepath.Join(temp, "nvim.*", "*", socket)}
+		if runtime := env("XDG_RUNTIME_DIR"); runtime != "" {
+			patterns = append([]string{filepath.Join(runtime, socket)}, patterns...)
+		}
+
+		for _, pattern := range patterns {
+			matches, err := glob(pattern)
+			if err != nil {
+				continue
+			}
+
+			for _, match := range matches {
+				add(match)
+			}
+		}
+	}
+
+	return result
+}
+
+// Connect to a unix socket or to a host:port address
+func dialNeovim(address string, notify func(method string, params []interface{})) (*neovimClient, error) {
+	network := "unix"
+	if _, _, err := net.SplitHostPort(address); err == nil && !strings.ContainsAny(address, `/\`) {
+		network = "tcp"
+	}
+
+	conn, err := net.DialTimeout(network, address, time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	return newNeovimClient(conn, notify), nil
+}
+
+func newNeovimClient(conn io.ReadWriteCloser, notify func(method string, params []interface{})) *neovimClient {
+	client := &neovimClient{
+		conn:    conn,
+		pending: make(map[uint32]chan neovimResult),
+		notify:  notify,
+		done:    make(chan struct{}),
+	}
+
+	go client.read()
+	return client
+}
+
+// Remove the autocommands of the client and close the connection. The removal is sent as a notification, Neovim handles
+// it before it sees the connection close.
+func (c *neovimClient) Close() error {
+	b, err := msgpackAppend(nil, []interface{}{rpcNotification, "nvim_command", []interface{}{"silent! autocmd! " + neovimAugroup}})
+	if err == nil {
+		c.mu.Lock()
+		_, _ = c.conn.Write(b)
+		c.mu.Unlock()
+	}
+
+	return c.conn.Close()
+}
+
+// Add autocommands that notify the client on buffer events, and report the buffer that is open right now. The
+// autocommands of an earlier run are replaced. When the client is gone without removing them, the first notification
+// that fails removes them, so the user does not get an error on every buffer event.
+func (c *neovimClient) subscribe() error {
+	info, err := c.call("nvim_get_api_info")
+	if err != nil {
+		return err
+	}
+
+	values, ok := info.([]interface{})
+	if !ok || len(values) == 0 {
+		return errors.New("invalid reply to nvim_get_api_info")
+	}
+
+	channel, ok := values[0].(int64)
+	if !ok {
+		return errors.New("neovim did not tell the channel of the client")
+	}
+
+	commands := []string{"augroup " + neovimAugroup, "autocmd!", "augroup END"}
+	for _, event := range neovimEvents {
+		commands = append(commands, fmt.Sprintf("autocmd %s %s * try | call rpcnotify(%d, '%s', '%s', expand('<afile>:p'), "+
+			"getbufvar(str2nr(expand('<abuf>')), '&filetype'), getbufvar(str2nr(expand('<abuf>')), '&buftype')) | "+
+			"catch | silent! autocmd! %s | endtry",
+			neovimAugroup, event, channel, neovimMethod, event, neovimAugroup))
+	}
+
+	for _, command := range commands {
+		_, err = c.call("nvim_command", command)
+		if err != nil {
+			return err
+		}
+	}
+
+	current, err := c.call("nvim_eval", "[expand('%:p'), &filetype, &buftype]")
+	if err != nil {
+		return err
+	}
+
+	if values, ok := current.([]interface{}); ok {
+		c.notify(neovimMethod, append([]interface{}{"BufEnter"}, values...))
+	}
+
+	return nil
+}
+
+// Call an API function and wait for the result
+func (c *neovimClient) call(method string, args ...interface{}) (interface{}, error) {
+	if args == nil {
+		args = []interface{}{}
+	}
+
+	result := make(chan neovimResult, 1)
+
+	c.mu.Lock()
+	c.msgid++
+	id := c.msgid
+	c.pending[id] = result
+
+	b, err := msgpackAppend(nil, []interface{}{rpcRequest, int64(id), method, args})
+	if err == nil {
+		_, err = c.conn.Write(b)
+	}
+
+	if err != nil {
+		delete(c.pending, id)
+	}
+	c.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case r := <-result:
+		return r.value, r.err
+	case <-c.done:
+		return nil, errors.New("connection to neovim was closed")
+	case <-time.After(neovimCallTimeout):
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return nil, errors.New(fmt.Sprintf("neovim did not answer %s in time", method))
+	}
+}
+
+// Read messages until the connection is closed, responses are handed to the waiting call and notifications to notify
+func (c *neovimClient) read() {
+	defer close(c.done)
+
+	reader := bufio.NewReader(c.conn)
+	for {
+		message, err := msgpackRead(reader)
+		if err != nil {
+			if err != io.EOF {
+				log.Debug().Err(err).Msg("connection to neovim failed")
+			}
+
+			return
+		}
+
+		fields, ok := message.([]interface{})
+		if !ok || len(fields) < 3 {
+			continue
+		}
+
+		typ, _ := fields[0].(int64)
+		switch typ {
+		case rpcResponse:
+			if len(fields) < 4 {
+				continue
+			}
+
+			id, _ := fields[1].(int64)
+
+			c.mu.Lock()
+			result, ok := c.pending[uint32(id)]
+			delete(c.pending, uint32(id))
+			c.mu.Unlock()
+
+			if ok {
+				result <- neovimResult{value: fields[3], err: neovimError(fields[2])}
+			}
+		case rpcNotification:
+			method, _ := fields[1].(string)
+			params, _ := fields[2].([]interface{})
+			c.notify(method, params)
+		case rpcRequest:
+			// The client does not serve any methods
+			b, err := msgpackAppend(nil, []interface{}{rpcResponse, fields[1], []interface{}{0, "not supported"}, nil})
+			if err != nil {
+				continue
+			}
+
+			c.mu.Lock()
+			_, _ = c.conn.Write(b)
+			c.mu.Unlock()
+		}
+	}
+}
+
+// Errors are sent as [type, message]
+func neovimError(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+
+	if fields, ok := value.([]interface{}); ok && len(fields) == 2 {
+		if message, ok := fields[1].(string); ok {
+			return errors.New(message)
+		}
+	}
+
+	return errors.New(fmt.Sprintf("neovim returned error %v", value))
+}
+
+// Report the buffer of a notification, scratch, help and terminal buffers have a buftype and are left out
+func neovimBuffer(throttle *bufferThrottle, c CodeCallback, method string, params []interface{}, now time.Time) {
+	if method != neovimMethod || len(params) < 4 {
+		return
+	}
+
+	event, _ := params[0].(string)
+	path, _ := params[1].(string)
+	fileType, _ := params[2].(string)
+	bufferType, _ := params[3].(string)
+
+	if path == "" || bufferType != "" {
+		return
+	}
+
+	if !throttle.allow(path, !neovimEditEvents[event], now) {
+		return
+	}
+
+	code, err := bufferCodeEvent(path, fileType)
+	if err != nil {
+		log.Debug().Err(err).Str("path", path).Msg("could not attribute neovim buffer")
+		return
+	}
+
+	c(code)
+}
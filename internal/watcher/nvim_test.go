@@ -0,0 +1,166 @@
+package watcher
+
+import (
+	"bufio"
+	"github.com/pacerank/client/pkg/system"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNeovim answers the calls of a client the way Neovim does, and sends a buffer notification once subscribed
+type fakeNeovim struct {
+	conn     net.Conn
+	mu       sync.Mutex
+	commands []string
+}
+
+func (n *fakeNeovim) serve(path string) {
+	reader := bufio.NewReader(n.conn)
+	for {
+		message, err := msgpackRead(reader)
+		if err != nil {
+			return
+		}
+
+		fields := message.([]interface{})
+		if fields[0].(int64) == rpcNotification {
+			n.command(fields[2].([]interface{})[0].(string))
+			continue
+		}
+
+		method := fields[2].(string)
+		params := fields[3].([]interface{})
+
+		var result interface{}
+		switch method {
+		case "nvim_get_api_info":
+			result = []interface{}{3, map[string]interface{}{}}
+		case "nvim_command":
+			n.command(params[0].(string))
+		case "nvim_eval":
+			result = []interface{}{"", "", "nofile"}
+		}
+
+		b, _ := msgpackAppend(nil, []interface{}{rpcResponse, fields[1], nil, result})
+		_, _ = n.conn.Write(b)
+
+		if method == "nvim_eval" {
+			b, _ = msgpackAppend(nil, []interface{}{rpcNotification, neovimMethod, []interface{}{"BufWritePost", path, "go", ""}})
+			_, _ = n.conn.Write(b)
+		}
+	}
+}
+
+func (n *fakeNeovim) command(command string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.commands = append(n.commands, command)
+}
+
+func (n *fakeNeovim) received() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return append([]string{}, n.commands...)
+}
+
+func TestNeovim_Client(t *testing.T) {
+	path, err := filepath.Abs("nvim.go")
+	assert.NoError(t, err, "finding path should not result in error")
+
+	server, conn := net.Pipe()
+	fake := &fakeNeovim{conn: server}
+	go fake.serve(path)
+
+	events := make(chan CodeEvent, 1)
+	throttle := newBufferThrottle()
+	client := newNeovimClient(conn, func(method string, params []interface{}) {
+		neovimBuffer(throttle, func(event CodeEvent) {
+			events <- event
+		}, method, params, time.Now())
+	})
+	assert.NoError(t, client.subscribe(), "subscribing should not result in error")
+
+	select {
+	case event := <-events:
+		assert.Equal(t, "nvim.go", event.FileName, "written buffer should be reported")
+		assert.Equal(t, "go", event.Language, "language should be found from the file type")
+	case <-time.After(time.Second * 5):
+		t.Fatal("written buffer was not reported")
+	}
+
+	commands := fake.received()
+	assert.Equal(t, "augroup pacerank", commands[0], "autocommands should be grouped")
+	assert.Len(t, commands, 3+len(neovimEvents), "every event should have an autocommand")
+	assert.True(t, strings.Contains(commands[3], "try | call rpcnotify(3, 'pacerank_buffer', 'BufEnter'"),
+		"autocommand should notify the channel of the client")
+	assert.True(t, strings.HasSuffix(commands[3], "catch | silent! autocmd! pacerank | endtry"),
+		"autocommand should remove the group when the client is gone")
+
+	// The pipe blocks until the notification is read
+	go func() {
+		_ = client.Close()
+	}()
+
+	assert.Eventually(t, func() bool {
+		commands := fake.received()
+		return commands[len(commands)-1] == "silent! autocmd! pacerank"
+	}, time.Second*5, time.Millisecond*10, "closing should remove the autocommands")
+}
+
+func TestNeovim_Buffer(t *testing.T) {
+	path, err := filepath.Abs("nvim.go")
+	assert.NoError(t, err, "finding path should not result in error")
+
+	var events []CodeEvent
+	c := func(event CodeEvent) {
+		events = append(events, event)
+	}
+
+	throttle := newBufferThrottle()
+	now := time.Now()
+
+	neovimBuffer(throttle, c, neovimMethod, []interface{}{"BufEnter", path, "go", ""}, now)
+	neovimBuffer(throttle, c, neovimMethod, []interface{}{"TextChanged", path, "go", ""}, now.Add(time.Second))
+	neovimBuffer(throttle, c, neovimMethod, []interface{}{"BufWritePost", path, "go", ""}, now.Add(time.Second*2))
+	neovimBuffer(throttle, c, neovimMethod, []interface{}{"TextChanged", path, "go", ""}, now.Add(time.Second*15))
+	neovimBuffer(throttle, c, neovimMethod, []interface{}{"BufEnter", "", "", ""}, now)
+	neovimBuffer(throttle, c, neovimMethod, []interface{}{"BufEnter", "/help.txt", "help", "help"}, now)
+	neovimBuffer(throttle, c, "other", []interface{}{"BufEnter", path, "go", ""}, now)
+
+	assert.Len(t, events, 3, "edits should be throttled and buffers without a file left out")
+}
+
+func TestNeovim_Addresses(t *testing.T) {
+	env := map[string]string{
+		"NVIM":            "/run/user/1000/nvim.10.0",
+		"XDG_RUNTIME_DIR": "/run/user/1000",
+	}
+
+	processes := []*system.Process{
+		{ProcessID: 10, FileName: "nvim"},
+		{ProcessID: 20, FileName: "nvim", CommandLine: []string{"nvim", "--listen", "127.0.0.1:6666"}},
+		{ProcessID: 30, FileName: "vim"},
+	}
+
+	var patterns []string
+	glob := func(pattern string) ([]string, error) {
+		patterns = append(patterns, pattern)
+		if pattern == "/run/user/1000/nvim.20.0" {
+			return []string{pattern}, nil
+		}
+
+		return nil, nil
+	}
+
+	addresses := neovimAddresses(func(key string) string { return env[key] }, processes, glob)
+	assert.Equal(t, []string{"/run/user/1000/nvim.10.0", "127.0.0.1:6666", "/run/user/1000/nvim.20.0"}, addresses)
+	assert.Contains(t, patterns, "/tmp/nvim.*/*/nvim.10.0", "run directory of older versions should be searched")
+	assert.Len(t, patterns, 4, "only neovim processes should be searched for")
+}
@@ -0,0 +1,60 @@
+package watcher
+
+import (
+	"context"
+	"github.com/pacerank/client/internal/inspect"
+	"github.com/pacerank/client/pkg/system"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+// EditorProcessEvent tells that an editor has started or exited
+type EditorProcessEvent struct {
+	Type      system.ProcessEventType
+	Editor    string
+	ProcessID int64
+	Time      time.Time
+}
+
+type EditorProcessCallback func(event EditorProcessEvent)
+
+// Follow the processes of the system and report editors that start and exit. Editors are known by their executable,
+// the system tells about process changes so processes are not listed again and again.
+func EditorProcesses(sys system.System, c EditorProcessCallback) {
+	running, err := sys.Processes()
+	if err != nil {
+		log.Debug().Err(err).Msg("could not list running editors")
+	}
+
+	followEditors(running, sys.WatchProcesses(context.Background()), c)
+}
+
+// Report editors from process events until the events end, running are the processes from before the events
+func followEditors(running []*system.Process, events <-chan system.ProcessEvent, c EditorProcessCallback) {
+	editors := make(map[int64]string)
+	for _, process := range running {
+		if editor, ok := inspect.Editor(process.Executable, nil); ok {
+			editors[process.ProcessID] = editor
+		}
+	}
+
+	for event := range events {
+		// A process that calls exec starts again with another executable, an editor that was running in it is gone
+		if previous, ok := editors[event.ProcessID]; ok {
+			delete(editors, event.ProcessID)
+			c(EditorProcessEvent{Type: system.ProcessExited, Editor: previous, ProcessID: event.ProcessID, Time: event.Time})
+		}
+
+		if event.Type != system.ProcessStarted || event.Process == nil {
+			continue
+		}
+
+		editor, ok := inspect.Editor(event.Process.Executable, nil)
+		if !ok {
+			continue
+		}
+
+		editors[event.ProcessID] = editor
+		c(EditorProcessEvent{Type: system.ProcessStarted, Editor: editor, ProcessID: event.ProcessID, Time: event.Time})
+	}
+}
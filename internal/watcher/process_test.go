@@ -0,0 +1,72 @@
+package watcher
+
+import (
+	"context"
+	"github.com/pacerank/client/pkg/system"
+	"github.com/pacerank/client/pkg/system/systemtest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestProcess_FollowEditors(t *testing.T) {
+	sys := systemtest.New()
+	sys.AddProcess(&system.Process{ProcessID: 10, FileName: "goland", Executable: "/opt/goland/bin/goland"})
+
+	running, err := sys.Processes()
+	assert.NoError(t, err, "listing processes should not result in error")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := sys.WatchProcesses(ctx)
+
+	var reported []EditorProcessEvent
+	done := make(chan struct{})
+	go func() {
+		followEditors(running, events, func(event EditorProcessEvent) {
+			reported = append(reported, event)
+		})
+		close(done)
+	}()
+
+	sys.StartProcess(&system.Process{ProcessID: 20, FileName: "go", Executable: "/usr/local/go/bin/go"})
+	sys.StartProcess(&system.Process{ProcessID: 30, FileName: "nvim", Executable: "/usr/bin/nvim"})
+	sys.ExitProcess(20)
+	sys.ExitProcess(10)
+
+	cancel()
+	<-done
+
+	assert.Equal(t, []EditorProcessEvent{
+		{Type: system.ProcessStarted, Editor: "Neovim", ProcessID: 30},
+		{Type: system.ProcessExited, Editor: "GoLand", ProcessID: 10},
+	}, withoutTime(reported), "only editors should be reported")
+}
+
+func TestProcess_FollowEditorsExec(t *testing.T) {
+	events := make(chan system.ProcessEvent, 2)
+
+	// A shell runs exec nvim, then nvim runs exec on something else
+	events <- system.ProcessEvent{Type: system.ProcessStarted, ProcessID: 10, Process: &system.Process{Executable: "/usr/bin/nvim"}}
+	events <- system.ProcessEvent{Type: system.ProcessStarted, ProcessID: 10, Process: &system.Process{Executable: "/usr/bin/bash"}}
+	close(events)
+
+	var reported []EditorProcessEvent
+	followEditors(nil, events, func(event EditorProcessEvent) {
+		reported = append(reported, event)
+	})
+
+	assert.Equal(t, []EditorProcessEvent{
+		{Type: system.ProcessStarted, Editor: "Neovim", ProcessID: 10},
+		{Type: system.ProcessExited, Editor: "Neovim", ProcessID: 10},
+	}, withoutTime(reported), "editor that is replaced by exec should exit")
+}
+
+func withoutTime(events []EditorProcessEvent) []EditorProcessEvent {
+	var result []EditorProcessEvent
+	for _, event := range events {
+		event.Time = time.Time{}
+		result = append(result, event)
+	}
+
+	return result
+}
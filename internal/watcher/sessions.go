@@ -2,127 +2,326 @@ package watcher
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/pacerank/client/internal/store"
 	"github.com/pacerank/client/pkg/model"
+	"github.com/pacerank/client/pkg/system"
 	"github.com/rs/zerolog/log"
 	"strconv"
 	"time"
 )
 
+const (
+	// Heaps are sent to queue when there has not been any editor activity for this long
+	queueThreshold = time.Minute * 3
+
+	// A new session is started when there has not been any editor activity for this long
+	sessionThreshold = time.Minute * 30
+
+	// The session is closed right away when the machine has not had any input for this long
+	idleThreshold = time.Minute * 2
+
+	// The session is closed right away when the wall clock and the monotonic clock differ by this much
+	clockJumpThreshold = time.Second * 30
+
+	// Fingerprints of files that have not been saved for this long are pruned when heaps are queued
+	fingerprintLifetime = time.Hour * 24 * 30
+)
+
+// Category that the keypress count of each key class is reported with
+var keyClassCategories = map[system.KeyClass]model.Category{
+	system.KeyCharacter:  model.CategoryKeyCountCharacter,
+	system.KeyEdit:       model.CategoryKeyCountEdit,
+	system.KeyNavigation: model.CategoryKeyCountNavigation,
+	system.KeyModifier:   model.CategoryKeyCountModifier,
+	system.KeyShortcut:   model.CategoryKeyCountShortcut,
+}
+
+// IdleSource tells if the user is away from the machine
+type IdleSource interface {
+	IdleTime() (time.Duration, error)
+	ScreenLocked() (bool, error)
+}
+
 // Function is used to see if a session has passed threshold times
 // if it has, it will add the session to the send queue and update
-// the state with new data.
-func Sessions(storage *store.Store) {
+// the state with new data. Sleep, screen lock and clock changes
+// closes the session right away, so it never spans time away.
+func Sessions(storage *store.Store, idle IdleSource, power <-chan system.PowerEvent) {
+	ticker := time.NewTicker(time.Second * 5)
+	defer ticker.Stop()
+
+	previous := time.Now()
+
 	// Infinite loop that checks if any time thresholds has been met
 	for {
-		time.Sleep(time.Second * 5)
+		select {
+		case event, ok := <-power:
+			if !ok {
+				power = nil
+				continue
+			}
+
+			closeSession(storage, fmt.Sprintf("machine reported %s", event.Type))
+		case <-ticker.C:
+			now := time.Now()
+			if clockJumped(now.Round(0).Sub(previous.Round(0)), now.Sub(previous)) {
+				closeSession(storage, "clock has jumped")
+			} else {
+				checkSession(storage, idle, now)
+			}
+
+			previous = now
+		}
+	}
+}
+
+// Check the thresholds of the current session
+func checkSession(storage *store.Store, idle IdleSource, now time.Time) {
+	meta, err := storage.Meta()
+	if err != nil {
+		return
+	}
+
+	away := isAway(idle)
+	queue, reset := sessionAction(meta, away, now)
+
+	if queue {
+		err = queueHeaps(storage, meta)
+		if err != nil {
+			log.Error().Err(err).Msg("could not queue heaps")
+			return
+		}
+	}
 
-		meta, err := storage.Meta()
+	if reset {
+		err = storage.NewSession()
 		if err != nil {
-			continue
+			log.Error().Err(err).Msg("could not start a new session")
+			return
 		}
 
-		// Send heap to queue if the last activity surpass x minutes and hasn't been sent already.
-		if time.Now().Add(-time.Minute*3).After(meta.LastActivity) && !meta.HeapAddedToQueue {
-			heaps, err := storage.Heaps()
-			if err != nil {
-				log.Error().Err(err).Msg("couldn't get any heaps")
-				continue
-			}
+		if away {
+			log.Info().Msg("machine is idle or locked, clear session")
+		} else {
+			log.Info().Msg("30 minutes has passed, clear session")
+		}
+	}
+}
+
+// Queue what has been done in the session and start a new one
+func closeSession(storage *store.Store, reason string) {
+	meta, err := storage.Meta()
+	if err != nil {
+		return
+	}
+
+	if !meta.HeapAddedToQueue {
+		err = queueHeaps(storage, meta)
+		if err != nil {
+			log.Error().Err(err).Msg("could not queue heaps")
+			return
+		}
+	}
+
+	err = storage.NewSession()
+	if err != nil {
+		log.Error().Err(err).Msg("could not start a new session")
+		return
+	}
+
+	log.Info().Msgf("%s, clear session", reason)
+}
+
+// The monotonic clock stops while the machine sleeps and is not changed when the wall clock is set, so a difference
+// between the wall clock and monotonic time since the previous check means the session times can not be trusted
+func clockJumped(wall time.Duration, elapsed time.Duration) bool {
+	gap := wall - elapsed
+	return gap > clockJumpThreshold || gap < -clockJumpThreshold
+}
+
+// Decide if heaps should be sent to queue, and if a new session should be started. A machine that is idle or locked
+// closes the session right away, otherwise it is decided by the time since last editor activity.
+func sessionAction(meta store.Meta, away bool, now time.Time) (queue bool, reset bool) {
+	if away {
+		return !meta.HeapAddedToQueue, true
+	}
+
+	queue = now.Add(-queueThreshold).After(meta.LastActivity) && !meta.HeapAddedToQueue
+	reset = now.Add(-sessionThreshold).After(meta.LastActivity)
+	return queue, reset
+}
+
+// Check if the user is away, either the screen is locked or there has not been any input for a while. If the idle
+// source can not tell, the user is not considered away and the activity thresholds decide.
+func isAway(idle IdleSource) bool {
+	if idle == nil {
+		return false
+	}
+
+	locked, err := idle.ScreenLocked()
+	if err != nil {
+		log.Debug().Err(err).Msg("could not get screen lock state")
+	}
+
+	if locked {
+		return true
+	}
+
+	duration, err := idle.IdleTime()
+	if err != nil {
+		log.Debug().Err(err).Msg("could not get idle time")
+		return false
+	}
+
+	return duration >= idleThreshold
+}
+
+// Turn every heap into a record and add it to the queue, then mark the heaps of the session as queued
+func queueHeaps(storage *store.Store, meta store.Meta) error {
+	heaps, err := storage.Heaps()
+	if err != nil {
+		log.Debug().Err(err).Msg("couldn't get any heaps")
+		return nil
+	}
+
+	// If no heaps, there is nothing to queue
+	if len(heaps) == 0 {
+		return nil
+	}
+
+	for _, heapId := range heaps {
+		heap, err := storage.HeapById(heapId)
+		if err != nil {
+			log.Error().Err(err).Msgf("couldn't get heap by id %s", heapId)
+			break
+		}
 
-			// If no heaps, continue
-			if len(heaps) == 0 {
+		record := model.Record{
+			Start:     meta.FirstActivity,
+			Stop:      meta.LastActivity,
+			Activity:  model.ActivityCoding,
+			SessionId: meta.SessionId,
+			Labels: []model.Label{
+				{
+					Category: model.CategoryProject,
+					Value:    heap.Project,
+				},
+				{
+					Category: model.CategoryBranch,
+					Value:    heap.Branch,
+				},
+				{
+					Category: model.CategoryGit,
+					Value:    heap.Git,
+				},
+				{
+					Category: model.CategoryKeyCount,
+					Value:    strconv.FormatUint(meta.KeypressCount, 10),
+				},
+			},
+		}
+
+		counts := []struct {
+			category model.Category
+			value    uint64
+		}{
+			{model.CategoryChangeCount, heap.Changes},
+			{model.CategoryLinesAdded, heap.LinesAdded},
+			{model.CategoryLinesRemoved, heap.LinesRemoved},
+		}
+
+		for _, count := range counts {
+			if count.value == 0 {
 				continue
 			}
 
-			for _, heapId := range heaps {
-				heap, err := storage.HeapById(heapId)
-				if err != nil {
-					log.Error().Err(err).Msgf("couldn't get heap by id %s", heapId)
-					break
-				}
-
-				record := model.Record{
-					Start:     meta.FirstActivity,
-					Stop:      meta.LastActivity,
-					Activity:  model.ActivityCoding,
-					SessionId: meta.SessionId,
-					Labels: []model.Label{
-						{
-							Category: model.CategoryProject,
-							Value:    heap.Project,
-						},
-						{
-							Category: model.CategoryBranch,
-							Value:    heap.Branch,
-						},
-						{
-							Category: model.CategoryGit,
-							Value:    heap.Git,
-						},
-						{
-							Category: model.CategoryKeyCount,
-							Value:    strconv.FormatUint(meta.KeypressCount, 10),
-						},
-					},
-				}
-
-				for _, file := range heap.Files {
-					record.Labels = append(record.Labels, model.Label{
-						Category: model.CategoryFilename,
-						Value:    file,
-					})
-				}
-
-				for _, language := range heap.Languages {
-					record.Labels = append(record.Labels, model.Label{
-						Category: model.CategoryLanguage,
-						Value:    language,
-					})
-				}
-
-				for _, editor := range meta.Editors {
-					record.Labels = append(record.Labels, model.Label{
-						Category: model.CategoryEditor,
-						Value:    editor,
-					})
-				}
-
-				b, err := json.Marshal(record)
-				if err != nil {
-					log.Error().Err(err).Msg("could not marshal record into byte array")
-					break
-				}
-
-				err = storage.AddToQueue(b)
-				if err != nil {
-					log.Error().Err(err).Msg("could not add record to queue")
-					break
-				}
-
-				err = storage.DeleteHeap(heapId)
-				if err != nil {
-					log.Error().Err(err).Msg("could not delete heap by id")
-					break
-				}
-			}
+			record.Labels = append(record.Labels, model.Label{
+				Category: count.category,
+				Value:    strconv.FormatUint(count.value, 10),
+			})
+		}
 
-			// Updated so that heap is added to queue (Skip queueing heap data that has already been queued)
-			err = storage.SentToQueue()
-			if err != nil {
-				log.Error().Err(err).Msg("could not update heap_added_to_queue state")
+		for _, file := range heap.Files {
+			record.Labels = append(record.Labels, model.Label{
+				Category: model.CategoryFilename,
+				Value:    file,
+			})
+		}
+
+		for _, tag := range heap.Tags {
+			record.Labels = append(record.Labels, model.Label{
+				Category: model.CategoryTag,
+				Value:    tag,
+			})
+		}
+
+		for _, role := range heap.Roles {
+			record.Labels = append(record.Labels, model.Label{
+				Category: model.CategoryRole,
+				Value:    role,
+			})
+		}
+
+		for _, language := range heap.Languages {
+			record.Labels = append(record.Labels, model.Label{
+				Category: model.CategoryLanguage,
+				Value:    language,
+			})
+		}
+
+		for _, class := range system.KeyClasses {
+			count, ok := meta.KeyClassCounts[class.String()]
+			if !ok {
 				continue
 			}
+
+			record.Labels = append(record.Labels, model.Label{
+				Category: keyClassCategories[class],
+				Value:    strconv.FormatUint(count, 10),
+			})
 		}
 
-		// If last editor activity was more than 30 minutes ago, start a new session
-		if time.Now().Add(-time.Minute * 30).After(meta.LastActivity) {
-			err = storage.NewSession()
-			if err != nil {
-				log.Error().Err(err).Msg("could not start a new session")
-			}
+		for _, editor := range meta.Editors {
+			record.Labels = append(record.Labels, model.Label{
+				Category: model.CategoryEditor,
+				Value:    editor,
+			})
+		}
 
-			log.Info().Msg("30 minutes has passed, clear session")
+		for _, version := range meta.EditorVersions {
+			record.Labels = append(record.Labels, model.Label{
+				Category: model.CategoryEditorVersion,
+				Value:    version,
+			})
+		}
+
+		b, err := json.Marshal(record)
+		if err != nil {
+			log.Error().Err(err).Msg("could not marshal record into byte array")
+			break
+		}
+
+		err = storage.AddToQueue(b)
+		if err != nil {
+			log.Error().Err(err).Msg("could not add record to queue")
+			break
+		}
+
+		err = storage.DeleteHeap(heapId)
+		if err != nil {
+			log.Error().Err(err).Msg("could not delete heap by id")
+			break
 		}
 	}
+
+	pruned, err := storage.PruneFingerprints(time.Now().Add(-fingerprintLifetime))
+	if err != nil {
+		log.Error().Err(err).Msg("could not prune fingerprints")
+	} else if pruned > 0 {
+		log.Debug().Msgf("pruned %d fingerprints", pruned)
+	}
+
+	// Updated so that heap is added to queue (Skip queueing heap data that has already been queued)
+	return storage.SentToQueue()
 }
@@ -0,0 +1,85 @@
+package watcher
+
+import (
+	"errors"
+	"github.com/pacerank/client/internal/store"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type fakeIdleSource struct {
+	idle   time.Duration
+	locked bool
+	err    error
+}
+
+func (f fakeIdleSource) IdleTime() (time.Duration, error) {
+	return f.idle, f.err
+}
+
+func (f fakeIdleSource) ScreenLocked() (bool, error) {
+	return f.locked, f.err
+}
+
+func TestSessions_IsAway(t *testing.T) {
+	assert.False(t, isAway(nil), "without idle source the user is not away")
+	assert.False(t, isAway(fakeIdleSource{idle: time.Second}), "recent input is not away")
+	assert.True(t, isAway(fakeIdleSource{idle: idleThreshold}), "idle machine is away")
+	assert.True(t, isAway(fakeIdleSource{locked: true}), "locked screen is away")
+	assert.False(t, isAway(fakeIdleSource{err: errors.New("no idle information")}), "unknown idle time is not away")
+}
+
+func TestSessions_SessionAction(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		meta  store.Meta
+		away  bool
+		queue bool
+		reset bool
+	}{
+		{
+			name: "recent activity keeps the session",
+			meta: store.Meta{LastActivity: now.Add(-time.Minute)},
+		},
+		{
+			name:  "inactive editor queues heaps",
+			meta:  store.Meta{LastActivity: now.Add(-queueThreshold - time.Second)},
+			queue: true,
+		},
+		{
+			name: "queued heaps are not queued again",
+			meta: store.Meta{LastActivity: now.Add(-queueThreshold - time.Second), HeapAddedToQueue: true},
+		},
+		{
+			name:  "long inactivity starts a new session",
+			meta:  store.Meta{LastActivity: now.Add(-sessionThreshold - time.Second), HeapAddedToQueue: true},
+			reset: true,
+		},
+		{
+			name:  "idle machine closes the session right away",
+			meta:  store.Meta{LastActivity: now.Add(-time.Minute)},
+			away:  true,
+			queue: true,
+			reset: true,
+		},
+	}
+
+	for _, test := range tests {
+		queue, reset := sessionAction(test.meta, test.away, now)
+		assert.Equal(t, test.queue, queue, test.name)
+		assert.Equal(t, test.reset, reset, test.name)
+	}
+}
+
+func TestSessions_ClockJumped(t *testing.T) {
+	tick := time.Second * 5
+
+	assert.False(t, clockJumped(tick, tick), "clocks that agree has not jumped")
+	assert.False(t, clockJumped(tick+time.Second, tick), "small drift has not jumped")
+	assert.True(t, clockJumped(time.Hour, tick), "sleep moves the wall clock ahead of monotonic time")
+	assert.True(t, clockJumped(tick+clockJumpThreshold*2, tick), "wall clock set forward has jumped")
+	assert.True(t, clockJumped(tick-clockJumpThreshold*2, tick), "wall clock set backward has jumped")
+}
@@ -0,0 +1,163 @@
+package watcher
+
+import (
+	"github.com/pacerank/client/internal/inspect"
+	"github.com/pacerank/client/internal/store"
+	"github.com/pacerank/client/pkg/system"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+const (
+	// The focused process is resolved again after this long, what runs in the foreground of a terminal can change
+	// without the focus changing
+	focusLifetime = time.Second * 10
+
+	// The window title of the focused editor is read at most this often
+	titleInterval = time.Second * 5
+)
+
+// TypingEvent is a key that went down in an editor. Code is set when the window title of the editor names a file, it
+// is throttled like the buffers of editors are.
+type TypingEvent struct {
+	Time          time.Time
+	Class         system.KeyClass
+	Process       *system.Process
+	Editor        string
+	EditorVersion string
+	Code          *CodeEvent
+}
+
+type TypingCallback func(event TypingEvent)
+
+// focus is the process that has keyboard focus, resolved once when the focus changes
+type focus struct {
+	processID int64
+	resolved  time.Time
+	titled    time.Time
+	process   *system.Process
+	editor    string
+	version   string
+}
+
+type typist struct {
+	sys      system.System
+	throttle *bufferThrottle
+	focus    focus
+}
+
+// Listen for keys typed in editors. The editor, its version and the file in its window title are looked up when the
+// focus changes and then now and again, not on every key.
+func Typing(sys system.System, c TypingCallback) {
+	t := &typist{sys: sys, throttle: newBufferThrottle()}
+
+	Keyboard(sys, func(key KeyEvent) {
+		event, ok := t.key(key)
+		if ok {
+			c(event)
+		}
+	})
+}
+
+func (t *typist) key(key KeyEvent) (TypingEvent, bool) {
+	if key.State != system.KeyDown {
+		return TypingEvent{}, false
+	}
+
+	now := key.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	elapsed := now.Sub(t.focus.resolved)
+	if t.focus.resolved.IsZero() || key.ProcessID != t.focus.processID || elapsed >= focusLifetime || elapsed < 0 {
+		t.resolve(key.ProcessID, now)
+	}
+
+	if t.focus.editor == "" {
+		return TypingEvent{}, false
+	}
+
+	event := TypingEvent{
+		Time:          now,
+		Class:         key.Class,
+		Process:       t.focus.process,
+		Editor:        t.focus.editor,
+		EditorVersion: t.focus.version,
+	}
+
+	if t.focus.titled.IsZero() || now.Sub(t.focus.titled) >= titleInterval {
+		t.focus.titled = now
+
+		code, ok := WindowTitle(t.sys, t.focus.editor)
+		if ok && t.throttle.allow(code.Id+"\x00"+code.FilePath, false, now) {
+			event.Code = &code
+		}
+	}
+
+	return event, true
+}
+
+// Find the editor of the focused process, processes that are not editors are remembered as well
+func (t *typist) resolve(processID int64, now time.Time) {
+	t.focus = focus{processID: processID, resolved: now}
+
+	process, err := t.sys.ActiveProcess()
+	if err != nil {
+		log.Debug().Err(err).Msg("could not get active process")
+		return
+	}
+
+	application, err := t.sys.Application(process)
+	if err != nil && err != system.ErrUnknownApplication {
+		log.Debug().Err(err).Msgf("could not get application of %s", process.FileName)
+	}
+
+	t.focus.process = process
+
+	editor, ok := inspect.Editor(process.Executable, application)
+	if !ok {
+		return
+	}
+
+	version, err := inspect.EditorVersion(editor, process)
+	if err != nil && err != inspect.ErrNoEditorVersion {
+		log.Debug().Err(err).Msgf("could not get version of %s", editor)
+	}
+
+	t.focus.editor = editor
+	t.focus.version = version
+}
+
+// Record typing as activity of the session, and the file in the window title as code activity
+func RecordTyping(storage *store.Store) TypingCallback {
+	return func(event TypingEvent) {
+		err := storage.MetaTypingActivity(store.TypingActivity{
+			Editor:        event.Editor,
+			EditorVersion: event.EditorVersion,
+			KeyClass:      event.Class.String(),
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("could not record typing activity to store")
+			return
+		}
+
+		if event.Code != nil {
+			err = storage.AddHeap(store.InHeap{
+				Id:       event.Code.Id,
+				Language: event.Code.Language,
+				Branch:   event.Code.Branch,
+				FileName: event.Code.FilePath,
+				Project:  event.Code.Project,
+				Git:      event.Code.Git,
+				Tags:     event.Code.Tags,
+				Role:     event.Code.Role,
+			})
+			if err != nil {
+				log.Error().Err(err).Msg("could not save window title activity to store")
+			}
+		}
+
+		log.Debug().Msgf("recorded typing activity in %s", event.Process.FileName)
+	}
+}
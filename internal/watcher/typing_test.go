@@ -0,0 +1,85 @@
+package watcher
+
+import (
+	"github.com/pacerank/client/pkg/system"
+	"github.com/pacerank/client/pkg/system/systemtest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// countingSystem counts the lookups that are too slow to do on every key
+type countingSystem struct {
+	*systemtest.System
+	processes int
+	windows   int
+}
+
+func (s *countingSystem) ActiveProcess() (*system.Process, error) {
+	s.processes++
+	return s.System.ActiveProcess()
+}
+
+func (s *countingSystem) ActiveWindow() (*system.Window, error) {
+	s.windows++
+	return s.System.ActiveWindow()
+}
+
+func TestTyping_Key(t *testing.T) {
+	sys := &countingSystem{System: systemtest.New()}
+	sys.AddProcess(&system.Process{ProcessID: 10, FileName: "goland", Executable: "/usr/bin/goland"})
+	sys.AddProcess(&system.Process{ProcessID: 20, FileName: "firefox", Executable: "/usr/bin/firefox"})
+	dir := t.TempDir()
+	sys.SetTitle(10, "backend ["+dir+"] – handler.py")
+	sys.SetActive(10)
+
+	typist := &typist{sys: sys, throttle: newBufferThrottle()}
+	start := time.Now()
+
+	key := func(processID int64, after time.Duration) (TypingEvent, bool) {
+		return typist.key(KeyEvent{
+			Time:      start.Add(after),
+			Class:     system.KeyCharacter,
+			State:     system.KeyDown,
+			ProcessID: processID,
+		})
+	}
+
+	event, ok := key(10, 0)
+	assert.True(t, ok, "key in an editor should be typing")
+	assert.Equal(t, "GoLand", event.Editor)
+	assert.NotNil(t, event.Code, "file in the window title should be reported")
+
+	event, ok = key(10, time.Second)
+	assert.True(t, ok, "next key in the editor should be typing")
+	assert.Nil(t, event.Code, "window title should not be read on every key")
+	assert.Equal(t, 1, sys.processes, "editor should be resolved once while it has focus")
+	assert.Equal(t, 1, sys.windows, "window title should be read once in a while")
+
+	_, ok = typist.key(KeyEvent{Time: start.Add(time.Second), State: system.KeyUp, ProcessID: 10})
+	assert.False(t, ok, "key going up should not be typing")
+
+	sys.SetTitle(10, "backend ["+dir+"] – main.go")
+	event, _ = key(10, titleInterval)
+	assert.Equal(t, 2, sys.windows, "window title should be read again after a while")
+	assert.NotNil(t, event.Code, "other file should be reported right away")
+	assert.Equal(t, "main.go", event.Code.FileName)
+
+	sys.SetActive(20)
+	_, ok = key(20, titleInterval+time.Second)
+	assert.False(t, ok, "key outside of an editor should not be typing")
+	assert.Equal(t, 2, sys.processes, "focus change should resolve the process")
+
+	_, ok = key(20, titleInterval+time.Second*2)
+	assert.False(t, ok, "process that is not an editor should be remembered")
+	assert.Equal(t, 2, sys.processes, "process that is not an editor should not be resolved again")
+
+	sys.SetActive(10)
+	event, ok = key(10, titleInterval+time.Second*3)
+	assert.True(t, ok, "editor should be typing again when it has focus")
+	assert.Equal(t, 3, sys.windows, "window title should be read when the focus changes")
+	assert.Nil(t, event.Code, "file that was just reported should be throttled")
+
+	key(10, titleInterval+time.Second*3+focusLifetime)
+	assert.Equal(t, 4, sys.processes, "focused process should be resolved again after a while")
+}
@@ -23,6 +23,18 @@ type CodeEvent struct {
 	Git      string
 	Branch   string
 	Err      error
+
+	// Path of the file on disk, only set when the file was saved
+	Path string
+
+	// Tags of the project from its .pacerank.yml
+	Tags []string
+
+	// Role of the file in the project, like source, test or documentation
+	Role string
+
+	// Privacy of the project from its .pacerank.yml
+	Privacy string
 }
 
 type CodeCallback func(event CodeEvent)
@@ -55,6 +67,8 @@ func Code(directory string, c CodeCallback) {
 
 	w.FilterOps(notify.Write, notify.Create, notify.Remove)
 
+	rules := newIgnoreRules()
+
 	done := make(chan bool)
 	go func() {
 		for {
@@ -71,18 +85,36 @@ func Code(directory string, c CodeCallback) {
 					break
 				}
 
+				if rules.changed(event.Name) {
+					// Directories that were ignored before may not be anymore
+					err := watchDirectories(fs, w, directory, rules)
+					if err != nil {
+						c(CodeEvent{Err: err})
+					}
+				}
+
+				if rules.ignored(event.Name, info.IsDir()) {
+					log.Debug().Str("path", event.Name).Msg("path is ignored")
+					break
+				}
+
 				if event.Op == fsnotify.Write {
 					if info.IsDir() {
 						break
 					}
 
-					lang, err := inspect.AnalyzeFile(event.Name, info.Name())
+					lang, role, err := inspect.AnalyzeFile(event.Name, info.Name())
 					if err != nil {
-						log.Error().Err(err).Msg("could not inspect file")
+						log.Debug().Err(err).Msg("could not inspect file")
 						break
 					}
 
 					pi, err := inspect.Project(event.Name, directory)
+					if err == inspect.ErrProjectIgnored {
+						log.Debug().Str("path", event.Name).Msg("file is ignored by its project")
+						break
+					}
+
 					if err != nil {
 						log.Debug().Err(err).Msg("could not get repository information")
 					}
@@ -96,6 +128,10 @@ func Code(directory string, c CodeCallback) {
 						Git:      pi.Git,
 						Branch:   pi.Branch,
 						Err:      err,
+						Path:     event.Name,
+						Tags:     pi.Tags,
+						Role:     role,
+						Privacy:  pi.Privacy,
 					})
 					break
 				}
@@ -134,18 +170,36 @@ func Code(directory string, c CodeCallback) {
 		for {
 			select {
 			case event := <-w.Event:
+				if rules.changed(event.Path) {
+					// Directories that were ignored before may not be anymore
+					err := watchDirectories(fs, w, directory, rules)
+					if err != nil {
+						c(CodeEvent{Err: err})
+					}
+				}
+
+				if rules.ignored(event.Path, event.IsDir()) {
+					log.Debug().Str("path", event.Path).Msg("path is ignored")
+					break
+				}
+
 				if event.Op == notify.Write {
 					if event.IsDir() {
 						break
 					}
 
-					lang, err := inspect.AnalyzeFile(event.Path, event.Name())
+					lang, role, err := inspect.AnalyzeFile(event.Path, event.Name())
 					if err != nil {
 						log.Debug().Err(err).Msg("could not analyze file")
 						break
 					}
 
 					pi, err := inspect.Project(event.Path, directory)
+					if err == inspect.ErrProjectIgnored {
+						log.Debug().Str("path", event.Path).Msg("file is ignored by its project")
+						break
+					}
+
 					if err != nil {
 						log.Debug().Err(err).Msg("could not get repository information")
 					}
@@ -159,12 +213,16 @@ func Code(directory string, c CodeCallback) {
 						Git:      pi.Git,
 						Branch:   pi.Branch,
 						Err:      err,
+						Path:     event.Path,
+						Tags:     pi.Tags,
+						Role:     role,
+						Privacy:  pi.Privacy,
 					})
 				}
 
 				if event.IsDir() {
 					if event.Op == notify.Create || event.Op == notify.Write {
-						for _, d := range watchList(event.Path) {
+						for _, d := range watchList(event.Path, rules) {
 							err = w.Add(d)
 							if err != nil {
 								c(CodeEvent{Err: err})
@@ -182,7 +240,25 @@ func Code(directory string, c CodeCallback) {
 		}
 	}()
 
-	for _, d := range watchList(directory) {
+	err = watchDirectories(fs, w, directory, rules)
+	if err != nil {
+		c(CodeEvent{Err: err})
+		return
+	}
+
+	// Start the watching process - it'll check for changes every 100ms.
+	if err := w.Start(time.Second * 5); err != nil {
+		c(CodeEvent{Err: err})
+		return
+	}
+
+	<-done
+}
+
+// Watch the directories of a folder that are not ignored. Directories that are watched already are added again
+// without harm, so this is also how the watch set is rebuilt when ignore rules change.
+func watchDirectories(fs *fsnotify.Watcher, w *notify.Watcher, folder string, rules *ignoreRules) error {
+	for _, d := range watchList(folder, rules) {
 		// Try to add to fsnotify first, for performance
 		err := fs.Add(d)
 		if err == nil {
@@ -193,23 +269,16 @@ func Code(directory string, c CodeCallback) {
 		// If it fails, fall back to file polling
 		err = w.Add(d)
 		if err != nil {
-			c(CodeEvent{Err: err})
-			return
+			return err
 		}
 
 		log.Debug().Str("path", d).Msg("folder is watched by file system polling")
 	}
 
-	// Start the watching process - it'll check for changes every 100ms.
-	if err := w.Start(time.Second * 5); err != nil {
-		c(CodeEvent{Err: err})
-		return
-	}
-
-	<-done
+	return nil
 }
 
-func watchList(folder string) []string {
+func watchList(folder string, rules *ignoreRules) []string {
 	var result []string
 
 	abs, err := filepath.Abs(folder)
@@ -228,11 +297,11 @@ func watchList(folder string) []string {
 
 	for _, file := range list {
 		if file.IsDir() {
-			if ignoreDirectory(fmt.Sprintf("%s/%s", abs, file.Name())) {
+			if rules.ignored(fmt.Sprintf("%s/%s", abs, file.Name()), true) {
 				continue
 			}
 
-			result = append(result, watchList(fmt.Sprintf("%s/%s", abs, file.Name()))...)
+			result = append(result, watchList(fmt.Sprintf("%s/%s", abs, file.Name()), rules)...)
 		}
 	}
 
@@ -249,29 +318,77 @@ func ignoreDirectory(path string) bool {
 	return false
 }
 
-type Key uint16
-
+// KeyEvent tells what class of key went down or up, and which process had focus. What was typed is never known.
 type KeyEvent struct {
-	Key  Key
-	Rune rune
-	Err  error
+	Time      time.Time
+	Class     system.KeyClass
+	State     system.KeyState
+	ProcessID int64
+	Err       error
 }
 
 type KeyboardCallback func(event KeyEvent)
 
-// Listen for keyboard inputs
-func Keyboard(c KeyboardCallback) {
-	channel := make(chan byte)
-	sys := system.New()
+// Listen for keyboard inputs of given system
+func Keyboard(sys system.System, c KeyboardCallback) {
+	channel := make(chan system.KeyEvent)
 	go sys.ListenKeyboard(channel)
 
 	for {
 		key := <-channel
 
 		c(KeyEvent{
-			Key:  Key(key),
-			Rune: rune(key),
-			Err:  nil,
+			Time:      key.Time,
+			Class:     key.Class,
+			State:     key.State,
+			ProcessID: key.ProcessID,
+			Err:       nil,
 		})
 	}
 }
+
+// Find the file and project an editor shows in the title of the active window, so typing can be attributed before the
+// file is saved and outside of the watched folders
+func WindowTitle(sys system.System, editor string) (CodeEvent, bool) {
+	window, err := sys.ActiveWindow()
+	if err != nil {
+		log.Debug().Err(err).Msg("could not get active window")
+		return CodeEvent{}, false
+	}
+
+	title, ok := inspect.EditorTitle(editor, window.Title)
+	if !ok {
+		return CodeEvent{}, false
+	}
+
+	// Titles that only name the file are placed in the project the editor has open
+	if title.Directory == "" {
+		roots, err := inspect.Workspaces(editor)
+		if err != nil {
+			log.Debug().Err(err).Msgf("could not read workspaces of %s", editor)
+		}
+
+		if root, ok := inspect.WorkspaceOf(roots, title.Project); ok {
+			title.Directory = root
+		}
+	}
+
+	activity, err := inspect.TitleProject(title)
+	if err != nil {
+		log.Debug().Err(err).Str("title", window.Title).Msg("could not attribute window title")
+		return CodeEvent{}, false
+	}
+
+	return CodeEvent{
+		Id:       activity.Id,
+		FilePath: activity.FilePath,
+		FileName: activity.FileName,
+		Language: activity.Language,
+		Project:  activity.Project,
+		Git:      activity.Git,
+		Branch:   activity.Branch,
+		Tags:     activity.Tags,
+		Role:     activity.Role,
+		Privacy:  activity.Privacy,
+	}, true
+}
@@ -0,0 +1,66 @@
+package watcher
+
+import (
+	"github.com/pacerank/client/pkg/system"
+	"github.com/pacerank/client/pkg/system/systemtest"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestWatcher_Keyboard(t *testing.T) {
+	sys := systemtest.New()
+	sys.AddProcess(&system.Process{ProcessID: 10, FileName: "code", Executable: "/usr/bin/code"})
+	sys.AddProcess(&system.Process{ProcessID: 20, FileName: "firefox", Executable: "/usr/bin/firefox"})
+
+	events := make(chan KeyEvent, 10)
+	go Keyboard(sys, func(event KeyEvent) {
+		events <- event
+	})
+
+	next := func() KeyEvent {
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(time.Second * 5):
+			t.Fatal("no key event was received")
+			return KeyEvent{}
+		}
+	}
+
+	sys.SetActive(10)
+	sys.Press(system.KeyEdit)
+
+	event := next()
+	assert.Equal(t, system.KeyEdit, event.Class, "class should be passed on")
+	assert.Equal(t, system.KeyDown, event.State, "key should go down first")
+	assert.Equal(t, int64(10), event.ProcessID, "key should belong to the active process")
+	assert.Equal(t, system.KeyUp, next().State, "key should go up after")
+
+	process, err := sys.ActiveProcess()
+	assert.NoError(t, err, "getting active process should not result in error")
+	assert.Equal(t, "/usr/bin/code", process.Executable, "active process should be the editor")
+
+	sys.SetActive(20)
+	sys.Press(system.KeyCharacter)
+
+	assert.Equal(t, int64(20), next().ProcessID, "key should follow the active process")
+}
+
+func TestWatcher_WindowTitle(t *testing.T) {
+	dir := t.TempDir()
+
+	sys := systemtest.New()
+	sys.AddProcess(&system.Process{ProcessID: 10, FileName: "goland", Executable: "/usr/bin/goland"})
+	sys.SetActive(10)
+
+	sys.SetTitle(10, "backend ["+dir+"] – handler.py")
+	event, ok := WindowTitle(sys, "GoLand")
+	assert.True(t, ok, "title of an editor should be attributed")
+	assert.Equal(t, "python", event.Language, "language should be found from the file name")
+	assert.Equal(t, "backend", event.Project, "project should be read from the title")
+
+	sys.SetTitle(10, "backend ["+dir+"] – Welcome")
+	_, ok = WindowTitle(sys, "GoLand")
+	assert.False(t, ok, "title without a code file should not be attributed")
+}
@@ -0,0 +1,84 @@
+package watcher
+
+import (
+	"github.com/pacerank/client/internal/inspect"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// How often the projects open in editors are looked for
+const workspaceInterval = time.Minute
+
+// WorkspaceEvent is a project to suggest watching. Recent projects were opened in the editor lately, but are not
+// necessarily open now.
+type WorkspaceEvent struct {
+	Editor    string
+	Directory string
+	Recent    bool
+}
+
+type WorkspaceCallback func(event WorkspaceEvent)
+
+// Suggest the projects that are open or were opened recently in editors but are not watched. Every project is suggested
+// once, watched returns the directories that are watched right now.
+func Workspaces(watched func() []string, c WorkspaceCallback) {
+	suggested := make(map[string]bool)
+
+	for {
+		open, recent := inspect.OpenWorkspaces(), inspect.RecentlyOpenedWorkspaces()
+		for _, event := range unwatchedWorkspaces(open, recent, watched(), suggested) {
+			c(event)
+		}
+
+		time.Sleep(workspaceInterval)
+	}
+}
+
+// Find the projects that are open or were opened recently in editors but are not watched
+func UnwatchedWorkspaces(watched []string) []WorkspaceEvent {
+	open, recent := inspect.OpenWorkspaces(), inspect.RecentlyOpenedWorkspaces()
+	return unwatchedWorkspaces(open, recent, watched, make(map[string]bool))
+}
+
+// The open projects come before the recent ones, a project that is both is suggested as open
+func unwatchedWorkspaces(open, recent map[string][]string, watched []string, suggested map[string]bool) []WorkspaceEvent {
+	var result []WorkspaceEvent
+	for _, list := range []struct {
+		roots  map[string][]string
+		recent bool
+	}{{open, false}, {recent, true}} {
+		var editors []string
+		for editor := range list.roots {
+			editors = append(editors, editor)
+		}
+
+		sort.Strings(editors)
+
+		for _, editor := range editors {
+			for _, root := range list.roots[editor] {
+				if suggested[root] || insideDirectories(root, watched) {
+					continue
+				}
+
+				suggested[root] = true
+				result = append(result, WorkspaceEvent{Editor: editor, Directory: root, Recent: list.recent})
+			}
+		}
+	}
+
+	return result
+}
+
+// Tell if a path is one of the directories or inside one of them
+func insideDirectories(path string, directories []string) bool {
+	for _, directory := range directories {
+		relative, err := filepath.Rel(filepath.Clean(directory), path)
+		if err == nil && relative != ".." && !strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
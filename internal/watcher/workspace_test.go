@@ -0,0 +1,38 @@
+package watcher
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWatcher_UnwatchedWorkspaces(t *testing.T) {
+	open := map[string][]string{
+		"GoLand":             {"/home/user/projects/client", "/home/user/projects/digest"},
+		"Visual Studio Code": {"/home/user/web", "/home/user/projects/client"},
+	}
+
+	suggested := make(map[string]bool)
+	events := unwatchedWorkspaces(open, nil, []string{"/home/user/projects/digest", "/home/user/web/"}, suggested)
+	assert.Equal(t, []WorkspaceEvent{{Editor: "GoLand", Directory: "/home/user/projects/client"}}, events,
+		"only unwatched projects should be suggested, once")
+
+	events = unwatchedWorkspaces(open, nil, nil, suggested)
+	assert.Equal(t, []WorkspaceEvent{
+		{Editor: "GoLand", Directory: "/home/user/projects/digest"},
+		{Editor: "Visual Studio Code", Directory: "/home/user/web"},
+	}, events, "suggested projects should not be suggested again")
+
+	recent := map[string][]string{
+		"Visual Studio Code": {"/home/user/web", "/home/user/old"},
+	}
+
+	events = unwatchedWorkspaces(map[string][]string{"Visual Studio Code": {"/home/user/web"}}, recent, nil,
+		make(map[string]bool))
+	assert.Equal(t, []WorkspaceEvent{
+		{Editor: "Visual Studio Code", Directory: "/home/user/web"},
+		{Editor: "Visual Studio Code", Directory: "/home/user/old", Recent: true},
+	}, events, "recent projects should be suggested after open ones, and only when they are not open")
+
+	assert.True(t, insideDirectories("/home/user/projects/client/api", []string{"/home/user/projects"}))
+	assert.False(t, insideDirectories("/home/user/projects2", []string{"/home/user/projects"}))
+}
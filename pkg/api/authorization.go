@@ -11,6 +11,8 @@ import (
 type InitializeAuthorizationFlowReply struct {
 	AuthorizationId  string `json:"authorization_id"`
 	AuthorizationUrl string `json:"authorization_url"`
+	UserCode         string `json:"user_code"`
+	VerificationUrl  string `json:"verification_url"`
 }
 
 func (api *Api) InitializeAuthorizationFlow() (*DefaultReplyStructure, *InitializeAuthorizationFlowReply, error) {
@@ -57,6 +57,19 @@ const (
 	CategoryLanguage Category = "language"
 	CategoryEditor   Category = "editor"
 	CategoryKeyCount Category = "keycount"
+
+	CategoryEditorVersion Category = "editor_version"
+	CategoryChangeCount   Category = "changecount"
+	CategoryLinesAdded    Category = "lines_added"
+	CategoryLinesRemoved  Category = "lines_removed"
+	CategoryTag           Category = "tag"
+	CategoryRole          Category = "role"
+
+	CategoryKeyCountCharacter  Category = "keycount_character"
+	CategoryKeyCountEdit       Category = "keycount_edit"
+	CategoryKeyCountNavigation Category = "keycount_navigation"
+	CategoryKeyCountModifier   Category = "keycount_modifier"
+	CategoryKeyCountShortcut   Category = "keycount_shortcut"
 )
 
 var toCategory = map[string]Category{
@@ -67,6 +80,19 @@ var toCategory = map[string]Category{
 	"language": CategoryLanguage,
 	"editor":   CategoryEditor,
 	"keycount": CategoryKeyCount,
+
+	"editor_version": CategoryEditorVersion,
+	"changecount":    CategoryChangeCount,
+	"lines_added":    CategoryLinesAdded,
+	"lines_removed":  CategoryLinesRemoved,
+	"tag":            CategoryTag,
+	"role":           CategoryRole,
+
+	"keycount_character":  CategoryKeyCountCharacter,
+	"keycount_edit":       CategoryKeyCountEdit,
+	"keycount_navigation": CategoryKeyCountNavigation,
+	"keycount_modifier":   CategoryKeyCountModifier,
+	"keycount_shortcut":   CategoryKeyCountShortcut,
 }
 
 func CategoryExist(category string) bool {
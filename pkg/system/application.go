@@ -0,0 +1,18 @@
+package system
+
+import (
+	"errors"
+)
+
+// ErrUnknownApplication is returned when a process can not be tied to an installed application
+var ErrUnknownApplication = errors.New("process does not belong to a known application")
+
+// Application is the installed desktop application a process belongs to. ID is the desktop entry id without the
+// .desktop suffix, Flatpak is the Flatpak app id and Snap the snap name, when the application is packaged like that.
+type Application struct {
+	ID      string
+	Name    string
+	WMClass string
+	Flatpak string
+	Snap    string
+}
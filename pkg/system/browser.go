@@ -0,0 +1,86 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ErrNoBrowser is returned when none of the browser launchers could open a browser
+var ErrNoBrowser = errors.New("no browser could be opened")
+
+// How long a launcher may run before it is considered to have opened the browser. Some launchers exit right away,
+// others run the browser in the foreground.
+const browserLaunchTimeout = time.Second * 3
+
+// browserLauncher is a command that opens an url, %s in the arguments is replaced with the url. If no argument has
+// %s, the url is added last.
+type browserLauncher struct {
+	name string
+	args []string
+}
+
+func (l browserLauncher) command(url string) (string, []string) {
+	var args []string
+	var replaced bool
+	for _, arg := range l.args {
+		if strings.Contains(arg, "%s") {
+			arg = strings.Replace(arg, "%s", url, -1)
+			replaced = true
+		}
+
+		args = append(args, arg)
+	}
+
+	if !replaced {
+		args = append(args, url)
+	}
+
+	return l.name, args
+}
+
+// Try the launchers in order until one of them opens the url
+func launchBrowser(url string, launchers []browserLauncher, start func(name string, args ...string) error) error {
+	for _, launcher := range launchers {
+		name, args := launcher.command(url)
+
+		err := start(name, args...)
+		if err == nil {
+			return nil
+		}
+
+		log.Debug().Err(err).Str("launcher", name).Msg("could not open browser")
+	}
+
+	return ErrNoBrowser
+}
+
+// Run the launcher and wait a moment for it to fail. A launcher that is still running after the timeout is left
+// running, it has most likely opened the browser.
+func startBrowser(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err = <-done:
+		if err != nil {
+			return errors.New(fmt.Sprintf("%s exited with error: %s", name, err))
+		}
+
+		return nil
+	case <-time.After(browserLaunchTimeout):
+		return nil
+	}
+}
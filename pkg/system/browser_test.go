@@ -0,0 +1,36 @@
+package system
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBrowser_LaunchBrowser(t *testing.T) {
+	var tried []string
+	start := func(name string, args ...string) error {
+		tried = append(tried, name+" "+args[len(args)-1])
+		if name == "works" {
+			return nil
+		}
+
+		return errors.New("could not start")
+	}
+
+	launchers := []browserLauncher{{name: "broken"}, {name: "works"}, {name: "never"}}
+	err := launchBrowser("https://pacerank.io", launchers, start)
+	assert.NoError(t, err, "launching browser should not result in error")
+	assert.Equal(t, []string{"broken https://pacerank.io", "works https://pacerank.io"}, tried, "launchers should be tried in order")
+
+	err = launchBrowser("https://pacerank.io", launchers[:1], start)
+	assert.Equal(t, ErrNoBrowser, err, "no working launcher should result in no browser")
+}
+
+func TestBrowser_LauncherCommand(t *testing.T) {
+	name, args := browserLauncher{name: "firefox", args: []string{"--new-tab"}}.command("https://pacerank.io")
+	assert.Equal(t, "firefox", name, "name should be kept")
+	assert.Equal(t, []string{"--new-tab", "https://pacerank.io"}, args, "url should be added last")
+
+	_, args = browserLauncher{name: "forward", args: []string{"--url=%s", "--quiet"}}.command("https://pacerank.io")
+	assert.Equal(t, []string{"--url=https://pacerank.io", "--quiet"}, args, "url should replace %s")
+}
@@ -0,0 +1,180 @@
+package system
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// How often the process cache looks for processes that have exited
+const cachePruneInterval = time.Minute
+
+// CacheStats contains the counters of the process metadata cache
+type CacheStats struct {
+	Hits           uint64
+	Misses         uint64
+	Evictions      uint64
+	ChecksumHits   uint64
+	ChecksumMisses uint64
+}
+
+// Processes are identified by process ID and start time, as process IDs are reused by the operating system
+type processKey struct {
+	processID int64
+	startTime int64
+}
+
+// Executables are identified by path, inode and modification time, so an updated editor gets a new checksum
+type fileKey struct {
+	path     string
+	inode    uint64
+	size     int64
+	modified int64
+}
+
+type processCache struct {
+	mu        sync.Mutex
+	processes map[processKey]*Process
+	checksums map[fileKey]string
+	stats     CacheStats
+	pruned    time.Time
+}
+
+// The process metadata cache that is shared by all lookups on this system
+var processes = newProcessCache()
+
+func newProcessCache() *processCache {
+	return &processCache{
+		processes: make(map[processKey]*Process),
+		checksums: make(map[fileKey]string),
+		pruned:    time.Now(),
+	}
+}
+
+// Get a process from the cache, or load it if it is not cached. A cached process with the same process ID but another
+// start time has exited, and is replaced. A process that has called exec keeps its process ID and start time, it is
+// told apart by its executable when one is given.
+func (c *processCache) lookup(processID int64, startTime time.Time, executable string, load func() (*Process, error)) (*Process, error) {
+	key := processKey{processID: processID, startTime: startTime.UnixNano()}
+
+	c.mu.Lock()
+	process, ok := c.processes[key]
+	if ok && (executable == "" || process.Executable == executable) {
+		c.stats.Hits++
+		c.mu.Unlock()
+		return process, nil
+	}
+
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	process, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k := range c.processes {
+		if k.processID == processID {
+			delete(c.processes, k)
+			c.stats.Evictions++
+		}
+	}
+
+	c.processes[key] = process
+	return process, nil
+}
+
+// Remove a process that has exited
+func (c *processCache) evict(processID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k := range c.processes {
+		if k.processID == processID {
+			delete(c.processes, k)
+			c.stats.Evictions++
+		}
+	}
+}
+
+// Remove every process that is no longer alive, at most once per cachePruneInterval
+func (c *processCache) prune(alive func(processID int64, startTime time.Time) bool) {
+	c.mu.Lock()
+	if time.Since(c.pruned) < cachePruneInterval {
+		c.mu.Unlock()
+		return
+	}
+
+	c.pruned = time.Now()
+
+	keys := make([]processKey, 0, len(c.processes))
+	for k := range c.processes {
+		keys = append(keys, k)
+	}
+	c.mu.Unlock()
+
+	for _, k := range keys {
+		if alive(k.processID, time.Unix(0, k.startTime)) {
+			continue
+		}
+
+		c.mu.Lock()
+		delete(c.processes, k)
+		c.stats.Evictions++
+		c.mu.Unlock()
+	}
+}
+
+// Get the checksum of an executable, it is only calculated again when the file has changed
+func (c *processCache) checksum(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	key := fileKey{
+		path:     path,
+		inode:    fileIdentity(info),
+		size:     info.Size(),
+		modified: info.ModTime().UnixNano(),
+	}
+
+	c.mu.Lock()
+	cs, ok := c.checksums[key]
+	if ok {
+		c.stats.ChecksumHits++
+		c.mu.Unlock()
+		return cs, nil
+	}
+
+	c.stats.ChecksumMisses++
+	c.mu.Unlock()
+
+	cs, err = checksum(path)
+	if err != nil {
+		return "", err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Older versions of the same executable will not be asked for again
+	for k := range c.checksums {
+		if k.path == path {
+			delete(c.checksums, k)
+		}
+	}
+
+	c.checksums[key] = cs
+	return cs, nil
+}
+
+func (c *processCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stats
+}
@@ -0,0 +1,114 @@
+package system
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCache_Lookup(t *testing.T) {
+	cache := newProcessCache()
+	started := time.Now()
+
+	var loads int
+	load := func(name string) func() (*Process, error) {
+		return func() (*Process, error) {
+			loads++
+			return &Process{ProcessID: 10, FileName: name}, nil
+		}
+	}
+
+	process, err := cache.lookup(10, started, "", load("goland"))
+	assert.NoError(t, err, "lookup should not result in error")
+	assert.Equal(t, "goland", process.FileName, "process should be loaded")
+
+	process, err = cache.lookup(10, started, "", load("goland"))
+	assert.NoError(t, err, "lookup should not result in error")
+	assert.Equal(t, 1, loads, "second lookup should be served from cache")
+
+	// Same process ID, but another start time is a new process
+	process, err = cache.lookup(10, started.Add(time.Second), "", load("bash"))
+	assert.NoError(t, err, "lookup should not result in error")
+	assert.Equal(t, "bash", process.FileName, "reused process id should not be confused with the old process")
+
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 2, Evictions: 1}, cache.Stats(), "counters should be updated")
+
+	cache.evict(10)
+	assert.Empty(t, cache.processes, "exited process should be evicted")
+}
+
+func TestCache_LookupExec(t *testing.T) {
+	cache := newProcessCache()
+	started := time.Now()
+
+	load := func(executable string) func() (*Process, error) {
+		return func() (*Process, error) {
+			return &Process{ProcessID: 10, FileName: filepath.Base(executable), Executable: executable}, nil
+		}
+	}
+
+	_, _ = cache.lookup(10, started, "/usr/bin/bash", load("/usr/bin/bash"))
+
+	process, err := cache.lookup(10, started, "/usr/bin/bash", load("/usr/bin/bash"))
+	assert.NoError(t, err, "lookup should not result in error")
+	assert.Equal(t, uint64(1), cache.Stats().Hits, "same executable should be served from cache")
+
+	// A shell that runs exec nvim keeps its process ID and start time
+	process, err = cache.lookup(10, started, "/usr/bin/nvim", load("/usr/bin/nvim"))
+	assert.NoError(t, err, "lookup should not result in error")
+	assert.Equal(t, "nvim", process.FileName, "process that called exec should be loaded again")
+	assert.Len(t, cache.processes, 1, "executable before exec should be replaced")
+}
+
+func TestCache_Prune(t *testing.T) {
+	cache := newProcessCache()
+	started := time.Now()
+
+	for _, pid := range []int64{1, 2, 3} {
+		_, _ = cache.lookup(pid, started, "", func() (*Process, error) {
+			return &Process{}, nil
+		})
+	}
+
+	cache.pruned = time.Now().Add(-cachePruneInterval)
+	cache.prune(func(processID int64, startTime time.Time) bool {
+		return processID != 2
+	})
+
+	assert.Len(t, cache.processes, 2, "exited process should be pruned")
+	assert.Equal(t, uint64(1), cache.Stats().Evictions, "pruned process should be counted as evicted")
+}
+
+func TestCache_Checksum(t *testing.T) {
+	file, err := ioutil.TempFile("", "pacerank-executable")
+	assert.NoError(t, err, "creating temporary file should not result in error")
+	defer os.Remove(file.Name())
+
+	_, _ = file.WriteString("version 1")
+	_ = file.Close()
+
+	cache := newProcessCache()
+
+	first, err := cache.checksum(file.Name())
+	assert.NoError(t, err, "checksum should not result in error")
+
+	cached, err := cache.checksum(file.Name())
+	assert.NoError(t, err, "checksum should not result in error")
+	assert.Equal(t, first, cached, "checksum should be the same")
+
+	err = ioutil.WriteFile(file.Name(), []byte("version 2"), 0600)
+	assert.NoError(t, err, "updating file should not result in error")
+	err = os.Chtimes(file.Name(), time.Now(), time.Now().Add(time.Minute))
+	assert.NoError(t, err, "updating modification time should not result in error")
+
+	updated, err := cache.checksum(file.Name())
+	assert.NoError(t, err, "checksum should not result in error")
+	assert.NotEqual(t, first, updated, "changed executable should get a new checksum")
+
+	stats := cache.Stats()
+	assert.Equal(t, uint64(1), stats.ChecksumHits, "unchanged file should be a hit")
+	assert.Equal(t, uint64(2), stats.ChecksumMisses, "new and changed file should be misses")
+}
@@ -0,0 +1,110 @@
+package system
+
+import (
+	"context"
+	"time"
+)
+
+// How often processes are listed when the operating system can not tell about process changes
+const processPollInterval = time.Second * 2
+
+type ProcessEventType int
+
+const (
+	ProcessStarted ProcessEventType = iota
+	ProcessExited
+)
+
+func (t ProcessEventType) String() string {
+	switch t {
+	case ProcessStarted:
+		return "started"
+	case ProcessExited:
+		return "exited"
+	}
+
+	return "unknown"
+}
+
+// ProcessEvent tells that a process has started or exited. Process is only set for started processes that could be
+// read, an exited process can not be inspected anymore.
+type ProcessEvent struct {
+	Type      ProcessEventType
+	ProcessID int64
+	Process   *Process
+	Time      time.Time
+}
+
+// Emit process events by comparing snapshots of the running processes. The first snapshot is the baseline, so
+// processes that were running before the watch started are not reported.
+func pollProcesses(ctx context.Context, list func() ([]*Process, error), channel chan<- ProcessEvent) {
+	defer close(channel)
+
+	var previous map[int64]*Process
+
+	ticker := time.NewTicker(processPollInterval)
+	defer ticker.Stop()
+
+	for {
+		current, err := snapshot(list)
+		if err == nil {
+			if previous != nil {
+				for _, event := range diffProcesses(previous, current, time.Now()) {
+					select {
+					case channel <- event:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+
+			previous = current
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func snapshot(list func() ([]*Process, error)) (map[int64]*Process, error) {
+	processList, err := list()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[int64]*Process, len(processList))
+	for _, process := range processList {
+		result[process.ProcessID] = process
+	}
+
+	return result, nil
+}
+
+// Compare two snapshots, a process ID that is in both but with another start time has been reused by a new process
+func diffProcesses(previous, current map[int64]*Process, now time.Time) []ProcessEvent {
+	var events []ProcessEvent
+
+	for pid, process := range previous {
+		next, ok := current[pid]
+		if ok && next.StartTime.Equal(process.StartTime) {
+			continue
+		}
+
+		processes.evict(pid)
+		events = append(events, ProcessEvent{Type: ProcessExited, ProcessID: pid, Time: now})
+	}
+
+	for pid, process := range current {
+		last, ok := previous[pid]
+		if ok && last.StartTime.Equal(process.StartTime) {
+			continue
+		}
+
+		events = append(events, ProcessEvent{Type: ProcessStarted, ProcessID: pid, Process: process, Time: now})
+	}
+
+	return events
+}
@@ -0,0 +1,41 @@
+package system
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestEvents_DiffProcesses(t *testing.T) {
+	started := time.Now().Add(-time.Hour)
+	now := time.Now()
+
+	previous := map[int64]*Process{
+		1:  {ProcessID: 1, StartTime: started},
+		20: {ProcessID: 20, StartTime: started},
+		30: {ProcessID: 30, StartTime: started},
+	}
+
+	current := map[int64]*Process{
+		1:  {ProcessID: 1, StartTime: started},
+		30: {ProcessID: 30, StartTime: now},
+		40: {ProcessID: 40, StartTime: now},
+	}
+
+	events := diffProcesses(previous, current, now)
+
+	exited := map[int64]bool{}
+	startedProcesses := map[int64]bool{}
+	for _, event := range events {
+		switch event.Type {
+		case ProcessExited:
+			exited[event.ProcessID] = true
+		case ProcessStarted:
+			assert.NotNil(t, event.Process, "started process should be set")
+			startedProcesses[event.ProcessID] = true
+		}
+	}
+
+	assert.Equal(t, map[int64]bool{20: true, 30: true}, exited, "missing and reused process ids should exit")
+	assert.Equal(t, map[int64]bool{30: true, 40: true}, startedProcesses, "new and reused process ids should start")
+}
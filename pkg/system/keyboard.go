@@ -0,0 +1,105 @@
+package system
+
+import (
+	"time"
+)
+
+// KeyClass is what kind of key was pressed, the key itself is never reported so what is typed can not be recovered
+type KeyClass int
+
+const (
+	KeyCharacter KeyClass = iota
+	KeyEdit
+	KeyNavigation
+	KeyModifier
+	KeyShortcut
+)
+
+// All key classes, in the order they are reported
+var KeyClasses = []KeyClass{KeyCharacter, KeyEdit, KeyNavigation, KeyModifier, KeyShortcut}
+
+func (c KeyClass) String() string {
+	switch c {
+	case KeyCharacter:
+		return "character"
+	case KeyEdit:
+		return "edit"
+	case KeyNavigation:
+		return "navigation"
+	case KeyModifier:
+		return "modifier"
+	case KeyShortcut:
+		return "shortcut"
+	}
+
+	return "unknown"
+}
+
+type KeyState int
+
+const (
+	KeyDown KeyState = iota
+	KeyUp
+)
+
+func (s KeyState) String() string {
+	switch s {
+	case KeyDown:
+		return "down"
+	case KeyUp:
+		return "up"
+	}
+
+	return "unknown"
+}
+
+// KeyEvent is a key going down or up, together with the process that had focus when it happened
+type KeyEvent struct {
+	Time      time.Time
+	Class     KeyClass
+	State     KeyState
+	ProcessID int64
+}
+
+// keyTracker remembers which keys are held down, to skip auto repeats and to classify keys pressed together with a
+// command modifier (control, alt or super) as shortcuts. Shift is not a command modifier, as it is used for typing.
+type keyTracker struct {
+	pressed  map[uint32]KeyClass
+	commands map[uint32]bool
+}
+
+func newKeyTracker() *keyTracker {
+	return &keyTracker{
+		pressed:  make(map[uint32]KeyClass),
+		commands: make(map[uint32]bool),
+	}
+}
+
+// Turn a platform key code into an event, returns false for auto repeats of a key that is already held down. A key
+// going up is reported with the same class as it had when it went down.
+func (k *keyTracker) event(code uint32, down bool, class KeyClass, command bool, now time.Time) (KeyEvent, bool) {
+	if !down {
+		if pressed, ok := k.pressed[code]; ok {
+			class = pressed
+		}
+
+		delete(k.pressed, code)
+		delete(k.commands, code)
+		return KeyEvent{Time: now, Class: class, State: KeyUp}, true
+	}
+
+	if _, ok := k.pressed[code]; ok {
+		return KeyEvent{}, false
+	}
+
+	if class != KeyModifier && len(k.commands) > 0 {
+		class = KeyShortcut
+	}
+
+	k.pressed[code] = class
+	if command {
+		k.commands[code] = true
+	}
+
+	return KeyEvent{Time: now, Class: class, State: KeyDown}, true
+}
@@ -0,0 +1,38 @@
+package system
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestKeyboard_KeyTracker(t *testing.T) {
+	tracker := newKeyTracker()
+	now := time.Now()
+
+	event, ok := tracker.event(1, true, KeyCharacter, false, now)
+	assert.True(t, ok, "key down should be reported")
+	assert.Equal(t, KeyEvent{Time: now, Class: KeyCharacter, State: KeyDown}, event, "event should have the time, class and state")
+
+	_, ok = tracker.event(1, true, KeyCharacter, false, now)
+	assert.False(t, ok, "auto repeat should not be reported")
+
+	event, ok = tracker.event(1, false, KeyCharacter, false, now)
+	assert.True(t, ok, "key up should be reported")
+	assert.Equal(t, KeyUp, event.State, "key up should have up state")
+
+	_, _ = tracker.event(2, true, KeyModifier, false, now)
+	event, _ = tracker.event(1, true, KeyCharacter, false, now)
+	assert.Equal(t, KeyCharacter, event.Class, "shift does not make a shortcut")
+
+	_, _ = tracker.event(3, true, KeyModifier, true, now)
+	event, _ = tracker.event(4, true, KeyNavigation, false, now)
+	assert.Equal(t, KeyShortcut, event.Class, "key pressed with a command modifier is a shortcut")
+
+	_, _ = tracker.event(3, false, KeyModifier, true, now)
+	event, _ = tracker.event(4, false, KeyNavigation, false, now)
+	assert.Equal(t, KeyShortcut, event.Class, "key up keeps the class from key down")
+
+	event, _ = tracker.event(5, true, KeyEdit, false, now)
+	assert.Equal(t, KeyEdit, event.Class, "released command modifier no longer makes shortcuts")
+}
@@ -3,11 +3,23 @@
 package system
 
 import (
-	"os/exec"
+	"os"
+	"sync"
+	"syscall"
 )
 
-type target struct{}
+type target struct {
+	mu      sync.Mutex
+	windows []windowProvider
+	idle    *x11
+}
+
+// Get the inode of a file
+func fileIdentity(info os.FileInfo) uint64 {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0
+	}
 
-func OpenBrowser(url string) error {
-	return exec.Command("xdg-open", url).Start()
+	return stat.Ino
 }
@@ -0,0 +1,552 @@
+// +build linux
+
+package system
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How long the index of desktop entries is used before it is built again, to see applications that were installed
+const applicationIndexLifetime = time.Minute * 10
+
+// Directories that are shared by many applications, so an executable below them does not tell which application it is
+var sharedRoots = map[string]bool{
+	"/":            true,
+	"/app":         true,
+	"/home":        true,
+	"/opt":         true,
+	"/snap":        true,
+	"/usr":         true,
+	"/usr/games":   true,
+	"/usr/lib":     true,
+	"/usr/libexec": true,
+	"/usr/local":   true,
+	"/usr/share":   true,
+}
+
+// Commands that run other programs, the program they run is what identifies the application
+var interpreters = map[string]bool{
+	"bash":    true,
+	"dotnet":  true,
+	"gjs":     true,
+	"java":    true,
+	"mono":    true,
+	"node":    true,
+	"perl":    true,
+	"python":  true,
+	"python2": true,
+	"python3": true,
+	"ruby":    true,
+	"sh":      true,
+}
+
+var (
+	flatpakScope = regexp.MustCompile(`app-flatpak-(.+)-[0-9]+\.scope`)
+	snapScope    = regexp.MustCompile(`snap\.([^./]+)\.`)
+)
+
+// Desktop entry of an application, exec is the executable it runs, resolved to an absolute path when possible.
+// https://specifications.freedesktop.org/desktop-entry-spec/latest/
+type desktopEntry struct {
+	id      string
+	name    string
+	wmClass string
+	flatpak string
+	snap    string
+	exec    []string
+}
+
+func (e *desktopEntry) application() *Application {
+	return &Application{
+		ID:      e.id,
+		Name:    e.name,
+		WMClass: e.wmClass,
+		Flatpak: e.flatpak,
+		Snap:    e.snap,
+	}
+}
+
+// Install root of an application, like /opt/goland, every executable below it belongs to the application
+type applicationRoot struct {
+	path  string
+	entry *desktopEntry
+}
+
+// applicationIndex finds desktop entries by what they run
+type applicationIndex struct {
+	home      string
+	byPath    map[string]*desktopEntry
+	byName    map[string]*desktopEntry
+	byFlatpak map[string]*desktopEntry
+	bySnap    map[string]*desktopEntry
+	roots     []applicationRoot
+}
+
+// How a process is packaged, found from its sandbox and environment
+type processPackaging struct {
+	flatpak  string
+	snap     string
+	appImage string
+}
+
+var applications struct {
+	mu    sync.Mutex
+	index *applicationIndex
+	built time.Time
+}
+
+// Find the application of a process. Flatpak and Snap applications are known by their sandbox, everything else is
+// looked up among the desktop entries by what the process runs.
+func (t *target) Application(process *Process) (*Application, error) {
+	applications.mu.Lock()
+	if applications.index == nil || time.Since(applications.built) > applicationIndexLifetime {
+		home, _ := os.UserHomeDir()
+		applications.index = newApplicationIndex(applicationDirectories(os.Getenv, home), home, exec.LookPath)
+		applications.built = time.Now()
+	}
+
+	index := applications.index
+	applications.mu.Unlock()
+
+	return index.resolve(process, readProcessPackaging(process.ProcessID))
+}
+
+// Directories with desktop entries in order of precedence, the user directory comes before the system directories.
+// Flatpak and snapd export their entries to directories that are usually, but not always, in XDG_DATA_DIRS.
+func applicationDirectories(env func(string) string, home string) []string {
+	dataHome := env("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+
+	dataDirs := env("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+
+	directories := append([]string{dataHome}, strings.Split(dataDirs, ":")...)
+	directories = append(directories,
+		filepath.Join(home, ".local", "share", "flatpak", "exports", "share"),
+		"/var/lib/flatpak/exports/share",
+		"/var/lib/snapd/desktop",
+	)
+
+	var result []string
+	seen := make(map[string]bool)
+	for _, directory := range directories {
+		if directory == "" {
+			continue
+		}
+
+		directory = filepath.Join(directory, "applications")
+		if seen[directory] {
+			continue
+		}
+
+		seen[directory] = true
+		result = append(result, directory)
+	}
+
+	return result
+}
+
+// Read every desktop entry of the directories, the first entry with an id wins
+func newApplicationIndex(directories []string, home string, lookPath func(string) (string, error)) *applicationIndex {
+	index := &applicationIndex{
+		home:      home,
+		byPath:    make(map[string]*desktopEntry),
+		byName:    make(map[string]*desktopEntry),
+		byFlatpak: make(map[string]*desktopEntry),
+		bySnap:    make(map[string]*desktopEntry),
+	}
+
+	seen := make(map[string]bool)
+	for _, directory := range directories {
+		_ = filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || !strings.HasSuffix(path, ".desktop") {
+				return nil
+			}
+
+			// Entries in sub directories have the directory in their id, like kde-konsole for kde/konsole.desktop
+			relative, err := filepath.Rel(directory, path)
+			if err != nil {
+				return nil
+			}
+
+			id := strings.Replace(strings.TrimSuffix(relative, ".desktop"), string(filepath.Separator), "-", -1)
+			if seen[id] {
+				return nil
+			}
+
+			seen[id] = true
+
+			file, err := os.Open(path)
+			if err != nil {
+				return nil
+			}
+
+			defer file.Close()
+
+			entry, ok := parseDesktopEntry(id, file)
+			if ok {
+				index.add(entry, lookPath)
+			}
+
+			return nil
+		})
+	}
+
+	sort.SliceStable(index.roots, func(i, j int) bool {
+		return len(index.roots[i].path) > len(index.roots[j].path)
+	})
+
+	return index
+}
+
+func (i *applicationIndex) add(entry *desktopEntry, lookPath func(string) (string, error)) {
+	addOnce := func(m map[string]*desktopEntry, key string) {
+		if _, ok := m[key]; !ok && key != "" {
+			m[key] = entry
+		}
+	}
+
+	addOnce(i.byFlatpak, entry.flatpak)
+	addOnce(i.bySnap, entry.snap)
+
+	for _, command := range entry.exec {
+		addOnce(i.byName, filepath.Base(command))
+
+		path := command
+		if !filepath.IsAbs(path) {
+			found, err := lookPath(path)
+			if err != nil {
+				continue
+			}
+
+			path = found
+		}
+
+		addOnce(i.byPath, path)
+
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			addOnce(i.byPath, resolved)
+			path = resolved
+		}
+
+		root := filepath.Dir(path)
+		if filepath.Base(root) == "bin" {
+			root = filepath.Dir(root)
+		}
+
+		if !sharedRoots[root] && root != i.home && root != filepath.Join(i.home, ".local") {
+			i.roots = append(i.roots, applicationRoot{path: root, entry: entry})
+		}
+	}
+}
+
+// Find the application of a process by its packaging, by the paths it runs, by the install root of its executable,
+// and last by the name of its executable
+func (i *applicationIndex) resolve(process *Process, packaging processPackaging) (*Application, error) {
+	if packaging.flatpak != "" {
+		if entry, ok := i.byFlatpak[packaging.flatpak]; ok {
+			return entry.application(), nil
+		}
+
+		return &Application{ID: packaging.flatpak, Name: packaging.flatpak, Flatpak: packaging.flatpak}, nil
+	}
+
+	if packaging.snap != "" {
+		if entry, ok := i.bySnap[packaging.snap]; ok {
+			return entry.application(), nil
+		}
+
+		return &Application{ID: packaging.snap, Name: packaging.snap, Snap: packaging.snap}, nil
+	}
+
+	paths := []string{packaging.appImage, process.Executable}
+
+	// Interpreters run the application as their first argument, like python3 /usr/bin/meld
+	for n, arg := range process.CommandLine {
+		if n > 1 {
+			break
+		}
+
+		if filepath.IsAbs(arg) {
+			paths = append(paths, arg)
+		}
+	}
+
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+
+		if entry, ok := i.byPath[path]; ok {
+			return entry.application(), nil
+		}
+
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			continue
+		}
+
+		if entry, ok := i.byPath[resolved]; ok {
+			return entry.application(), nil
+		}
+	}
+
+	// Bundled runtimes are below the install root, like /opt/goland/jbr/bin/java
+	for _, root := range i.roots {
+		if strings.HasPrefix(process.Executable, root.path+string(filepath.Separator)) {
+			return root.entry.application(), nil
+		}
+	}
+
+	if entry, ok := i.byName[filepath.Base(process.Executable)]; ok && process.Executable != "" {
+		return entry.application(), nil
+	}
+
+	return nil, ErrUnknownApplication
+}
+
+// Parse the Desktop Entry group of a desktop file, returns false for entries that are not applications or are hidden
+func parseDesktopEntry(id string, reader io.Reader) (*desktopEntry, bool) {
+	entry := &desktopEntry{id: id}
+	values := make(map[string]string)
+
+	var group string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			group = line[1 : len(line)-1]
+			continue
+		}
+
+		if group != "Desktop Entry" {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		// Localised keys like Name[sv] are skipped
+		key := strings.TrimSpace(parts[0])
+		if _, ok := values[key]; !ok && !strings.Contains(key, "[") {
+			values[key] = strings.TrimSpace(parts[1])
+		}
+	}
+
+	if values["Type"] != "Application" || values["Hidden"] == "true" {
+		return nil, false
+	}
+
+	entry.name = values["Name"]
+	entry.wmClass = values["StartupWMClass"]
+	entry.flatpak = values["X-Flatpak"]
+	entry.snap = values["X-SnapInstanceName"]
+
+	command, flatpak, snap := unwrapExec(desktopExecArgs(values["Exec"]))
+	if entry.flatpak == "" {
+		entry.flatpak = flatpak
+	}
+
+	if entry.snap == "" {
+		entry.snap = snap
+	}
+
+	if command != "" {
+		entry.exec = append(entry.exec, command)
+	}
+
+	if tryExec := values["TryExec"]; tryExec != "" && tryExec != command {
+		entry.exec = append(entry.exec, tryExec)
+	}
+
+	return entry, true
+}
+
+// Split the Exec key into arguments, double quotes group arguments and backslash escapes inside them. Field codes like
+// %f and %U are removed.
+func desktopExecArgs(value string) []string {
+	var (
+		result  []string
+		arg     strings.Builder
+		quoted  bool
+		escaped bool
+		started bool
+	)
+
+	flush := func() {
+		if started && !(arg.Len() == 2 && strings.HasPrefix(arg.String(), "%")) {
+			result = append(result, arg.String())
+		}
+
+		arg.Reset()
+		started = false
+	}
+
+	for _, r := range value {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+			started = true
+		case !quoted && (r == ' ' || r == '\t'):
+			flush()
+		default:
+			arg.WriteRune(r)
+			started = true
+		}
+	}
+
+	flush()
+
+	return result
+}
+
+// Find what a command line runs. env and interpreters are skipped, flatpak run gives the Flatpak app id and the
+// launchers in /snap/bin give the snap name. Shell commands given with -c can not be told.
+func unwrapExec(args []string) (command string, flatpak string, snap string) {
+	for len(args) > 0 {
+		if interpreters[filepath.Base(args[0])] {
+			args = args[1:]
+			for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+				if args[0] == "-c" {
+					return "", "", ""
+				}
+
+				args = args[1:]
+			}
+
+			continue
+		}
+
+		switch filepath.Base(args[0]) {
+		case "env":
+			args = args[1:]
+			for len(args) > 0 && (strings.Contains(args[0], "=") || strings.HasPrefix(args[0], "-")) {
+				args = args[1:]
+			}
+
+			continue
+		case "flatpak":
+			running := false
+			for _, arg := range args[1:] {
+				if arg == "run" {
+					running = true
+					continue
+				}
+
+				if running && !strings.HasPrefix(arg, "-") {
+					return "", arg, ""
+				}
+			}
+
+			return "", "", ""
+		}
+
+		// Snap launchers are named snap.app, or just snap when the app has the same name
+		if strings.HasPrefix(args[0], "/snap/bin/") {
+			return "", "", strings.SplitN(filepath.Base(args[0]), ".", 2)[0]
+		}
+
+		return args[0], "", ""
+	}
+
+	return "", "", ""
+}
+
+// Find how a process is packaged. Flatpak applications have .flatpak-info in their root and a scope in their
+// cgroup, snaps run from /snap and AppImages tell where they are in the environment.
+func readProcessPackaging(processID int64) processPackaging {
+	directory := filepath.Join(procDirectory, strconv.FormatInt(processID, 10))
+
+	var packaging processPackaging
+
+	info, err := ioutil.ReadFile(filepath.Join(directory, "root", ".flatpak-info"))
+	if err == nil {
+		packaging.flatpak = parseFlatpakInfo(info)
+	}
+
+	cgroup, err := ioutil.ReadFile(filepath.Join(directory, "cgroup"))
+	if err == nil {
+		flatpak, snap := parseCgroupPackaging(cgroup)
+		if packaging.flatpak == "" {
+			packaging.flatpak = flatpak
+		}
+
+		packaging.snap = snap
+	}
+
+	executable, err := os.Readlink(filepath.Join(directory, "exe"))
+	if err == nil && strings.HasPrefix(executable, "/snap/") && packaging.snap == "" {
+		packaging.snap = strings.SplitN(strings.TrimPrefix(executable, "/snap/"), "/", 2)[0]
+	}
+
+	environ, err := ioutil.ReadFile(filepath.Join(directory, "environ"))
+	if err == nil {
+		for _, variable := range bytes.Split(environ, []byte{0}) {
+			if bytes.HasPrefix(variable, []byte("APPIMAGE=")) {
+				packaging.appImage = string(bytes.TrimPrefix(variable, []byte("APPIMAGE=")))
+			}
+		}
+	}
+
+	return packaging
+}
+
+// Get the app id from the name key of the Application group
+func parseFlatpakInfo(b []byte) string {
+	var group string
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			group = line
+			continue
+		}
+
+		if group == "[Application]" && strings.HasPrefix(line, "name=") {
+			return strings.TrimPrefix(line, "name=")
+		}
+	}
+
+	return ""
+}
+
+// systemd puts Flatpak applications in an app-flatpak-<id>-<n>.scope and snaps in a snap.<name>.<app> scope
+func parseCgroupPackaging(b []byte) (flatpak string, snap string) {
+	if match := flatpakScope.FindSubmatch(b); match != nil {
+		flatpak = string(match[1])
+	}
+
+	if match := snapScope.FindSubmatch(b); match != nil {
+		snap = string(match[1])
+	}
+
+	return flatpak, snap
+}
@@ -0,0 +1,162 @@
+// +build linux
+
+package system
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeDesktopEntry(t *testing.T, directory string, name string, content string) {
+	err := os.MkdirAll(filepath.Dir(filepath.Join(directory, name)), 0700)
+	assert.NoError(t, err, "creating application directory should not result in error")
+
+	err = ioutil.WriteFile(filepath.Join(directory, name), []byte(content), 0600)
+	assert.NoError(t, err, "writing desktop entry should not result in error")
+}
+
+func TestLinux_DesktopExecArgs(t *testing.T) {
+	assert.Equal(t, []string{"/usr/bin/code", "--unity-launch"}, desktopExecArgs("/usr/bin/code --unity-launch %F"), "field codes should be removed")
+	assert.Equal(t, []string{"/opt/My App/run", "a \"b\""}, desktopExecArgs(`"/opt/My App/run" "a \"b\""`), "quotes should group arguments")
+}
+
+func TestLinux_UnwrapExec(t *testing.T) {
+	tests := []struct {
+		exec    string
+		command string
+		flatpak string
+		snap    string
+	}{
+		{"env BAMF_DESKTOP_FILE_HINT=/var/lib/snapd/desktop/applications/code_code.desktop /snap/bin/code --force-user-env %F", "", "", "code"},
+		{"/usr/bin/flatpak run --branch=stable --arch=x86_64 --command=code --file-forwarding com.visualstudio.code @@ %F @@", "", "com.visualstudio.code", ""},
+		{"/opt/goland/bin/goland.sh %f", "/opt/goland/bin/goland.sh", "", ""},
+		{"python3 -O /usr/bin/meld %F", "/usr/bin/meld", "", ""},
+		{"sh -c \"code --new-window\"", "", "", ""},
+	}
+
+	for _, test := range tests {
+		command, flatpak, snap := unwrapExec(desktopExecArgs(test.exec))
+		assert.Equal(t, test.command, command, test.exec)
+		assert.Equal(t, test.flatpak, flatpak, test.exec)
+		assert.Equal(t, test.snap, snap, test.exec)
+	}
+}
+
+func TestLinux_ApplicationIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pacerank-applications")
+	assert.NoError(t, err, "creating temporary directory should not result in error")
+	defer os.RemoveAll(dir)
+
+	user, system := filepath.Join(dir, "user"), filepath.Join(dir, "system")
+
+	writeDesktopEntry(t, system, "jetbrains-goland.desktop", `[Desktop Entry]
+Type=Application
+Name=GoLand
+Name[sv]=GoLand på svenska
+Exec="/opt/goland/bin/goland.sh" %f
+StartupWMClass=jetbrains-goland
+
+[Desktop Action new-window]
+Name=Something else
+Exec=/usr/bin/other
+`)
+	writeDesktopEntry(t, system, "code_code.desktop", `[Desktop Entry]
+Type=Application
+Name=Visual Studio Code
+Exec=env BAMF_DESKTOP_FILE_HINT=/var/lib/snapd/desktop/applications/code_code.desktop /snap/bin/code %F
+X-SnapInstanceName=code
+`)
+	writeDesktopEntry(t, system, "com.visualstudio.code.desktop", `[Desktop Entry]
+Type=Application
+Name=Code (Flatpak)
+Exec=/usr/bin/flatpak run --command=code com.visualstudio.code %F
+X-Flatpak=com.visualstudio.code
+`)
+	writeDesktopEntry(t, system, "nvim.desktop", `[Desktop Entry]
+Type=Application
+Name=Neovim
+Exec=nvim %F
+Terminal=true
+`)
+	writeDesktopEntry(t, system, "hidden.desktop", `[Desktop Entry]
+Type=Application
+Name=Hidden
+Exec=/usr/bin/hidden
+Hidden=true
+`)
+	writeDesktopEntry(t, system, "kde/kate.desktop", `[Desktop Entry]
+Type=Application
+Name=Kate
+Exec=/usr/bin/kate
+`)
+
+	// The user entry comes first and wins over the system entry with the same id
+	writeDesktopEntry(t, user, "nvim.desktop", `[Desktop Entry]
+Type=Application
+Name=My Neovim
+Exec=nvim %F
+`)
+
+	lookPath := func(file string) (string, error) {
+		if strings.Contains(file, "/") {
+			return file, nil
+		}
+
+		if file == "nvim" {
+			return "/usr/bin/nvim", nil
+		}
+
+		return "", errors.New("not found")
+	}
+
+	index := newApplicationIndex([]string{user, system}, "/home/user", lookPath)
+
+	tests := []struct {
+		name      string
+		process   *Process
+		packaging processPackaging
+		id        string
+		ok        bool
+	}{
+		{"bundled runtime", &Process{Executable: "/opt/goland/jbr/bin/java"}, processPackaging{}, "jetbrains-goland", true},
+		{"snap", &Process{Executable: "/snap/code/45/usr/share/code/code"}, processPackaging{snap: "code"}, "code_code", true},
+		{"flatpak", &Process{Executable: "/app/extra/vscode/code"}, processPackaging{flatpak: "com.visualstudio.code"}, "com.visualstudio.code", true},
+		{"unknown flatpak", &Process{Executable: "/app/bin/gedit"}, processPackaging{flatpak: "org.gnome.gedit"}, "org.gnome.gedit", true},
+		{"path lookup", &Process{Executable: "/usr/bin/nvim"}, processPackaging{}, "nvim", true},
+		{"sub directory id", &Process{Executable: "/usr/bin/kate"}, processPackaging{}, "kde-kate", true},
+		{"hidden entry", &Process{Executable: "/usr/bin/hidden"}, processPackaging{}, "", false},
+		{"unknown", &Process{Executable: "/usr/bin/bash"}, processPackaging{}, "", false},
+	}
+
+	for _, test := range tests {
+		application, err := index.resolve(test.process, test.packaging)
+		assert.Equal(t, test.ok, err == nil, test.name)
+		if err == nil {
+			assert.Equal(t, test.id, application.ID, test.name)
+		}
+	}
+
+	application, _ := index.resolve(&Process{Executable: "/usr/bin/nvim"}, processPackaging{})
+	assert.Equal(t, "My Neovim", application.Name, "user entry should win over system entry")
+
+	application, _ = index.resolve(&Process{Executable: "/opt/goland/jbr/bin/java"}, processPackaging{})
+	assert.Equal(t, "GoLand", application.Name, "localised name should not be used")
+	assert.Equal(t, "jetbrains-goland", application.WMClass, "window class should be read")
+}
+
+func TestLinux_ParsePackaging(t *testing.T) {
+	flatpak, snap := parseCgroupPackaging([]byte("0::/user.slice/user-1000.slice/user@1000.service/app.slice/app-flatpak-com.visualstudio.code-2211.scope\n"))
+	assert.Equal(t, "com.visualstudio.code", flatpak, "flatpak app id should be read from scope")
+	assert.Empty(t, snap, "flatpak should not be a snap")
+
+	flatpak, snap = parseCgroupPackaging([]byte("0::/user.slice/user-1000.slice/user@1000.service/app.slice/snap.code.code-1c4a.scope\n"))
+	assert.Empty(t, flatpak, "snap should not be a flatpak")
+	assert.Equal(t, "code", snap, "snap name should be read from scope")
+
+	assert.Equal(t, "org.gnome.gedit", parseFlatpakInfo([]byte("[Application]\nname=org.gnome.gedit\nruntime=runtime/org.gnome.Platform/x86_64/3.38\n")), "app id should be read from flatpak info")
+}
@@ -0,0 +1,38 @@
+// +build linux
+
+package system
+
+import (
+	"os"
+	"strings"
+)
+
+// Open url in the browser of the user, returns ErrNoBrowser if there is no browser that can be reached
+func OpenBrowser(url string) error {
+	return launchBrowser(url, browserLaunchers(os.Getenv), startBrowser)
+}
+
+// Launchers in the order they are tried. $BROWSER comes first, it is a list of commands separated by colon and is
+// often set to forward the url from a SSH session. The desktop launchers are left out without a display, as they
+// seem to succeed without anything showing up.
+func browserLaunchers(env func(string) string) []browserLauncher {
+	var result []browserLauncher
+	for _, command := range strings.Split(env("BROWSER"), ":") {
+		fields := strings.Fields(command)
+		if len(fields) == 0 {
+			continue
+		}
+
+		result = append(result, browserLauncher{name: fields[0], args: fields[1:]})
+	}
+
+	if env("DISPLAY") == "" && env("WAYLAND_DISPLAY") == "" {
+		return result
+	}
+
+	return append(result,
+		browserLauncher{name: "xdg-open"},
+		browserLauncher{name: "gio", args: []string{"open"}},
+		browserLauncher{name: "sensible-browser"},
+	)
+}
@@ -0,0 +1,36 @@
+// +build linux
+
+package system
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLinux_BrowserLaunchers(t *testing.T) {
+	env := func(values map[string]string) func(string) string {
+		return func(key string) string {
+			return values[key]
+		}
+	}
+
+	names := func(launchers []browserLauncher) []string {
+		var result []string
+		for _, launcher := range launchers {
+			result = append(result, launcher.name)
+		}
+
+		return result
+	}
+
+	assert.Empty(t, browserLaunchers(env(nil)), "headless machine without BROWSER should not have launchers")
+
+	assert.Equal(t, []string{"w3m", "forward"}, names(browserLaunchers(env(map[string]string{
+		"BROWSER": "w3m:forward --url %s",
+	}))), "BROWSER should be used without display")
+
+	assert.Equal(t, []string{"firefox", "xdg-open", "gio", "sensible-browser"}, names(browserLaunchers(env(map[string]string{
+		"BROWSER":         "firefox",
+		"WAYLAND_DISPLAY": "wayland-0",
+	}))), "desktop launchers should follow BROWSER with a display")
+}
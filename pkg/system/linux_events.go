@@ -0,0 +1,208 @@
+// +build linux
+
+package system
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/rs/zerolog/log"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Constants of the netlink process connector, from linux/connector.h and linux/cn_proc.h
+const (
+	netlinkConnector = 11
+
+	cnIdxProc = 1
+	cnValProc = 1
+
+	procCnMcastListen = 1
+	procCnMcastIgnore = 2
+
+	procEventNone = 0x00000000
+	procEventExec = 0x00000002
+	procEventExit = 0x80000000
+
+	nlmsgHeaderLength = 16
+	cnMsgLength       = 20
+)
+
+func (t *target) WatchProcesses(ctx context.Context) <-chan ProcessEvent {
+	channel := make(chan ProcessEvent)
+
+	connector, err := listenProcConnector()
+	if err != nil {
+		log.Debug().Err(err).Msg("process connector is not available, fall back to polling processes")
+		go pollProcesses(ctx, t.Processes, channel)
+		return channel
+	}
+
+	go connector.watch(ctx, channel)
+	return channel
+}
+
+// procConnector receives process events from the kernel over netlink. It requires CAP_NET_ADMIN.
+// https://www.kernel.org/doc/html/latest/driver-api/connector.html
+type procConnector struct {
+	fd int
+}
+
+// Open a netlink socket and subscribe to process events. The kernel acknowledges the subscription, and the
+// acknowledgement carries the error if the process is not allowed to listen.
+func listenProcConnector() (*procConnector, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, netlinkConnector)
+	if err != nil {
+		return nil, err
+	}
+
+	connector := &procConnector{fd: fd}
+
+	err = syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: cnIdxProc})
+	if err != nil {
+		connector.Close()
+		return nil, err
+	}
+
+	// Wake up regularly while reading, so the watch can see that it has been cancelled
+	err = syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &syscall.Timeval{Sec: 1})
+	if err != nil {
+		connector.Close()
+		return nil, err
+	}
+
+	err = connector.send(procCnMcastListen)
+	if err != nil {
+		connector.Close()
+		return nil, err
+	}
+
+	deadline := time.Now().Add(time.Second * 2)
+	for time.Now().Before(deadline) {
+		what, data, err := connector.receive()
+		if err == syscall.EAGAIN || err == syscall.EINTR {
+			continue
+		}
+
+		if err != nil {
+			connector.Close()
+			return nil, err
+		}
+
+		if what != procEventNone || len(data) < 4 {
+			continue
+		}
+
+		if code := binary.LittleEndian.Uint32(data); code != 0 {
+			connector.Close()
+			return nil, errors.New(fmt.Sprintf("process connector refused to subscribe: %s", syscall.Errno(code)))
+		}
+
+		return connector, nil
+	}
+
+	connector.Close()
+	return nil, errors.New("process connector did not acknowledge the subscription")
+}
+
+// Send a multicast operation to the process connector
+func (c *procConnector) send(op uint32) error {
+	b := make([]byte, nlmsgHeaderLength+cnMsgLength+4)
+
+	// nlmsghdr
+	binary.LittleEndian.PutUint32(b[0:], uint32(len(b)))
+	binary.LittleEndian.PutUint16(b[4:], syscall.NLMSG_DONE)
+	binary.LittleEndian.PutUint32(b[12:], uint32(os.Getpid()))
+
+	// cn_msg
+	binary.LittleEndian.PutUint32(b[16:], cnIdxProc)
+	binary.LittleEndian.PutUint32(b[20:], cnValProc)
+	binary.LittleEndian.PutUint16(b[32:], 4)
+
+	// proc_cn_mcast_op
+	binary.LittleEndian.PutUint32(b[36:], op)
+
+	return syscall.Sendto(c.fd, b, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK})
+}
+
+// Receive a single process event, returns what happened and the event data
+func (c *procConnector) receive() (uint32, []byte, error) {
+	b := make([]byte, 4096)
+	n, _, err := syscall.Recvfrom(c.fd, b, 0)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return parseProcEvent(b[:n])
+}
+
+// Parse a netlink message from the process connector into the event type and the event data
+func parseProcEvent(b []byte) (uint32, []byte, error) {
+	// The proc_event starts with what, cpu and timestamp_ns before the event data
+	start := nlmsgHeaderLength + cnMsgLength
+	if len(b) < start+16 {
+		return 0, nil, errors.New("process event is too short")
+	}
+
+	return binary.LittleEndian.Uint32(b[start:]), b[start+16:], nil
+}
+
+// Read events until the context is done, threads are filtered away so only processes are reported
+func (c *procConnector) watch(ctx context.Context, channel chan<- ProcessEvent) {
+	defer close(channel)
+	defer c.Close()
+
+	for ctx.Err() == nil {
+		what, data, err := c.receive()
+		if err == syscall.EAGAIN || err == syscall.EINTR {
+			continue
+		}
+
+		if err != nil {
+			log.Error().Err(err).Msg("could not receive process event")
+			return
+		}
+
+		if len(data) < 8 {
+			continue
+		}
+
+		pid := int64(binary.LittleEndian.Uint32(data))
+		tgid := int64(binary.LittleEndian.Uint32(data[4:]))
+		if pid != tgid {
+			continue
+		}
+
+		var event ProcessEvent
+		switch what {
+		case procEventExec:
+			// The process keeps its process ID and start time when it runs a new executable
+			processes.evict(pid)
+
+			process, err := getProcess(pid)
+			if err != nil {
+				log.Debug().Err(err).Int64("pid", pid).Msg("could not read started process")
+			}
+
+			event = ProcessEvent{Type: ProcessStarted, ProcessID: pid, Process: process, Time: time.Now()}
+		case procEventExit:
+			processes.evict(pid)
+			event = ProcessEvent{Type: ProcessExited, ProcessID: pid, Time: time.Now()}
+		default:
+			continue
+		}
+
+		select {
+		case channel <- event:
+		case <-ctx.Done():
+		}
+	}
+}
+
+func (c *procConnector) Close() {
+	_ = c.send(procCnMcastIgnore)
+	_ = syscall.Close(c.fd)
+}
@@ -0,0 +1,94 @@
+// +build linux
+
+package system
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// gnomeProvider asks GNOME Shell for the focused window over D-Bus. Since GNOME 41 Shell.Eval is only available when
+// GNOME Shell runs in unsafe mode or development mode, otherwise it answers every script with a failure and an empty
+// result. The provider then stops asking for the rest of the session.
+type gnomeProvider struct {
+	// eval runs a script in GNOME Shell, it is replaceable so the provider can be tested without a session bus
+	eval func(script string) (bool, string, error)
+
+	unsupported bool
+}
+
+var errGnomeEvalUnsupported = errors.New("gnome shell does not allow Shell.Eval, it requires unsafe mode since GNOME 41")
+
+func (p *gnomeProvider) Name() string {
+	return "gnome"
+}
+
+// The result of the script is given back as JSON
+const gnomeFocusWindowScript = "(w => w ? {pid: w.get_pid(), title: w.get_title()} : null)(global.display.focus_window)"
+
+type gnomeWindow struct {
+	Pid   int64  `json:"pid"`
+	Title string `json:"title"`
+}
+
+func (p *gnomeProvider) ActiveWindow() (*Window, error) {
+	if p.unsupported {
+		return nil, errGnomeEvalUnsupported
+	}
+
+	if p.eval == nil {
+		p.eval = gnomeShellEval
+	}
+
+	ok, result, err := p.eval(gnomeFocusWindowScript)
+	if err != nil {
+		return nil, err
+	}
+
+	// A failure without a message is how Shell.Eval answers when it is disabled, a failing script has a message
+	if !ok && result == "" {
+		p.unsupported = true
+		log.Warn().Msg("gnome shell does not tell which window is focused, Shell.Eval is disabled since GNOME 41 " +
+			"unless gnome shell runs in unsafe mode. Only windows of X11 applications can be seen.")
+		return nil, errGnomeEvalUnsupported
+	}
+
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("gnome shell could not evaluate script: %s", result))
+	}
+
+	var window gnomeWindow
+	err = json.Unmarshal([]byte(result), &window)
+	if err != nil {
+		return nil, err
+	}
+
+	if window.Pid <= 0 {
+		return nil, errors.New("no window is currently focused in gnome shell")
+	}
+
+	return &Window{ProcessID: window.Pid, Title: window.Title}, nil
+}
+
+func (p *gnomeProvider) Close() error {
+	return nil
+}
+
+// Call org.gnome.Shell.Eval on the session bus
+func gnomeShellEval(script string) (bool, string, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return false, "", err
+	}
+
+	var (
+		ok     bool
+		result string
+	)
+
+	err = conn.Object("org.gnome.Shell", "/org/gnome/Shell").Call("org.gnome.Shell.Eval", 0, script).Store(&ok, &result)
+	return ok, result, err
+}
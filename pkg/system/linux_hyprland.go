@@ -0,0 +1,76 @@
+// +build linux
+
+package system
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// hyprlandProvider asks Hyprland for the active window over the request socket
+// https://wiki.hyprland.org/IPC/
+type hyprlandProvider struct {
+	socket string
+}
+
+type hyprlandWindow struct {
+	Pid   int64  `json:"pid"`
+	Class string `json:"class"`
+	Title string `json:"title"`
+}
+
+// Find the request socket, Hyprland moved it from /tmp/hypr into XDG_RUNTIME_DIR in later versions
+func hyprlandSocket(runtimeDir, signature string) string {
+	socket := filepath.Join(runtimeDir, "hypr", signature, ".socket.sock")
+	if _, err := os.Stat(socket); err == nil {
+		return socket
+	}
+
+	return filepath.Join("/tmp", "hypr", signature, ".socket.sock")
+}
+
+func (p *hyprlandProvider) Name() string {
+	return "hyprland"
+}
+
+func (p *hyprlandProvider) ActiveWindow() (*Window, error) {
+	conn, err := net.DialTimeout("unix", p.socket, time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(time.Second * 2))
+
+	// The j flag gives the reply as JSON, Hyprland closes the connection after the reply
+	if _, err = conn.Write([]byte("j/activewindow")); err != nil {
+		return nil, err
+	}
+
+	b, err := ioutil.ReadAll(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var window hyprlandWindow
+	err = json.Unmarshal(b, &window)
+	if err != nil {
+		return nil, errors.New("no window is currently active in hyprland")
+	}
+
+	if window.Pid <= 0 {
+		return nil, errors.New("active hyprland window does not belong to a process")
+	}
+
+	return &Window{ProcessID: window.Pid, Title: window.Title}, nil
+}
+
+func (p *hyprlandProvider) Close() error {
+	return nil
+}
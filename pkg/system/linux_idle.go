@@ -0,0 +1,55 @@
+// +build linux
+
+package system
+
+import (
+	"github.com/rs/zerolog/log"
+	"os"
+	"time"
+)
+
+// Idle time is asked from the X server when running X11, as it counts every input event. Under Wayland the X server
+// only sees input to XWayland windows, so logind is asked instead.
+func (t *target) IdleTime() (time.Duration, error) {
+	if os.Getenv("WAYLAND_DISPLAY") == "" && os.Getenv("DISPLAY") != "" {
+		idle, err := t.x11IdleTime()
+		if err == nil {
+			return idle, nil
+		}
+
+		log.Debug().Err(err).Msg("could not get idle time from x server")
+	}
+
+	return logindIdleTime()
+}
+
+func (t *target) ScreenLocked() (bool, error) {
+	return logindLocked()
+}
+
+func (t *target) x11IdleTime() (time.Duration, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.idle == nil {
+		x, err := dialX11(os.Getenv("DISPLAY"))
+		if err != nil {
+			return 0, err
+		}
+
+		t.idle = x
+	}
+
+	idle, err := t.idle.idleTime()
+	if err != nil {
+		// Only protocol errors leaves the connection usable
+		if _, ok := err.(x11Error); !ok {
+			_ = t.idle.Close()
+			t.idle = nil
+		}
+
+		return 0, err
+	}
+
+	return idle, nil
+}
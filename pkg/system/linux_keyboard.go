@@ -2,7 +2,398 @@
 
 package system
 
-// This function setups a listener on the channel that send back the byte type of the key press
-func (t *target) ListenKeyboard(channel chan byte) {
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog/log"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
 
+// ErrInputPermission is returned when the input devices exist, but the current user is not allowed to read them.
+var ErrInputPermission = errors.New("no permission to read keyboard input devices, add the user to the input group " +
+	"or grant read access to /dev/input/event*")
+
+// ErrNoKeyboard is returned when no keyboard could be found among the input devices.
+var ErrNoKeyboard = errors.New("no keyboard input device could be found")
+
+// Event types and codes from linux/input-event-codes.h
+const (
+	evKey = 0x01
+	evRep = 0x14
+
+	// Key codes from BTN_MISC and upwards are buttons on mice, joysticks and tablets
+	btnMisc = 0x100
+)
+
+// Value of an EV_KEY event when the key goes down, 0 is a release and 2 is an auto repeat
+const (
+	keyReleased = 0
+	keyPressed  = 1
+)
+
+// Key codes from linux/input-event-codes.h that are not characters
+var (
+	linuxEditKeys = map[uint16]bool{
+		14:  true, // KEY_BACKSPACE
+		110: true, // KEY_INSERT
+		111: true, // KEY_DELETE
+	}
+
+	linuxNavigationKeys = map[uint16]bool{
+		102: true, // KEY_HOME
+		103: true, // KEY_UP
+		104: true, // KEY_PAGEUP
+		105: true, // KEY_LEFT
+		106: true, // KEY_RIGHT
+		107: true, // KEY_END
+		108: true, // KEY_DOWN
+		109: true, // KEY_PAGEDOWN
+	}
+
+	// The value tells if it is a command modifier
+	linuxModifierKeys = map[uint16]bool{
+		29:  true,  // KEY_LEFTCTRL
+		42:  false, // KEY_LEFTSHIFT
+		54:  false, // KEY_RIGHTSHIFT
+		56:  true,  // KEY_LEFTALT
+		58:  false, // KEY_CAPSLOCK
+		97:  true,  // KEY_RIGHTCTRL
+		100: true,  // KEY_RIGHTALT
+		125: true,  // KEY_LEFTMETA
+		126: true,  // KEY_RIGHTMETA
+	}
+)
+
+// inputEvent is the raw input_event structure that the kernel writes to /dev/input/event*.
+// https://www.kernel.org/doc/html/latest/input/input.html#event-interface
+type inputEvent struct {
+	Time  syscall.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+// Location of the input device list and the device nodes, they are variables so they can be pointed somewhere else
+var (
+	inputDevicesPath = "/proc/bus/input/devices"
+	inputDirectory   = "/dev/input"
+)
+
+// The focused process is looked up again when a key goes down this long after the last look up, so fast typing does not
+// ask the window providers for every key
+const focusedLifetime = time.Millisecond * 250
+
+// keyboardListener keeps track of the keyboard devices that are currently being read
+type keyboardListener struct {
+	mu      sync.Mutex
+	devices map[string]bool
+	channel chan KeyEvent
+	focused func() int64
+}
+
+// This function setups a listener on the channel that send back the class of every key that goes down or up
+func (t *target) ListenKeyboard(channel chan KeyEvent) {
+	listener := &keyboardListener{
+		devices: make(map[string]bool),
+		channel: channel,
+		focused: cachedFocus(func() int64 {
+			window, err := t.activeWindow()
+			if err != nil {
+				return 0
+			}
+
+			return window.ProcessID
+		}, focusedLifetime),
+	}
+
+	err := listener.scan()
+	if err != nil {
+		log.Error().Err(err).Msg("could not listen to keyboard")
+	}
+
+	// Keep listening for keyboards that are plugged in after start
+	err = listener.hotplug()
+	if err != nil {
+		log.Error().Err(err).Msg("could not watch for new keyboards")
+	}
+}
+
+// Find all keyboards and start reading the ones that are not read already. Returns ErrInputPermission if there were
+// keyboards, but none of them could be opened because of permissions.
+func (l *keyboardListener) scan() error {
+	devices, err := keyboardDevices(inputDevicesPath)
+	if err != nil {
+		return err
+	}
+
+	if len(devices) == 0 {
+		return ErrNoKeyboard
+	}
+
+	var denied int
+	for _, device := range devices {
+		l.mu.Lock()
+		listening := l.devices[device]
+		l.mu.Unlock()
+
+		if listening {
+			continue
+		}
+
+		file, err := os.Open(device)
+		if os.IsPermission(err) {
+			denied++
+			continue
+		}
+
+		if err != nil {
+			log.Debug().Err(err).Str("device", device).Msg("could not open keyboard")
+			continue
+		}
+
+		l.mu.Lock()
+		l.devices[device] = true
+		l.mu.Unlock()
+
+		log.Debug().Str("device", device).Msg("listening to keyboard")
+		go l.read(device, file)
+	}
+
+	if denied == len(devices) {
+		return ErrInputPermission
+	}
+
+	return nil
+}
+
+// Read key presses from an opened device until it is unplugged
+func (l *keyboardListener) read(device string, file *os.File) {
+	defer func() {
+		_ = file.Close()
+
+		l.mu.Lock()
+		delete(l.devices, device)
+		l.mu.Unlock()
+	}()
+
+	err := readKeyboard(file, l.channel, l.focused)
+	if err != nil && !errors.Is(err, syscall.ENODEV) {
+		log.Error().Err(err).Str("device", device).Msg("could not read keyboard")
+		return
+	}
+
+	log.Debug().Str("device", device).Msg("keyboard was removed")
+}
+
+// Watch the input directory and scan for keyboards when a new device node appears. This function blocks.
+func (l *keyboardListener) hotplug() error {
+	fs, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	defer fs.Close()
+
+	err = fs.Add(inputDirectory)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case event, ok := <-fs.Events:
+			if !ok {
+				return nil
+			}
+
+			if event.Op&fsnotify.Create == 0 || !strings.HasPrefix(filepath.Base(event.Name), "event") {
+				break
+			}
+
+			// udev sets the permissions of the node shortly after it has been created
+			time.Sleep(time.Second)
+
+			err = l.scan()
+			if err != nil {
+				log.Error().Err(err).Msg("could not listen to new keyboard")
+			}
+		case err, ok := <-fs.Errors:
+			if !ok {
+				return nil
+			}
+
+			log.Error().Err(err).Msg("error while watching for new keyboards")
+		}
+	}
+}
+
+// Remember the focused process for a while, the keyboards are read in a goroutine each so it is safe for concurrent use
+func cachedFocus(focused func() int64, lifetime time.Duration) func() int64 {
+	var (
+		mu        sync.Mutex
+		processID int64
+		resolved  time.Time
+	)
+
+	return func() int64 {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if resolved.IsZero() || time.Since(resolved) >= lifetime {
+			processID = focused()
+			resolved = time.Now()
+		}
+
+		return processID
+	}
+}
+
+// Read raw input_event records from reader and send an event for every key that goes down or up on the channel.
+// Auto repeats and mouse buttons are skipped. It returns nil when the reader reaches the end. The focused process is
+// only looked up when a key goes down, a key that goes up belongs to the process it went down in.
+func readKeyboard(reader io.Reader, channel chan KeyEvent, focused func() int64) error {
+	tracker := newKeyTracker()
+	pressed := make(map[uint16]int64)
+
+	var event inputEvent
+	for {
+		err := binary.Read(reader, binary.LittleEndian, &event)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if event.Type != evKey || event.Code >= btnMisc {
+			continue
+		}
+
+		if event.Value != keyPressed && event.Value != keyReleased {
+			continue
+		}
+
+		class, command := linuxKeyClass(event.Code)
+		now := time.Unix(int64(event.Time.Sec), int64(event.Time.Usec)*int64(time.Microsecond))
+
+		key, ok := tracker.event(uint32(event.Code), event.Value == keyPressed, class, command, now)
+		if !ok {
+			continue
+		}
+
+		if key.State == KeyDown {
+			key.ProcessID = focused()
+			pressed[event.Code] = key.ProcessID
+		} else {
+			key.ProcessID = pressed[event.Code]
+			delete(pressed, event.Code)
+		}
+
+		channel <- key
+	}
+}
+
+// Classify a key code, and tell if it is a modifier that turns other keys into shortcuts
+func linuxKeyClass(code uint16) (KeyClass, bool) {
+	if command, ok := linuxModifierKeys[code]; ok {
+		return KeyModifier, command
+	}
+
+	switch {
+	case linuxEditKeys[code]:
+		return KeyEdit, false
+	case linuxNavigationKeys[code]:
+		return KeyNavigation, false
+	case code == 1 || (code >= 59 && code <= 68) || code == 87 || code == 88 || (code >= 183 && code <= 194):
+		// KEY_ESC and the function keys KEY_F1 to KEY_F24 run commands rather than type
+		return KeyShortcut, false
+	}
+
+	return KeyCharacter, false
+}
+
+// Parse the input device list and return the device nodes of everything that looks like a keyboard. A keyboard has the
+// kbd handler and supports key repeat, which rules out power buttons, lid switches and similar devices.
+func keyboardDevices(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	return parseInputDevices(file)
+}
+
+func parseInputDevices(reader io.Reader) ([]string, error) {
+	var (
+		result   []string
+		handlers []string
+		events   uint64
+	)
+
+	flush := func() {
+		defer func() {
+			handlers = nil
+			events = 0
+		}()
+
+		if events&(1<<evKey) == 0 || events&(1<<evRep) == 0 {
+			return
+		}
+
+		var keyboard bool
+		var node string
+		for _, handler := range handlers {
+			if handler == "kbd" {
+				keyboard = true
+			}
+
+			if strings.HasPrefix(handler, "event") {
+				node = handler
+			}
+		}
+
+		if keyboard && node != "" {
+			result = append(result, filepath.Join(inputDirectory, node))
+		}
+	}
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		// An empty line separates devices
+		if line == "" {
+			flush()
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "H: Handlers="):
+			handlers = strings.Fields(strings.TrimPrefix(line, "H: Handlers="))
+		case strings.HasPrefix(line, "B: EV="):
+			value, err := strconv.ParseUint(strings.TrimPrefix(line, "B: EV="), 16, 64)
+			if err != nil {
+				return nil, fmt.Errorf("could not parse event bitmap %q: %w", line, err)
+			}
+
+			events = value
+		}
+	}
+
+	flush()
+
+	return result, scanner.Err()
 }
@@ -0,0 +1,220 @@
+// +build linux
+
+package system
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const fakeInputDevices = `I: Bus=0019 Vendor=0000 Product=0001 Version=0000
+N: Name="Power Button"
+P: Phys=LNXPWRBN/button/input0
+H: Handlers=kbd event0
+B: PROP=0
+B: EV=3
+B: KEY=10000000000000 0
+
+I: Bus=0011 Vendor=0001 Product=0001 Version=ab41
+N: Name="AT Translated Set 2 keyboard"
+P: Phys=isa0060/serio0/input0
+H: Handlers=sysrq kbd leds event3
+B: PROP=0
+B: EV=120013
+B: KEY=402000000 3803078f800d001 feffffdfffefffff fffffffffffffffe
+
+I: Bus=0003 Vendor=046d Product=c077 Version=0111
+N: Name="Logitech USB Optical Mouse"
+H: Handlers=mouse0 event5
+B: PROP=0
+B: EV=17
+`
+
+func writeInputEvents(t *testing.T, events ...inputEvent) []byte {
+	var buffer bytes.Buffer
+	for _, event := range events {
+		err := binary.Write(&buffer, binary.LittleEndian, event)
+		assert.NoError(t, err, "writing input event should not result in error")
+	}
+
+	return buffer.Bytes()
+}
+
+func TestLinux_ParseInputDevices(t *testing.T) {
+	devices, err := parseInputDevices(strings.NewReader(fakeInputDevices))
+	assert.NoError(t, err, "parsing input devices should not result in error")
+	assert.Equal(t, []string{filepath.Join(inputDirectory, "event3")}, devices, "only the keyboard should be found")
+}
+
+func TestLinux_ReadKeyboard(t *testing.T) {
+	b := writeInputEvents(t,
+		inputEvent{Type: evKey, Code: 30, Value: keyPressed},
+		inputEvent{Type: evKey, Code: 30, Value: 2},
+		inputEvent{Type: evKey, Code: 30, Value: keyReleased},
+		inputEvent{Type: evKey, Code: 0x110, Value: keyPressed},
+		inputEvent{Type: 0x04, Code: 4, Value: 458756},
+		inputEvent{Type: evKey, Code: 14, Value: keyPressed},
+		inputEvent{Type: evKey, Code: 103, Value: keyPressed},
+		inputEvent{Type: evKey, Code: 29, Value: keyPressed},
+		inputEvent{Type: evKey, Code: 46, Value: keyPressed},
+		inputEvent{Type: evKey, Code: 46, Value: keyReleased},
+		inputEvent{Type: evKey, Code: 29, Value: keyReleased},
+		inputEvent{Type: evKey, Code: 42, Value: keyPressed},
+		inputEvent{Type: evKey, Code: 48, Value: keyPressed, Time: syscall.Timeval{Sec: 1600000000, Usec: 500}},
+	)
+
+	channel := make(chan KeyEvent, 20)
+	err := readKeyboard(bytes.NewReader(b), channel, func() int64 { return 4242 })
+	assert.NoError(t, err, "reading keyboard should not result in error")
+	close(channel)
+
+	var keys []KeyEvent
+	for key := range channel {
+		keys = append(keys, key)
+	}
+
+	type key struct {
+		class KeyClass
+		state KeyState
+	}
+
+	var result []key
+	for _, event := range keys {
+		assert.Equal(t, int64(4242), event.ProcessID, "focused process should be set")
+		result = append(result, key{event.Class, event.State})
+	}
+
+	assert.Equal(t, []key{
+		{KeyCharacter, KeyDown},
+		{KeyCharacter, KeyUp},
+		{KeyEdit, KeyDown},
+		{KeyNavigation, KeyDown},
+		{KeyModifier, KeyDown},
+		{KeyShortcut, KeyDown},
+		{KeyShortcut, KeyUp},
+		{KeyModifier, KeyUp},
+		{KeyModifier, KeyDown},
+		{KeyCharacter, KeyDown},
+	}, result, "keys should be classified, without repeats and mouse buttons")
+
+	assert.Equal(t, time.Unix(1600000000, 500000), keys[len(keys)-1].Time, "time should be read from the event")
+}
+
+func TestLinux_ReadKeyboardFocus(t *testing.T) {
+	b := writeInputEvents(t,
+		inputEvent{Type: evKey, Code: 30, Value: keyPressed},
+		inputEvent{Type: evKey, Code: 48, Value: keyPressed},
+		inputEvent{Type: evKey, Code: 30, Value: keyReleased},
+		inputEvent{Type: evKey, Code: 48, Value: keyReleased},
+	)
+
+	// Focus moves to another process with every look up
+	var lookups int64
+	focused := func() int64 {
+		lookups++
+		return 100 + lookups
+	}
+
+	channel := make(chan KeyEvent, 4)
+	err := readKeyboard(bytes.NewReader(b), channel, focused)
+	assert.NoError(t, err, "reading keyboard should not result in error")
+	close(channel)
+
+	var processes []int64
+	for key := range channel {
+		processes = append(processes, key.ProcessID)
+	}
+
+	assert.Equal(t, []int64{101, 102, 101, 102}, processes, "keys going up should keep the process they went down in")
+	assert.Equal(t, int64(2), lookups, "focused process should only be looked up when a key goes down")
+}
+
+func TestLinux_CachedFocus(t *testing.T) {
+	var lookups int
+	focused := cachedFocus(func() int64 {
+		lookups++
+		return 4242
+	}, time.Hour)
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, int64(4242), focused(), "cached process should be returned")
+	}
+
+	assert.Equal(t, 1, lookups, "focused process should be looked up once within its lifetime")
+
+	focused = cachedFocus(func() int64 {
+		lookups++
+		return 4242
+	}, 0)
+	focused()
+	focused()
+	assert.Equal(t, 3, lookups, "focused process should be looked up again after its lifetime")
+}
+
+func TestLinux_ListenFakeKeyboard(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pacerank-input")
+	assert.NoError(t, err, "creating temporary directory should not result in error")
+	defer os.RemoveAll(dir)
+
+	devicesPath, directory := inputDevicesPath, inputDirectory
+	defer func() {
+		inputDevicesPath, inputDirectory = devicesPath, directory
+	}()
+
+	inputDevicesPath = filepath.Join(dir, "devices")
+	inputDirectory = dir
+
+	err = ioutil.WriteFile(inputDevicesPath, []byte(fakeInputDevices), 0600)
+	assert.NoError(t, err, "writing devices should not result in error")
+
+	err = ioutil.WriteFile(filepath.Join(dir, "event3"), writeInputEvents(t,
+		inputEvent{Type: evKey, Code: 35, Value: keyPressed},
+	), 0600)
+	assert.NoError(t, err, "writing fake keyboard should not result in error")
+
+	channel := make(chan KeyEvent)
+	listener := &keyboardListener{devices: make(map[string]bool), channel: channel, focused: func() int64 { return 0 }}
+	assert.NoError(t, listener.scan(), "scanning keyboards should not result in error")
+
+	select {
+	case key := <-channel:
+		assert.Equal(t, KeyCharacter, key.Class, "key press should be read from the fake device")
+	case <-time.After(time.Second * 5):
+		t.Fatal("no key press was read from the fake device")
+	}
+}
+
+func TestLinux_ListenKeyboardPermission(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skipf("permissions are not enforced for root")
+	}
+
+	dir, err := ioutil.TempDir("", "pacerank-input")
+	assert.NoError(t, err, "creating temporary directory should not result in error")
+	defer os.RemoveAll(dir)
+
+	devicesPath, directory := inputDevicesPath, inputDirectory
+	defer func() {
+		inputDevicesPath, inputDirectory = devicesPath, directory
+	}()
+
+	inputDevicesPath = filepath.Join(dir, "devices")
+	inputDirectory = dir
+
+	err = ioutil.WriteFile(inputDevicesPath, []byte(fakeInputDevices), 0600)
+	assert.NoError(t, err, "writing devices should not result in error")
+
+	err = ioutil.WriteFile(filepath.Join(dir, "event3"), nil, 0000)
+	assert.NoError(t, err, "writing fake keyboard should not result in error")
+
+	listener := &keyboardListener{devices: make(map[string]bool), channel: make(chan KeyEvent)}
+	assert.Equal(t, ErrInputPermission, listener.scan(), "unreadable keyboard should give permission error")
+}
@@ -0,0 +1,105 @@
+// +build linux
+
+package system
+
+import (
+	"errors"
+	"github.com/godbus/dbus/v5"
+	"os"
+	"time"
+)
+
+// systemd-logind keeps track of the user sessions, the desktop tells it when the session is idle or locked
+// https://www.freedesktop.org/software/systemd/man/org.freedesktop.login1.html
+const (
+	logindDestination = "org.freedesktop.login1"
+	logindPath        = "/org/freedesktop/login1"
+	logindManager     = "org.freedesktop.login1.Manager"
+	logindSession     = "org.freedesktop.login1.Session"
+)
+
+// Find the logind session this process belongs to
+func logindSessionPath(conn *dbus.Conn) dbus.ObjectPath {
+	manager := conn.Object(logindDestination, logindPath)
+
+	var path dbus.ObjectPath
+	if id := os.Getenv("XDG_SESSION_ID"); id != "" {
+		err := manager.Call(logindManager+".GetSession", 0, id).Store(&path)
+		if err == nil {
+			return path
+		}
+	}
+
+	err := manager.Call(logindManager+".GetSessionByPID", 0, uint32(os.Getpid())).Store(&path)
+	if err == nil {
+		return path
+	}
+
+	// Resolves to the session of the caller, or the display session of the user. Signals are sent from the real path
+	// of the session, so it is looked up by the id when possible.
+	auto := dbus.ObjectPath(logindPath + "/session/auto")
+
+	variant, err := conn.Object(logindDestination, auto).GetProperty(logindSession + ".Id")
+	if id, ok := variant.Value().(string); err == nil && ok {
+		err = manager.Call(logindManager+".GetSession", 0, id).Store(&path)
+		if err == nil {
+			return path
+		}
+	}
+
+	return auto
+}
+
+// Get a property of the current logind session
+func logindSessionProperty(name string) (interface{}, error) {
+	conn, err := dbus.SystemBus()
+	if err != nil {
+		return nil, err
+	}
+
+	variant, err := conn.Object(logindDestination, logindSessionPath(conn)).GetProperty(logindSession + "." + name)
+	if err != nil {
+		return nil, err
+	}
+
+	return variant.Value(), nil
+}
+
+// Get idle time from the IdleHint and IdleSinceHint of the session, the session is not idle until the desktop says so
+func logindIdleTime() (time.Duration, error) {
+	hint, err := logindSessionProperty("IdleHint")
+	if err != nil {
+		return 0, err
+	}
+
+	if idle, ok := hint.(bool); !ok || !idle {
+		return 0, nil
+	}
+
+	since, err := logindSessionProperty("IdleSinceHint")
+	if err != nil {
+		return 0, err
+	}
+
+	usec, ok := since.(uint64)
+	if !ok || usec == 0 {
+		return 0, errors.New("logind session does not have IdleSinceHint")
+	}
+
+	return time.Since(time.Unix(0, int64(usec)*int64(time.Microsecond))), nil
+}
+
+// Get the LockedHint of the session
+func logindLocked() (bool, error) {
+	hint, err := logindSessionProperty("LockedHint")
+	if err != nil {
+		return false, err
+	}
+
+	locked, ok := hint.(bool)
+	if !ok {
+		return false, errors.New("logind session has an invalid LockedHint")
+	}
+
+	return locked, nil
+}
@@ -0,0 +1,131 @@
+// +build linux
+
+package system
+
+import (
+	"context"
+	"github.com/godbus/dbus/v5"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+// Listen to logind for sleep and lock signals, the lock state is polled when the system bus can not be reached
+func (t *target) WatchPower(ctx context.Context) <-chan PowerEvent {
+	channel := make(chan PowerEvent)
+
+	conn, session, err := subscribeLogind()
+	if err != nil {
+		log.Debug().Err(err).Msg("could not subscribe to logind, fall back to polling screen lock")
+		go pollLock(ctx, t.ScreenLocked, channel)
+		return channel
+	}
+
+	go watchLogind(ctx, conn, session, channel)
+	return channel
+}
+
+// Open a private connection to the system bus, so it can be closed when the watch is done, and subscribe to
+// PrepareForSleep from the manager and Lock and Unlock from the session
+func subscribeLogind() (*dbus.Conn, dbus.ObjectPath, error) {
+	conn, err := dbus.SystemBusPrivate()
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = conn.Auth(nil)
+	if err != nil {
+		_ = conn.Close()
+		return nil, "", err
+	}
+
+	err = conn.Hello()
+	if err != nil {
+		_ = conn.Close()
+		return nil, "", err
+	}
+
+	session := logindSessionPath(conn)
+
+	err = conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(logindPath),
+		dbus.WithMatchInterface(logindManager),
+		dbus.WithMatchMember("PrepareForSleep"),
+	)
+	if err != nil {
+		_ = conn.Close()
+		return nil, "", err
+	}
+
+	err = conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(session),
+		dbus.WithMatchInterface(logindSession),
+	)
+	if err != nil {
+		_ = conn.Close()
+		return nil, "", err
+	}
+
+	return conn, session, nil
+}
+
+func watchLogind(ctx context.Context, conn *dbus.Conn, session dbus.ObjectPath, channel chan<- PowerEvent) {
+	defer close(channel)
+	defer conn.Close()
+
+	signals := make(chan *dbus.Signal, 10)
+	conn.Signal(signals)
+
+	for {
+		var signal *dbus.Signal
+		var ok bool
+
+		select {
+		case signal, ok = <-signals:
+			if !ok {
+				log.Error().Msg("connection to logind was closed")
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+
+		event, ok := logindPowerEvent(signal, session, time.Now())
+		if !ok {
+			continue
+		}
+
+		select {
+		case channel <- event:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Turn a logind signal into a power event. PrepareForSleep is sent with true before suspending and with false after
+// resuming, Lock and Unlock are only of interest for the session of this process.
+func logindPowerEvent(signal *dbus.Signal, session dbus.ObjectPath, now time.Time) (PowerEvent, bool) {
+	switch signal.Name {
+	case logindManager + ".PrepareForSleep":
+		if len(signal.Body) == 0 {
+			return PowerEvent{}, false
+		}
+
+		sleeping, ok := signal.Body[0].(bool)
+		if !ok {
+			return PowerEvent{}, false
+		}
+
+		if sleeping {
+			return PowerEvent{Type: PowerSuspend, Time: now}, true
+		}
+
+		return PowerEvent{Type: PowerResume, Time: now}, true
+	case logindSession + ".Lock":
+		return PowerEvent{Type: PowerLock, Time: now}, signal.Path == session
+	case logindSession + ".Unlock":
+		return PowerEvent{Type: PowerUnlock, Time: now}, signal.Path == session
+	}
+
+	return PowerEvent{}, false
+}
@@ -0,0 +1,39 @@
+// +build linux
+
+package system
+
+import (
+	"github.com/godbus/dbus/v5"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLinux_LogindPowerEvent(t *testing.T) {
+	now := time.Now()
+	session := dbus.ObjectPath(logindPath + "/session/_32")
+
+	tests := []struct {
+		name   string
+		signal *dbus.Signal
+		event  PowerEventType
+		ok     bool
+	}{
+		{"suspend", &dbus.Signal{Path: logindPath, Name: logindManager + ".PrepareForSleep", Body: []interface{}{true}}, PowerSuspend, true},
+		{"resume", &dbus.Signal{Path: logindPath, Name: logindManager + ".PrepareForSleep", Body: []interface{}{false}}, PowerResume, true},
+		{"sleep without body", &dbus.Signal{Path: logindPath, Name: logindManager + ".PrepareForSleep"}, 0, false},
+		{"lock", &dbus.Signal{Path: session, Name: logindSession + ".Lock"}, PowerLock, true},
+		{"unlock", &dbus.Signal{Path: session, Name: logindSession + ".Unlock"}, PowerUnlock, true},
+		{"lock of other session", &dbus.Signal{Path: logindPath + "/session/c1", Name: logindSession + ".Lock"}, 0, false},
+		{"unrelated signal", &dbus.Signal{Path: session, Name: "org.freedesktop.DBus.Properties.PropertiesChanged"}, 0, false},
+	}
+
+	for _, test := range tests {
+		event, ok := logindPowerEvent(test.signal, session, now)
+		assert.Equal(t, test.ok, ok, test.name)
+		if ok {
+			assert.Equal(t, test.event, event.Type, test.name)
+			assert.Equal(t, now, event.Time, test.name)
+		}
+	}
+}
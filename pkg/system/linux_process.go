@@ -5,12 +5,15 @@ package system
 import (
 	"bytes"
 	"errors"
-	"fmt"
+	"github.com/rs/zerolog/log"
 	"io"
+	"io/ioutil"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 func (t *target) Processes() ([]*Process, error) {
@@ -50,38 +53,39 @@ func (t *target) Processes() ([]*Process, error) {
 }
 
 func (t *target) ActiveProcess() (*Process, error) {
-	if !activeWindowSupport() {
-		return nil, errors.New("your window manager does not support _NET_ACTIVE_WINDOW")
+	window, err := t.activeWindow()
+	if err != nil {
+		return nil, err
 	}
 
-	return &Process{}, nil
-}
-
-// Function looks if the current xorg supports _NET_ACTIVE_WINDOW
-func activeWindowSupport() bool {
-	cmd := exec.Command("xprop", "-root", "_NET_SUPPORTED")
-	var output bytes.Buffer
-	cmd.Stdout = &output
-	err := cmd.Run()
+	process, err := getProcess(window.ProcessID)
 	if err != nil {
-		return false
+		return nil, err
 	}
 
-	str := strings.ReplaceAll(strings.Trim(output.String(), "\n"), " ", "")
-	str = str[strings.LastIndex(str, "=")+1:]
-	strArr := strings.Split(str, ",")
-	for _, supported := range strArr {
-		if supported == "_NET_ACTIVE_WINDOW" {
-			return true
+	// In a terminal emulator the keystrokes go to whatever runs in the foreground of its terminal
+	if isTerminalEmulator(process) {
+		foreground, err := foregroundProcess(process)
+		if err != nil {
+			log.Debug().Err(err).Str("terminal", process.FileName).Msg("could not find foreground process of terminal")
+			return process, nil
 		}
+
+		return foreground, nil
 	}
 
-	return false
+	return process, nil
 }
 
-// Create process struct for given processID
+// Create process struct for given processID. Everything but the children is cached for as long as the process lives,
+// the stat file tells if the process ID has been reused and the executable tells if the process has called exec.
 func getProcess(processID int64) (*Process, error) {
-	path, err := os.Readlink(fmt.Sprintf("/proc/%d/exe", processID))
+	stat, err := readProcessStat(processID)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := os.Readlink(filepath.Join(procDirectory, strconv.FormatInt(processID, 10), "exe"))
 	if os.IsPermission(err) {
 		return nil, errors.New("no permission to read pid")
 	}
@@ -90,21 +94,205 @@ func getProcess(processID int64) (*Process, error) {
 		return nil, err
 	}
 
-	cs, err := checksum(path)
+	processes.prune(processAlive)
+
+	cached, err := processes.lookup(processID, stat.StartTime, path, func() (*Process, error) {
+		return loadProcess(processID, stat, path)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	children, err := processChildren(processID)
+	if err != nil {
+		return nil, err
+	}
+
+	process := *cached
+	process.Children = children
+	return &process, nil
+}
+
+func loadProcess(processID int64, stat processStat, path string) (*Process, error) {
+	commandLine, err := processCommandLine(processID)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Process{
-		ProcessID:  processID,
-		Parent:     0,
-		Children:   nil,
-		FileName:   getExecutableName(path),
-		Checksum:   cs,
-		Executable: path,
+		ProcessID:   processID,
+		Parent:      stat.Parent,
+		FileName:    getExecutableName(path),
+		Executable:  path,
+		CommandLine: commandLine,
+		StartTime:   stat.StartTime,
 	}, nil
 }
 
+// Check if a process with the given start time is still running
+func processAlive(processID int64, startTime time.Time) bool {
+	stat, err := readProcessStat(processID)
+	if err != nil {
+		return false
+	}
+
+	return stat.StartTime.Equal(startTime)
+}
+
+func (t *target) ProcessTree(processID int64) (*ProcessNode, error) {
+	return processTree(processID, getProcess, make(map[int64]bool))
+}
+
+// Location of the proc file system, it is a variable so it can be pointed somewhere else
+var procDirectory = "/proc"
+
+// The kernel reports times in /proc in clock ticks, USER_HZ is 100 on every architecture Linux runs on
+const clockTicks = 100
+
+type processStat struct {
+	ProcessID       int64
+	Parent          int64
+	Terminal        int64
+	ForegroundGroup int64
+	StartTime       time.Time
+}
+
+// Read /proc/<pid>/stat
+// https://man7.org/linux/man-pages/man5/proc.5.html
+func readProcessStat(processID int64) (processStat, error) {
+	b, err := ioutil.ReadFile(filepath.Join(procDirectory, strconv.FormatInt(processID, 10), "stat"))
+	if err != nil {
+		return processStat{}, err
+	}
+
+	boot, err := bootTime()
+	if err != nil {
+		return processStat{}, err
+	}
+
+	return parseProcessStat(b, boot)
+}
+
+func parseProcessStat(b []byte, boot time.Time) (processStat, error) {
+	var result processStat
+
+	// The command name is in parentheses and can contain both spaces and parentheses, so fields are read from the
+	// last closing parenthesis
+	end := bytes.LastIndexByte(b, ')')
+	if end < 0 {
+		return result, errors.New("invalid process stat format")
+	}
+
+	// Fields starting from the state, which is field 3 in proc(5)
+	fields := strings.Fields(string(b[end+1:]))
+	if len(fields) < 20 {
+		return result, errors.New("process stat has too few fields")
+	}
+
+	var err error
+	result.ProcessID, err = strconv.ParseInt(strings.TrimSpace(string(b[:bytes.IndexByte(b, ' ')+1])), 10, 64)
+	if err != nil {
+		return result, err
+	}
+
+	result.Parent, err = strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return result, err
+	}
+
+	result.Terminal, err = strconv.ParseInt(fields[4], 10, 64)
+	if err != nil {
+		return result, err
+	}
+
+	result.ForegroundGroup, err = strconv.ParseInt(fields[5], 10, 64)
+	if err != nil {
+		return result, err
+	}
+
+	ticks, err := strconv.ParseInt(fields[19], 10, 64)
+	if err != nil {
+		return result, err
+	}
+
+	result.StartTime = boot.Add(time.Duration(ticks) * time.Second / clockTicks)
+	return result, nil
+}
+
+var (
+	boot     time.Time
+	bootErr  error
+	bootOnce sync.Once
+)
+
+// Get the time the system booted from btime in /proc/stat
+func bootTime() (time.Time, error) {
+	bootOnce.Do(func() {
+		var b []byte
+		b, bootErr = ioutil.ReadFile(filepath.Join(procDirectory, "stat"))
+		if bootErr != nil {
+			return
+		}
+
+		for _, line := range strings.Split(string(b), "\n") {
+			if !strings.HasPrefix(line, "btime ") {
+				continue
+			}
+
+			var seconds int64
+			seconds, bootErr = strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(line, "btime ")), 10, 64)
+			boot = time.Unix(seconds, 0)
+			return
+		}
+
+		bootErr = errors.New("boot time could not be found in stat")
+	})
+
+	return boot, bootErr
+}
+
+// Get the children of a process from /proc/<pid>/task/<tid>/children, every thread has its own list
+func processChildren(processID int64) ([]int64, error) {
+	tasks, err := filepath.Glob(filepath.Join(procDirectory, strconv.FormatInt(processID, 10), "task", "*", "children"))
+	if err != nil {
+		return nil, err
+	}
+
+	var children []int64
+	for _, task := range tasks {
+		b, err := ioutil.ReadFile(task)
+		if err != nil {
+			continue
+		}
+
+		for _, field := range strings.Fields(string(b)) {
+			child, err := strconv.ParseInt(field, 10, 64)
+			if err != nil {
+				continue
+			}
+
+			children = append(children, child)
+		}
+	}
+
+	return children, nil
+}
+
+// Get the arguments of a process, they are separated by null bytes in /proc/<pid>/cmdline
+func processCommandLine(processID int64) ([]string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(procDirectory, strconv.FormatInt(processID, 10), "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+
+	b = bytes.TrimRight(b, "\x00")
+	if len(b) == 0 {
+		return nil, nil
+	}
+
+	return strings.Split(string(b), "\x00"), nil
+}
+
 // Get name of executable from path
 func getExecutableName(path string) string {
 	return path[strings.LastIndex(path, "/")+1:]
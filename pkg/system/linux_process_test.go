@@ -0,0 +1,62 @@
+// +build linux
+
+package system
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLinux_ParseProcessStat(t *testing.T) {
+	boot := time.Unix(1600000000, 0)
+	b := []byte("4242 (tmux: server (1)) S 1 4242 4242 0 -1 4194368 1175 0 0 0 17 5 0 0 20 0 1 0 12345 12730368 1091 " +
+		"18446744073709551615 1 1 0 0 0 0 0 3674116 1266777851 0 0 0 17 3 0 0 0 0 0\n")
+
+	stat, err := parseProcessStat(b, boot)
+	assert.NoError(t, err, "parsing stat should not result in error")
+	assert.Equal(t, int64(4242), stat.ProcessID, "process id should be read before the command name")
+	assert.Equal(t, int64(1), stat.Parent, "parent should be read after the command name")
+	assert.Equal(t, int64(-1), stat.ForegroundGroup, "process without terminal has no foreground group")
+	assert.Equal(t, boot.Add(time.Millisecond*123450), stat.StartTime, "start time should be relative to boot")
+}
+
+func TestLinux_ForegroundGroup(t *testing.T) {
+	now := time.Now()
+	used := map[int64]time.Time{
+		34816: now.Add(-time.Minute),
+		34817: now,
+	}
+
+	stats := []processStat{
+		{ProcessID: 10, Terminal: 34816, ForegroundGroup: 12},
+		{ProcessID: 12, Terminal: 34816, ForegroundGroup: 12},
+		{ProcessID: 20, Terminal: 34817, ForegroundGroup: 25},
+		{ProcessID: 30, Terminal: 0, ForegroundGroup: -1},
+	}
+
+	group, ok := foregroundGroup(stats, func(terminal int64) time.Time {
+		return used[terminal]
+	})
+
+	assert.True(t, ok, "a foreground group should be found")
+	assert.Equal(t, int64(25), group, "foreground group of the most recently used terminal should win")
+
+	_, ok = foregroundGroup(stats[3:], func(terminal int64) time.Time {
+		return now
+	})
+	assert.False(t, ok, "processes without terminal should not have a foreground group")
+}
+
+func TestLinux_TerminalDevice(t *testing.T) {
+	path, err := terminalDevice(34817)
+	assert.NoError(t, err, "pseudo terminal should be resolved")
+	assert.Equal(t, "/dev/pts/1", path, "minor number should be the pts number")
+
+	path, err = terminalDevice(137<<8 | 4)
+	assert.NoError(t, err, "pseudo terminal should be resolved")
+	assert.Equal(t, "/dev/pts/260", path, "every major number holds 256 terminals")
+
+	_, err = terminalDevice(4<<8 | 1)
+	assert.Error(t, err, "virtual console is not a pseudo terminal")
+}
@@ -0,0 +1,125 @@
+// +build linux
+
+package system
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// i3 IPC message types, sway speaks the same protocol
+// https://i3wm.org/docs/ipc.html
+const (
+	i3IpcGetTree = 4
+)
+
+var i3IpcMagic = []byte("i3-ipc")
+
+// swayProvider asks sway over the IPC socket for the layout tree and finds the focused node
+type swayProvider struct {
+	socket string
+}
+
+type swayNode struct {
+	Focused       bool       `json:"focused"`
+	Pid           int64      `json:"pid"`
+	Name          string     `json:"name"`
+	Nodes         []swayNode `json:"nodes"`
+	FloatingNodes []swayNode `json:"floating_nodes"`
+}
+
+func (p *swayProvider) Name() string {
+	return "sway"
+}
+
+func (p *swayProvider) ActiveWindow() (*Window, error) {
+	b, err := i3IpcRequest(p.socket, i3IpcGetTree, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree swayNode
+	err = json.Unmarshal(b, &tree)
+	if err != nil {
+		return nil, err
+	}
+
+	node := tree.focused()
+	if node == nil {
+		return nil, errors.New("no window is currently focused in sway")
+	}
+
+	if node.Pid == 0 {
+		return nil, errors.New("focused sway node does not belong to a process")
+	}
+
+	return &Window{ProcessID: node.Pid, Title: node.Name}, nil
+}
+
+func (p *swayProvider) Close() error {
+	return nil
+}
+
+// Find the focused node by walking the tree
+func (n *swayNode) focused() *swayNode {
+	if n.Focused {
+		return n
+	}
+
+	for _, children := range [][]swayNode{n.Nodes, n.FloatingNodes} {
+		for i := range children {
+			if node := children[i].focused(); node != nil {
+				return node
+			}
+		}
+	}
+
+	return nil
+}
+
+// Send a message on the IPC socket and read the reply payload
+func i3IpcRequest(socket string, messageType uint32, payload []byte) ([]byte, error) {
+	conn, err := net.DialTimeout("unix", socket, time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(time.Second * 2))
+
+	request := make([]byte, len(i3IpcMagic)+8, len(i3IpcMagic)+8+len(payload))
+	copy(request, i3IpcMagic)
+	binary.LittleEndian.PutUint32(request[len(i3IpcMagic):], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(request[len(i3IpcMagic)+4:], messageType)
+	request = append(request, payload...)
+
+	if _, err = conn.Write(request); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, len(i3IpcMagic)+8)
+	if _, err = io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+
+	if string(header[:len(i3IpcMagic)]) != string(i3IpcMagic) {
+		return nil, errors.New("invalid reply from sway ipc socket")
+	}
+
+	if reply := binary.LittleEndian.Uint32(header[len(i3IpcMagic)+4:]); reply != messageType {
+		return nil, errors.New(fmt.Sprintf("expected reply of type %d from sway, got %d", messageType, reply))
+	}
+
+	b := make([]byte, binary.LittleEndian.Uint32(header[len(i3IpcMagic):]))
+	if _, err = io.ReadFull(conn, b); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
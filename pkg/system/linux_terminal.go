@@ -0,0 +1,122 @@
+// +build linux
+
+package system
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Executable names of terminal emulators, the editor is found among their descendants
+var terminalEmulators = map[string]bool{
+	"gnome-terminal-server": true,
+	"kgx":                   true,
+	"kitty":                 true,
+	"alacritty":             true,
+	"wezterm-gui":           true,
+	"konsole":               true,
+	"xfce4-terminal":        true,
+	"tilix":                 true,
+	"terminator":            true,
+	"foot":                  true,
+	"xterm":                 true,
+	"urxvt":                 true,
+	"st":                    true,
+}
+
+func isTerminalEmulator(process *Process) bool {
+	return terminalEmulators[process.FileName]
+}
+
+// Find the process in the foreground of the terminal that the user typed in last. A terminal emulator can have many
+// tabs and windows, each with its own pseudo terminal, so the terminal with the most recent input wins.
+func foregroundProcess(terminal *Process) (*Process, error) {
+	tree, err := processTree(terminal.ProcessID, getProcess, make(map[int64]bool))
+	if err != nil {
+		return nil, err
+	}
+
+	var stats []processStat
+	var walk func(node *ProcessNode)
+	walk = func(node *ProcessNode) {
+		for _, child := range node.Children {
+			stat, err := readProcessStat(child.Process.ProcessID)
+			if err == nil {
+				stats = append(stats, stat)
+			}
+
+			walk(child)
+		}
+	}
+
+	walk(tree)
+
+	group, ok := foregroundGroup(stats, terminalLastUsed)
+	if !ok {
+		return nil, errors.New("terminal does not have any process in the foreground")
+	}
+
+	return getProcess(group)
+}
+
+// Pick the foreground process group of the most recently used terminal among the given processes
+func foregroundGroup(stats []processStat, lastUsed func(terminal int64) time.Time) (int64, bool) {
+	var (
+		group  int64
+		latest time.Time
+		found  bool
+	)
+
+	seen := make(map[int64]bool)
+	for _, stat := range stats {
+		if stat.Terminal == 0 || stat.ForegroundGroup <= 0 || seen[stat.Terminal] {
+			continue
+		}
+
+		seen[stat.Terminal] = true
+
+		used := lastUsed(stat.Terminal)
+		if !found || used.After(latest) {
+			group, latest, found = stat.ForegroundGroup, used, true
+		}
+	}
+
+	return group, found
+}
+
+// Get the last time a terminal device was read from or written to, which is what w(1) uses to show idle time
+func terminalLastUsed(terminal int64) time.Time {
+	path, err := terminalDevice(terminal)
+	if err != nil {
+		return time.Time{}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.ModTime()
+	}
+
+	return time.Unix(stat.Atim.Sec, stat.Atim.Nsec)
+}
+
+// Get the device path from a tty_nr in /proc/<pid>/stat, only pseudo terminals are supported as that is what terminal
+// emulators use
+func terminalDevice(terminal int64) (string, error) {
+	major := (terminal >> 8) & 0xfff
+	minor := (terminal & 0xff) | ((terminal >> 12) & 0xfff00)
+
+	// Unix98 pseudo terminal slaves use major 136 to 143
+	if major < 136 || major > 143 {
+		return "", errors.New(fmt.Sprintf("terminal %d:%d is not a pseudo terminal", major, minor))
+	}
+
+	return fmt.Sprintf("/dev/pts/%d", (major-136)*256+minor), nil
+}
@@ -1,10 +1,14 @@
 package system_test
 
 import (
+	"context"
 	"github.com/pacerank/client/pkg/system"
 	"github.com/stretchr/testify/assert"
+	"os"
+	"os/exec"
 	"runtime"
 	"testing"
+	"time"
 )
 
 func TestLinux_Processes(t *testing.T) {
@@ -17,3 +21,63 @@ func TestLinux_Processes(t *testing.T) {
 	_, err := sys.Processes()
 	assert.NoError(t, err, "finding processes should not result in error")
 }
+
+func TestLinux_ProcessTree(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skipf("current operating system is not target")
+	}
+
+	sys := system.New()
+
+	tree, err := sys.ProcessTree(int64(os.Getppid()))
+	assert.NoError(t, err, "getting process tree should not result in error")
+
+	var current *system.Process
+	for _, child := range tree.Children {
+		if child.Process.ProcessID == int64(os.Getpid()) {
+			current = child.Process
+		}
+	}
+
+	if assert.NotNil(t, current, "current process should be a child of its parent") {
+		assert.Equal(t, int64(os.Getppid()), current.Parent, "parent should be set")
+		assert.NotEmpty(t, current.CommandLine, "command line should be set")
+		assert.True(t, current.StartTime.Before(time.Now()), "start time should be in the past")
+	}
+}
+
+func TestLinux_WatchProcesses(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skipf("current operating system is not target")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := system.New().WatchProcesses(ctx)
+
+	// Give the polling fallback time to take its first snapshot
+	time.Sleep(time.Second)
+
+	cmd := exec.Command("sleep", "3")
+	assert.NoError(t, cmd.Start(), "starting process should not result in error")
+
+	pid := int64(cmd.Process.Pid)
+	_ = cmd.Wait()
+
+	var started, exited bool
+	timeout := time.After(time.Second * 10)
+	for !started || !exited {
+		select {
+		case event := <-events:
+			if event.ProcessID != pid {
+				continue
+			}
+
+			started = started || event.Type == system.ProcessStarted
+			exited = exited || event.Type == system.ProcessExited
+		case <-timeout:
+			t.Fatalf("did not receive start and exit of process, started: %t, exited: %t", started, exited)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// +build linux
+
+package system
+
+import (
+	"errors"
+	"github.com/rs/zerolog/log"
+	"os"
+	"strings"
+)
+
+// windowProvider finds the focused window and the process that owns it. Every display server and compositor has its own way
+// of telling, so the providers are tried in order until one of them gives an answer.
+type windowProvider interface {
+	Name() string
+	ActiveWindow() (*Window, error)
+	Close() error
+}
+
+var errNoWindowProvider = errors.New("no supported display server found, set DISPLAY for X11 or run a supported " +
+	"Wayland compositor (Sway, Hyprland or GNOME Shell)")
+
+// Pick the providers that are relevant for the current session from the environment. Compositor specific providers
+// come first, X11 is last since it only sees XWayland windows when running under Wayland.
+func windowProviders(env func(string) string) []windowProvider {
+	var providers []windowProvider
+
+	if socket := env("SWAYSOCK"); socket != "" {
+		providers = append(providers, &swayProvider{socket: socket})
+	}
+
+	if signature := env("HYPRLAND_INSTANCE_SIGNATURE"); signature != "" {
+		providers = append(providers, &hyprlandProvider{socket: hyprlandSocket(env("XDG_RUNTIME_DIR"), signature)})
+	}
+
+	if env("WAYLAND_DISPLAY") != "" && strings.Contains(strings.ToUpper(env("XDG_CURRENT_DESKTOP")), "GNOME") {
+		providers = append(providers, &gnomeProvider{})
+	}
+
+	if display := env("DISPLAY"); display != "" {
+		providers = append(providers, &x11Provider{display: display})
+	}
+
+	return providers
+}
+
+func (t *target) ActiveWindow() (*Window, error) {
+	return t.activeWindow()
+}
+
+// Ask the providers in order for the focused window
+func (t *target) activeWindow() (*Window, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.windows == nil {
+		t.windows = windowProviders(os.Getenv)
+	}
+
+	if len(t.windows) == 0 {
+		return nil, errNoWindowProvider
+	}
+
+	var err error
+	for _, provider := range t.windows {
+		var window *Window
+		window, err = provider.ActiveWindow()
+		if err == nil {
+			return window, nil
+		}
+
+		log.Debug().Err(err).Str("provider", provider.Name()).Msg("could not get active window")
+	}
+
+	return nil, err
+}
+
+// x11Provider uses _NET_ACTIVE_WINDOW, _NET_WM_PID and _NET_WM_NAME. The connection is kept open between calls and is established
+// again if it fails.
+type x11Provider struct {
+	display string
+	x11     *x11
+}
+
+func (p *x11Provider) Name() string {
+	return "x11"
+}
+
+func (p *x11Provider) ActiveWindow() (*Window, error) {
+	if p.x11 == nil {
+		x, err := dialX11(p.display)
+		if err != nil {
+			return nil, err
+		}
+
+		p.x11 = x
+	}
+
+	window, err := p.x11.activeWindow()
+	if err == nil {
+		var pid int64
+		pid, err = p.x11.windowPID(window)
+		if err == nil {
+			return &Window{ProcessID: pid, Title: p.x11.windowTitle(window)}, nil
+		}
+	}
+
+	// Only protocol errors leaves the connection usable
+	if _, ok := err.(x11Error); !ok {
+		_ = p.Close()
+	}
+
+	return nil, err
+}
+
+func (p *x11Provider) Close() error {
+	if p.x11 == nil {
+		return nil
+	}
+
+	err := p.x11.Close()
+	p.x11 = nil
+	return err
+}
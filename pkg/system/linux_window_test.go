@@ -0,0 +1,165 @@
+// +build linux
+
+package system
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Listen on a unix socket in a temporary directory and handle every connection with serve
+func fakeSocketServer(t *testing.T, serve func(conn net.Conn)) (string, func()) {
+	dir, err := ioutil.TempDir("", "pacerank-socket")
+	assert.NoError(t, err, "creating temporary directory should not result in error")
+
+	socket := filepath.Join(dir, "socket")
+	listener, err := net.Listen("unix", socket)
+	assert.NoError(t, err, "listening on socket should not result in error")
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				serve(conn)
+			}()
+		}
+	}()
+
+	return socket, func() {
+		_ = listener.Close()
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestLinux_WindowProviders(t *testing.T) {
+	env := func(values map[string]string) func(string) string {
+		return func(key string) string {
+			return values[key]
+		}
+	}
+
+	names := func(providers []windowProvider) []string {
+		var result []string
+		for _, provider := range providers {
+			result = append(result, provider.Name())
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"x11"}, names(windowProviders(env(map[string]string{
+		"DISPLAY": ":0",
+	}))), "plain X11 session should only use x11")
+
+	assert.Equal(t, []string{"sway", "x11"}, names(windowProviders(env(map[string]string{
+		"DISPLAY":         ":0",
+		"WAYLAND_DISPLAY": "wayland-1",
+		"SWAYSOCK":        "/run/user/1000/sway-ipc.sock",
+	}))), "sway should be tried before XWayland")
+
+	assert.Equal(t, []string{"hyprland"}, names(windowProviders(env(map[string]string{
+		"WAYLAND_DISPLAY":             "wayland-1",
+		"HYPRLAND_INSTANCE_SIGNATURE": "abc",
+	}))), "hyprland should be picked from its signature")
+
+	assert.Equal(t, []string{"gnome", "x11"}, names(windowProviders(env(map[string]string{
+		"DISPLAY":             ":0",
+		"WAYLAND_DISPLAY":     "wayland-0",
+		"XDG_CURRENT_DESKTOP": "ubuntu:GNOME",
+	}))), "gnome should be picked on wayland")
+
+	assert.Empty(t, windowProviders(env(map[string]string{})), "no display means no providers")
+}
+
+func TestLinux_SwayProvider(t *testing.T) {
+	tree := []byte(`{"focused":false,"nodes":[{"focused":false,"nodes":[
+		{"focused":false,"pid":11,"name":"firefox"},
+		{"focused":false,"nodes":[],"floating_nodes":[{"focused":true,"pid":42,"name":"main.go - client - Visual Studio Code"}]}
+	]}]}`)
+
+	socket, closer := fakeSocketServer(t, func(conn net.Conn) {
+		header := make([]byte, 14)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+
+		reply := append([]byte("i3-ipc"), make([]byte, 8)...)
+		binary.LittleEndian.PutUint32(reply[6:], uint32(len(tree)))
+		binary.LittleEndian.PutUint32(reply[10:], binary.LittleEndian.Uint32(header[10:]))
+		_, _ = conn.Write(append(reply, tree...))
+	})
+	defer closer()
+
+	provider := &swayProvider{socket: socket}
+	window, err := provider.ActiveWindow()
+	assert.NoError(t, err, "getting active window from sway should not result in error")
+	assert.Equal(t, int64(42), window.ProcessID, "pid of the focused floating node should be found")
+	assert.Equal(t, "main.go - client - Visual Studio Code", window.Title, "title of the focused floating node should be found")
+}
+
+func TestLinux_HyprlandProvider(t *testing.T) {
+	socket, closer := fakeSocketServer(t, func(conn net.Conn) {
+		request := make([]byte, len("j/activewindow"))
+		if _, err := io.ReadFull(conn, request); err != nil || string(request) != "j/activewindow" {
+			return
+		}
+
+		_, _ = conn.Write([]byte(`{"address":"0x55d2","pid":1337,"class":"kitty","title":"nvim"}`))
+	})
+	defer closer()
+
+	provider := &hyprlandProvider{socket: socket}
+	window, err := provider.ActiveWindow()
+	assert.NoError(t, err, "getting active window from hyprland should not result in error")
+	assert.Equal(t, int64(1337), window.ProcessID, "pid of the active window should be found")
+	assert.Equal(t, "nvim", window.Title, "title of the active window should be found")
+}
+
+func TestLinux_GnomeProvider(t *testing.T) {
+	provider := &gnomeProvider{eval: func(script string) (bool, string, error) {
+		return true, `{"pid":2048,"title":"client – watcher.go [client] – IntelliJ IDEA"}`, nil
+	}}
+
+	window, err := provider.ActiveWindow()
+	assert.NoError(t, err, "getting active window from gnome should not result in error")
+	assert.Equal(t, int64(2048), window.ProcessID, "pid of the focused window should be found")
+	assert.Equal(t, "client – watcher.go [client] – IntelliJ IDEA", window.Title, "title of the focused window should be found")
+
+	provider = &gnomeProvider{eval: func(script string) (bool, string, error) {
+		return true, "null", nil
+	}}
+
+	_, err = provider.ActiveWindow()
+	assert.Error(t, err, "no focused window should result in error")
+
+	provider = &gnomeProvider{eval: func(script string) (bool, string, error) {
+		return false, "SyntaxError: unexpected token", nil
+	}}
+
+	_, err = provider.ActiveWindow()
+	assert.Error(t, err, "failing script should result in error")
+	assert.False(t, provider.unsupported, "failing script should not disable the provider")
+
+	calls := 0
+	provider = &gnomeProvider{eval: func(script string) (bool, string, error) {
+		calls++
+		return false, "", nil
+	}}
+
+	_, err = provider.ActiveWindow()
+	assert.Equal(t, errGnomeEvalUnsupported, err, "disabled eval should be reported as unsupported")
+
+	_, err = provider.ActiveWindow()
+	assert.Equal(t, errGnomeEvalUnsupported, err, "disabled eval should still be reported as unsupported")
+	assert.Equal(t, 1, calls, "gnome shell should not be asked again once eval is known to be disabled")
+}
@@ -0,0 +1,462 @@
+// +build linux
+
+package system
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// This is a minimal X11 client that speaks the core protocol directly over the display socket, it only implements the
+// requests that are needed to find out which window has focus and which process it belongs to.
+// https://www.x.org/releases/X11R7.7/doc/xproto/x11protocol.html
+
+// Core protocol request opcodes
+const (
+	x11OpInternAtom     = 16
+	x11OpGetProperty    = 20
+	x11OpQueryExtension = 98
+)
+
+// Every request has to be answered within this time, the connection is useless after a timeout and is dialed again
+const x11Timeout = time.Second * 2
+
+// MIT-SCREEN-SAVER extension minor opcodes
+const (
+	x11ScreenSaverQueryInfo = 1
+)
+
+// Predefined atoms
+const (
+	x11AtomCardinal = 6
+	x11AtomString   = 31
+	x11AtomWindow   = 33
+)
+
+var errX11NoProperty = errors.New("window does not have the requested property")
+
+type x11Error struct {
+	Code     byte
+	Sequence uint16
+	Opcode   byte
+}
+
+func (e x11Error) Error() string {
+	return fmt.Sprintf("x11 request %d failed with error code %d", e.Opcode, e.Code)
+}
+
+type x11 struct {
+	conn       net.Conn
+	timeout    time.Duration
+	root       uint32
+	sequence   uint16
+	atoms      map[string]uint32
+	extensions map[string]byte
+}
+
+// Connect to the X server given in the DISPLAY format, e.g. ":0", "unix:1.0" or "localhost:10.0"
+func dialX11(display string) (*x11, error) {
+	if display == "" {
+		return nil, errors.New("DISPLAY is not set, no X server to connect to")
+	}
+
+	colon := strings.LastIndex(display, ":")
+	if colon < 0 {
+		return nil, errors.New(fmt.Sprintf("invalid display %q", display))
+	}
+
+	host := display[:colon]
+	number := display[colon+1:]
+	if dot := strings.Index(number, "."); dot >= 0 {
+		number = number[:dot]
+	}
+
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("invalid display number in %q", display))
+	}
+
+	var conn net.Conn
+	if host == "" || host == "unix" {
+		conn, err = net.DialTimeout("unix", fmt.Sprintf("/tmp/.X11-unix/X%d", n), time.Second)
+	} else {
+		conn, err = net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(6000+n)), time.Second)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	name, data := x11Authority(number)
+
+	x, err := newX11(conn, name, data)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return x, nil
+}
+
+// Do the connection setup on an established connection and read the root window of the first screen
+func newX11(conn net.Conn, authName string, authData []byte) (*x11, error) {
+	if err := conn.SetDeadline(time.Now().Add(x11Timeout)); err != nil {
+		return nil, err
+	}
+
+	request := make([]byte, 12)
+	request[0] = 'l'
+	binary.LittleEndian.PutUint16(request[2:], 11)
+	binary.LittleEndian.PutUint16(request[4:], 0)
+	binary.LittleEndian.PutUint16(request[6:], uint16(len(authName)))
+	binary.LittleEndian.PutUint16(request[8:], uint16(len(authData)))
+	request = append(request, x11Pad([]byte(authName))...)
+	request = append(request, x11Pad(authData)...)
+
+	if _, err := conn.Write(request); err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return nil, err
+	}
+
+	body := make([]byte, int(binary.LittleEndian.Uint16(header[6:]))*4)
+	if _, err := io.ReadFull(conn, body); err != nil {
+		return nil, err
+	}
+
+	if header[0] != 1 {
+		reason := body
+		if int(header[1]) <= len(reason) {
+			reason = reason[:header[1]]
+		}
+
+		return nil, errors.New(fmt.Sprintf("x11 connection refused: %s", strings.TrimSpace(string(reason))))
+	}
+
+	if len(body) < 32 {
+		return nil, errors.New("x11 connection setup reply is too short")
+	}
+
+	vendorLength := int(binary.LittleEndian.Uint16(body[16:]))
+	formats := int(body[21])
+
+	screen := 32 + len(x11Pad(make([]byte, vendorLength))) + formats*8
+	if len(body) < screen+4 {
+		return nil, errors.New("x11 server did not announce any screen")
+	}
+
+	return &x11{
+		conn:       conn,
+		timeout:    x11Timeout,
+		root:       binary.LittleEndian.Uint32(body[screen:]),
+		atoms:      make(map[string]uint32),
+		extensions: make(map[string]byte),
+	}, nil
+}
+
+func (x *x11) Close() error {
+	return x.conn.Close()
+}
+
+// Send a request and wait for the matching reply, events that arrive in between are discarded
+func (x *x11) request(opcode byte, data byte, body []byte) ([]byte, error) {
+	request := make([]byte, 4, 4+len(body))
+	request[0] = opcode
+	request[1] = data
+	binary.LittleEndian.PutUint16(request[2:], uint16((4+len(body))/4))
+	request = append(request, body...)
+
+	if err := x.conn.SetDeadline(time.Now().Add(x.timeout)); err != nil {
+		return nil, err
+	}
+
+	if _, err := x.conn.Write(request); err != nil {
+		return nil, err
+	}
+
+	x.sequence++
+
+	for {
+		reply := make([]byte, 32)
+		if _, err := io.ReadFull(x.conn, reply); err != nil {
+			return nil, err
+		}
+
+		sequence := binary.LittleEndian.Uint16(reply[2:])
+
+		switch reply[0] {
+		case 0:
+			if sequence == x.sequence {
+				return nil, x11Error{Code: reply[1], Sequence: sequence, Opcode: reply[10]}
+			}
+		case 1:
+			extra := make([]byte, int(binary.LittleEndian.Uint32(reply[4:]))*4)
+			if _, err := io.ReadFull(x.conn, extra); err != nil {
+				return nil, err
+			}
+
+			if sequence == x.sequence {
+				return append(reply, extra...), nil
+			}
+		}
+	}
+}
+
+// Get the atom for a name, atoms are cached for the lifetime of the connection
+func (x *x11) internAtom(name string) (uint32, error) {
+	if atom, ok := x.atoms[name]; ok {
+		return atom, nil
+	}
+
+	body := make([]byte, 4)
+	binary.LittleEndian.PutUint16(body, uint16(len(name)))
+	body = append(body, x11Pad([]byte(name))...)
+
+	reply, err := x.request(x11OpInternAtom, 0, body)
+	if err != nil {
+		return 0, err
+	}
+
+	atom := binary.LittleEndian.Uint32(reply[8:])
+	x.atoms[name] = atom
+	return atom, nil
+}
+
+// Read the value of a window property, returns errX11NoProperty if the window does not have it
+func (x *x11) property(window uint32, name string, typ uint32) ([]byte, error) {
+	atom, err := x.internAtom(name)
+	if err != nil {
+		return nil, err
+	}
+
+	body := make([]byte, 20)
+	binary.LittleEndian.PutUint32(body[0:], window)
+	binary.LittleEndian.PutUint32(body[4:], atom)
+	binary.LittleEndian.PutUint32(body[8:], typ)
+	binary.LittleEndian.PutUint32(body[12:], 0)
+	binary.LittleEndian.PutUint32(body[16:], 1024)
+
+	reply, err := x.request(x11OpGetProperty, 0, body)
+	if err != nil {
+		return nil, err
+	}
+
+	format := int(reply[1])
+	if binary.LittleEndian.Uint32(reply[8:]) == 0 || format == 0 {
+		return nil, errX11NoProperty
+	}
+
+	length := int(binary.LittleEndian.Uint32(reply[16:])) * format / 8
+	if 32+length > len(reply) {
+		return nil, errors.New("x11 property reply is too short")
+	}
+
+	return reply[32 : 32+length], nil
+}
+
+// Get the window that currently has focus according to the window manager
+func (x *x11) activeWindow() (uint32, error) {
+	value, err := x.property(x.root, "_NET_ACTIVE_WINDOW", x11AtomWindow)
+	if err == errX11NoProperty {
+		return 0, errors.New("your window manager does not support _NET_ACTIVE_WINDOW")
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if len(value) < 4 || binary.LittleEndian.Uint32(value) == 0 {
+		return 0, errors.New("no window is currently active")
+	}
+
+	return binary.LittleEndian.Uint32(value), nil
+}
+
+// Get the process ID that owns a window
+func (x *x11) windowPID(window uint32) (int64, error) {
+	value, err := x.property(window, "_NET_WM_PID", x11AtomCardinal)
+	if err == errX11NoProperty {
+		return 0, errors.New("active window does not have _NET_WM_PID set")
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if len(value) < 4 {
+		return 0, errors.New("invalid _NET_WM_PID value")
+	}
+
+	return int64(binary.LittleEndian.Uint32(value)), nil
+}
+
+// Get the title of a window, _NET_WM_NAME is UTF-8 while the older WM_NAME is Latin-1. An empty title is returned when
+// the window has neither.
+func (x *x11) windowTitle(window uint32) string {
+	utf8, err := x.internAtom("UTF8_STRING")
+	if err == nil {
+		value, err := x.property(window, "_NET_WM_NAME", utf8)
+		if err == nil {
+			return string(value)
+		}
+	}
+
+	value, err := x.property(window, "WM_NAME", x11AtomString)
+	if err != nil {
+		return ""
+	}
+
+	runes := make([]rune, len(value))
+	for i, b := range value {
+		runes[i] = rune(b)
+	}
+
+	return string(runes)
+}
+
+// Find the MIT-MAGIC-COOKIE-1 for the display in the Xauthority file. An empty name is returned if there is no cookie,
+// in which case the connection is attempted without authorization.
+func x11Authority(display string) (string, []byte) {
+	path := os.Getenv("XAUTHORITY")
+	if path == "" {
+		usr, err := user.Current()
+		if err != nil {
+			return "", nil
+		}
+
+		path = filepath.Join(usr.HomeDir, ".Xauthority")
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", nil
+	}
+
+	hostname, _ := os.Hostname()
+	return parseX11Authority(b, hostname, display)
+}
+
+func parseX11Authority(b []byte, hostname, display string) (string, []byte) {
+	const (
+		familyLocal = 256
+		familyWild  = 65535
+	)
+
+	field := func() ([]byte, bool) {
+		if len(b) < 2 {
+			return nil, false
+		}
+
+		length := int(binary.BigEndian.Uint16(b))
+		if len(b) < 2+length {
+			return nil, false
+		}
+
+		value := b[2 : 2+length]
+		b = b[2+length:]
+		return value, true
+	}
+
+	for len(b) >= 2 {
+		family := binary.BigEndian.Uint16(b)
+		b = b[2:]
+
+		address, ok := field()
+		if !ok {
+			break
+		}
+
+		number, ok := field()
+		if !ok {
+			break
+		}
+
+		name, ok := field()
+		if !ok {
+			break
+		}
+
+		data, ok := field()
+		if !ok {
+			break
+		}
+
+		if string(name) != "MIT-MAGIC-COOKIE-1" {
+			continue
+		}
+
+		if family == familyLocal && string(address) != hostname {
+			continue
+		}
+
+		if family != familyWild && len(number) > 0 && string(number) != display {
+			continue
+		}
+
+		return string(name), data
+	}
+
+	return "", nil
+}
+
+// Pad a value with zeros to a multiple of four bytes
+func x11Pad(b []byte) []byte {
+	padded := make([]byte, (len(b)+3)/4*4)
+	copy(padded, b)
+	return padded
+}
+
+// Ask the X server how long it has been since the last user input, using the MIT-SCREEN-SAVER extension
+// https://www.x.org/releases/X11R7.7/doc/scrnsaverproto/saver.html
+func (x *x11) idleTime() (time.Duration, error) {
+	major, err := x.extension("MIT-SCREEN-SAVER")
+	if err != nil {
+		return 0, err
+	}
+
+	body := make([]byte, 4)
+	binary.LittleEndian.PutUint32(body, x.root)
+
+	reply, err := x.request(major, x11ScreenSaverQueryInfo, body)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(binary.LittleEndian.Uint32(reply[16:])) * time.Millisecond, nil
+}
+
+// Get the major opcode of an extension
+func (x *x11) extension(name string) (byte, error) {
+	if opcode, ok := x.extensions[name]; ok {
+		return opcode, nil
+	}
+
+	body := make([]byte, 4)
+	binary.LittleEndian.PutUint16(body, uint16(len(name)))
+	body = append(body, x11Pad([]byte(name))...)
+
+	reply, err := x.request(x11OpQueryExtension, 0, body)
+	if err != nil {
+		return 0, err
+	}
+
+	if reply[8] == 0 {
+		return 0, errors.New(fmt.Sprintf("x server does not support the %s extension", name))
+	}
+
+	x.extensions[name] = reply[9]
+	return reply[9], nil
+}
@@ -0,0 +1,235 @@
+// +build linux
+
+package system
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+const (
+	fakeX11Root             = 0x1e1
+	fakeX11ScreenSaver      = 140
+	fakeX11IdleMilliseconds = 95000
+)
+
+// Answer the connection setup of the client with a single screen
+func acceptFakeX11(conn net.Conn) bool {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return false
+	}
+
+	auth := make([]byte, len(x11Pad(make([]byte, binary.LittleEndian.Uint16(header[6:]))))+
+		len(x11Pad(make([]byte, binary.LittleEndian.Uint16(header[8:])))))
+	if _, err := io.ReadFull(conn, auth); err != nil {
+		return false
+	}
+
+	// Setup reply without vendor and formats, followed by a single screen
+	setup := make([]byte, 8+32+40)
+	setup[0] = 1
+	binary.LittleEndian.PutUint16(setup[2:], 11)
+	binary.LittleEndian.PutUint16(setup[6:], (32+40)/4)
+	binary.LittleEndian.PutUint32(setup[40:], fakeX11Root)
+	if _, err := conn.Write(setup); err != nil {
+		return false
+	}
+
+	return true
+}
+
+// Serve the X11 requests the client use, properties are given per window and property name
+func serveFakeX11(conn net.Conn, properties map[uint32]map[string][]byte) {
+	defer conn.Close()
+
+	if !acceptFakeX11(conn) {
+		return
+	}
+
+	atoms := map[string]uint32{}
+	names := map[uint32]string{}
+	var sequence uint16
+
+	for {
+		head := make([]byte, 4)
+		if _, err := io.ReadFull(conn, head); err != nil {
+			return
+		}
+
+		body := make([]byte, int(binary.LittleEndian.Uint16(head[2:]))*4-4)
+		if _, err := io.ReadFull(conn, body); err != nil {
+			return
+		}
+
+		sequence++
+		reply := make([]byte, 32)
+		reply[0] = 1
+		binary.LittleEndian.PutUint16(reply[2:], sequence)
+
+		switch head[0] {
+		case x11OpInternAtom:
+			name := string(body[4 : 4+binary.LittleEndian.Uint16(body)])
+			if _, ok := atoms[name]; !ok {
+				atoms[name] = uint32(100 + len(atoms))
+				names[atoms[name]] = name
+			}
+
+			binary.LittleEndian.PutUint32(reply[8:], atoms[name])
+		case x11OpGetProperty:
+			value, ok := properties[binary.LittleEndian.Uint32(body)][names[binary.LittleEndian.Uint32(body[4:])]]
+			if ok {
+				// Windows and cardinals are 32 bit values, strings are bytes
+				format := 32
+				if typ := binary.LittleEndian.Uint32(body[8:]); typ != x11AtomCardinal && typ != x11AtomWindow {
+					format = 8
+				}
+
+				reply[1] = byte(format)
+				binary.LittleEndian.PutUint32(reply[4:], uint32(len(x11Pad(value))/4))
+				binary.LittleEndian.PutUint32(reply[8:], binary.LittleEndian.Uint32(body[8:]))
+				binary.LittleEndian.PutUint32(reply[16:], uint32(len(value)*8/format))
+				reply = append(reply, x11Pad(value)...)
+			}
+		case x11OpQueryExtension:
+			if string(body[4:4+binary.LittleEndian.Uint16(body)]) == "MIT-SCREEN-SAVER" {
+				reply[8] = 1
+				reply[9] = fakeX11ScreenSaver
+			}
+		case fakeX11ScreenSaver:
+			binary.LittleEndian.PutUint32(reply[16:], fakeX11IdleMilliseconds)
+		default:
+			reply[0] = 0
+			reply[1] = 1
+			reply[10] = head[0]
+		}
+
+		if _, err := conn.Write(reply); err != nil {
+			return
+		}
+	}
+}
+
+func x11Cardinal(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return b
+}
+
+func TestLinux_X11ActiveWindow(t *testing.T) {
+	client, server := net.Pipe()
+	go serveFakeX11(server, map[uint32]map[string][]byte{
+		fakeX11Root: {"_NET_ACTIVE_WINDOW": x11Cardinal(0x3400007)},
+		0x3400007:   {"_NET_WM_PID": x11Cardinal(4242)},
+	})
+
+	x, err := newX11(client, "", nil)
+	assert.NoError(t, err, "connection setup should not result in error")
+	defer x.Close()
+
+	assert.Equal(t, uint32(fakeX11Root), x.root, "root window should be read from setup")
+
+	window, err := x.activeWindow()
+	assert.NoError(t, err, "getting active window should not result in error")
+	assert.Equal(t, uint32(0x3400007), window, "active window should be read from root")
+
+	pid, err := x.windowPID(window)
+	assert.NoError(t, err, "getting window pid should not result in error")
+	assert.Equal(t, int64(4242), pid, "pid should be read from window")
+}
+
+func TestLinux_X11WindowTitle(t *testing.T) {
+	client, server := net.Pipe()
+	go serveFakeX11(server, map[uint32]map[string][]byte{
+		0x3400007: {"_NET_WM_NAME": []byte("main.go — client"), "WM_NAME": []byte("main.go - client")},
+		0x3600001: {"WM_NAME": []byte("caf\xe9.go - VIM")},
+	})
+
+	x, err := newX11(client, "", nil)
+	assert.NoError(t, err, "connection setup should not result in error")
+	defer x.Close()
+
+	assert.Equal(t, "main.go — client", x.windowTitle(0x3400007), "utf-8 title should be preferred")
+	assert.Equal(t, "café.go - VIM", x.windowTitle(0x3600001), "latin-1 title should be converted")
+	assert.Equal(t, "", x.windowTitle(0x3800001), "window without title should have an empty title")
+}
+
+func TestLinux_X11MissingProperty(t *testing.T) {
+	client, server := net.Pipe()
+	go serveFakeX11(server, map[uint32]map[string][]byte{
+		fakeX11Root: {"_NET_ACTIVE_WINDOW": x11Cardinal(0x3400007)},
+	})
+
+	x, err := newX11(client, "", nil)
+	assert.NoError(t, err, "connection setup should not result in error")
+	defer x.Close()
+
+	_, err = x.windowPID(0x3400007)
+	assert.Error(t, err, "window without _NET_WM_PID should result in error")
+}
+
+func TestLinux_X11Authority(t *testing.T) {
+	var b []byte
+	entry := func(family uint16, fields ...string) {
+		b = append(b, byte(family>>8), byte(family))
+		for _, field := range fields {
+			b = append(b, byte(len(field)>>8), byte(len(field)))
+			b = append(b, field...)
+		}
+	}
+
+	entry(256, "otherhost", "0", "MIT-MAGIC-COOKIE-1", "wrong")
+	entry(256, "myhost", "1", "MIT-MAGIC-COOKIE-1", "wrong")
+	entry(256, "myhost", "0", "MIT-MAGIC-COOKIE-1", "cookie")
+
+	name, data := parseX11Authority(b, "myhost", "0")
+	assert.Equal(t, "MIT-MAGIC-COOKIE-1", name, "cookie name should be found")
+	assert.Equal(t, []byte("cookie"), data, "cookie for display should be found")
+}
+
+func TestLinux_X11IdleTime(t *testing.T) {
+	client, server := net.Pipe()
+	go serveFakeX11(server, nil)
+
+	x, err := newX11(client, "", nil)
+	assert.NoError(t, err, "connection setup should not result in error")
+	defer x.Close()
+
+	idle, err := x.idleTime()
+	assert.NoError(t, err, "getting idle time should not result in error")
+	assert.Equal(t, time.Millisecond*fakeX11IdleMilliseconds, idle, "idle time should be read from screen saver info")
+}
+
+func TestLinux_X11Timeout(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+
+	// The server accepts the connection and then never replies to a request
+	go func() {
+		if !acceptFakeX11(server) {
+			return
+		}
+
+		_, _ = io.Copy(ioutil.Discard, server)
+	}()
+
+	x, err := newX11(client, "", nil)
+	assert.NoError(t, err, "connection setup should not result in error")
+	defer x.Close()
+
+	x.timeout = time.Millisecond * 50
+
+	_, err = x.activeWindow()
+	assert.Error(t, err, "request without reply should result in error")
+
+	netErr, ok := err.(net.Error)
+	assert.True(t, ok && netErr.Timeout(), "request without reply should time out")
+
+	_, ok = err.(x11Error)
+	assert.False(t, ok, "timeout should not be a protocol error, so the connection is dialed again")
+}
@@ -0,0 +1,81 @@
+package system
+
+import (
+	"context"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+// How often the lock state is checked when the operating system can not tell when the screen is locked
+const lockPollInterval = time.Second * 5
+
+type PowerEventType int
+
+const (
+	PowerSuspend PowerEventType = iota
+	PowerResume
+	PowerLock
+	PowerUnlock
+)
+
+func (t PowerEventType) String() string {
+	switch t {
+	case PowerSuspend:
+		return "suspend"
+	case PowerResume:
+		return "resume"
+	case PowerLock:
+		return "lock"
+	case PowerUnlock:
+		return "unlock"
+	}
+
+	return "unknown"
+}
+
+// PowerEvent tells that the machine is about to sleep, has woken up, or that the screen was locked or unlocked
+type PowerEvent struct {
+	Type PowerEventType
+	Time time.Time
+}
+
+// Emit lock and unlock events by checking the lock state regularly. The first state is the baseline, so a screen
+// that is locked when the watch starts is not reported.
+func pollLock(ctx context.Context, locked func() (bool, error), channel chan<- PowerEvent) {
+	defer close(channel)
+
+	ticker := time.NewTicker(lockPollInterval)
+	defer ticker.Stop()
+
+	previous, err := locked()
+	known := err == nil
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		current, err := locked()
+		if err != nil {
+			log.Debug().Err(err).Msg("could not get screen lock state")
+			continue
+		}
+
+		if known && current != previous {
+			event := PowerEvent{Type: PowerUnlock, Time: time.Now()}
+			if current {
+				event.Type = PowerLock
+			}
+
+			select {
+			case channel <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		previous, known = current, true
+	}
+}
@@ -1,31 +1,84 @@
 package system
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"io"
 	"os"
+	"time"
 )
 
 type System interface {
 	Processes() ([]*Process, error)
 	ActiveProcess() (*Process, error)
-	ListenKeyboard(chan byte)
+	ActiveWindow() (*Window, error)
+	Application(process *Process) (*Application, error)
+	ListenKeyboard(chan KeyEvent)
+	ProcessTree(processID int64) (*ProcessNode, error)
+	CacheStats() CacheStats
+	WatchProcesses(ctx context.Context) <-chan ProcessEvent
+	IdleTime() (time.Duration, error)
+	ScreenLocked() (bool, error)
+	WatchPower(ctx context.Context) <-chan PowerEvent
 }
 
 type Process struct {
-	ProcessID  int64
-	Parent     int64
-	Children   []int64
-	FileName   string
-	Checksum   string
-	Executable string
+	ProcessID   int64
+	Parent      int64
+	Children    []int64
+	FileName    string
+	Executable  string
+	CommandLine []string
+	StartTime   time.Time
+}
+
+// Checksum of the executable, it is calculated the first time it is asked for and cached until the executable changes
+func (p *Process) Checksum() (string, error) {
+	return processes.checksum(p.Executable)
+}
+
+// ProcessNode is a process together with all of its descendants
+type ProcessNode struct {
+	Process  *Process
+	Children []*ProcessNode
 }
 
 func New() System {
 	return &target{}
 }
 
+func (t *target) CacheStats() CacheStats {
+	return processes.Stats()
+}
+
+// Build the tree recursively, visited guards against loops when process IDs are reused while walking
+func processTree(processID int64, lookup func(int64) (*Process, error), visited map[int64]bool) (*ProcessNode, error) {
+	process, err := lookup(processID)
+	if err != nil {
+		return nil, err
+	}
+
+	visited[processID] = true
+	node := &ProcessNode{Process: process}
+
+	for _, child := range process.Children {
+		if visited[child] {
+			continue
+		}
+
+		// Children can exit or be unreadable while walking, those are left out
+		childNode, err := processTree(child, lookup, visited)
+		if err != nil {
+			continue
+		}
+
+		node.Children = append(node.Children, childNode)
+	}
+
+	return node, nil
+}
+
 // Create a checksum of given file
 func checksum(path string) (string, error) {
 	hasher := sha256.New()
@@ -0,0 +1,430 @@
+// Package systemtest provides a scriptable System, so code that depends on the operating system can be tested
+// without a real keyboard, windows or processes.
+package systemtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/pacerank/client/pkg/system"
+	"sort"
+	"sync"
+	"time"
+)
+
+var _ system.System = (*System)(nil)
+
+// System is a fake system where the test decides which processes run, which one is active and which keys are pressed
+type System struct {
+	mu        sync.Mutex
+	processes map[int64]*system.Process
+	apps      map[int64]*system.Application
+	titles    map[int64]string
+	active    int64
+	idle      time.Duration
+	locked    bool
+	stats     system.CacheStats
+	keys      chan system.KeyEvent
+	watchers  []*processWatcher
+	power     []*powerWatcher
+}
+
+// The channel of a watcher is closed when its context is done, the lock makes sure nothing is sent after that
+type processWatcher struct {
+	mu      sync.Mutex
+	ctx     context.Context
+	channel chan system.ProcessEvent
+	closed  bool
+}
+
+func (w *processWatcher) send(event system.ProcessEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return
+	}
+
+	select {
+	case w.channel <- event:
+	case <-w.ctx.Done():
+	}
+}
+
+func (w *processWatcher) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+	close(w.channel)
+}
+
+type powerWatcher struct {
+	mu      sync.Mutex
+	ctx     context.Context
+	channel chan system.PowerEvent
+	closed  bool
+}
+
+func (w *powerWatcher) send(event system.PowerEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return
+	}
+
+	select {
+	case w.channel <- event:
+	case <-w.ctx.Done():
+	}
+}
+
+func (w *powerWatcher) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+	close(w.channel)
+}
+
+func New() *System {
+	return &System{
+		processes: make(map[int64]*system.Process),
+		apps:      make(map[int64]*system.Application),
+		titles:    make(map[int64]string),
+		keys:      make(chan system.KeyEvent),
+	}
+}
+
+// Add a process that is running from the start, it is not reported to process watchers
+func (s *System) AddProcess(process *system.Process) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.addProcess(process)
+}
+
+// Start a process, every process watcher receives the event before this returns
+func (s *System) StartProcess(process *system.Process) {
+	s.mu.Lock()
+	s.addProcess(process)
+	watchers := s.processWatchers()
+	s.mu.Unlock()
+
+	event := system.ProcessEvent{Type: system.ProcessStarted, ProcessID: process.ProcessID, Process: process, Time: time.Now()}
+	for _, watcher := range watchers {
+		watcher.send(event)
+	}
+}
+
+// Exit a process, every process watcher receives the event before this returns. If the process was active, no
+// process is active anymore.
+func (s *System) ExitProcess(processID int64) {
+	s.mu.Lock()
+	process, ok := s.processes[processID]
+	if ok {
+		delete(s.processes, processID)
+		delete(s.apps, processID)
+		delete(s.titles, processID)
+
+		if parent, ok := s.processes[process.Parent]; ok {
+			parent.Children = removeChild(parent.Children, processID)
+		}
+	}
+
+	if s.active == processID {
+		s.active = 0
+	}
+
+	watchers := s.processWatchers()
+	s.mu.Unlock()
+
+	event := system.ProcessEvent{Type: system.ProcessExited, ProcessID: processID, Time: time.Now()}
+	for _, watcher := range watchers {
+		watcher.send(event)
+	}
+}
+
+// Tell which application a process belongs to
+func (s *System) SetApplication(processID int64, application *system.Application) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.apps[processID] = application
+}
+
+// Set the title of the window of a process
+func (s *System) SetTitle(processID int64, title string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.titles[processID] = title
+}
+
+// Give focus to a process, it has to be added or started first
+func (s *System) SetActive(processID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.active = processID
+}
+
+// Send a key event to the keyboard listener, it blocks until the listener has received it. The process ID is set to
+// the active process when it is not given.
+func (s *System) Key(event system.KeyEvent) {
+	if event.ProcessID == 0 {
+		s.mu.Lock()
+		event.ProcessID = s.active
+		s.mu.Unlock()
+	}
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	s.keys <- event
+}
+
+// Press and release a key of given class in the active process
+func (s *System) Press(class system.KeyClass) {
+	s.Key(system.KeyEvent{Class: class, State: system.KeyDown})
+	s.Key(system.KeyEvent{Class: class, State: system.KeyUp})
+}
+
+// Set how long the machine has been without input
+func (s *System) SetIdle(idle time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.idle = idle
+}
+
+// Lock or unlock the screen, every power watcher receives the event before this returns
+func (s *System) SetLocked(locked bool) {
+	s.mu.Lock()
+	s.locked = locked
+	s.mu.Unlock()
+
+	event := system.PowerEvent{Type: system.PowerUnlock, Time: time.Now()}
+	if locked {
+		event.Type = system.PowerLock
+	}
+
+	s.Power(event)
+}
+
+// Send a power event to every power watcher
+func (s *System) Power(event system.PowerEvent) {
+	s.mu.Lock()
+	watchers := make([]*powerWatcher, 0, len(s.power))
+	for _, watcher := range s.power {
+		if watcher.ctx.Err() == nil {
+			watchers = append(watchers, watcher)
+		}
+	}
+
+	s.power = watchers
+	s.mu.Unlock()
+
+	for _, watcher := range watchers {
+		watcher.send(event)
+	}
+}
+
+// Set the cache statistics that are reported
+func (s *System) SetCacheStats(stats system.CacheStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats = stats
+}
+
+func (s *System) Processes() ([]*system.Process, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]*system.Process, 0, len(s.processes))
+	for _, process := range s.processes {
+		result = append(result, copyProcess(process))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ProcessID < result[j].ProcessID
+	})
+
+	return result, nil
+}
+
+func (s *System) ActiveProcess() (*system.Process, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active == 0 {
+		return nil, errors.New("no window is currently active")
+	}
+
+	process, ok := s.processes[s.active]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("active process %d does not exist", s.active))
+	}
+
+	return copyProcess(process), nil
+}
+
+func (s *System) ActiveWindow() (*system.Window, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active == 0 {
+		return nil, errors.New("no window is currently active")
+	}
+
+	return &system.Window{ProcessID: s.active, Title: s.titles[s.active]}, nil
+}
+
+func (s *System) Application(process *system.Process) (*system.Application, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	application, ok := s.apps[process.ProcessID]
+	if !ok {
+		return nil, system.ErrUnknownApplication
+	}
+
+	copied := *application
+	return &copied, nil
+}
+
+// Forward the key events given to Key, like the real listener this never returns
+func (s *System) ListenKeyboard(channel chan system.KeyEvent) {
+	for event := range s.keys {
+		channel <- event
+	}
+}
+
+func (s *System) ProcessTree(processID int64) (*system.ProcessNode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.processTree(processID, make(map[int64]bool))
+}
+
+func (s *System) CacheStats() system.CacheStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stats
+}
+
+// Events are sent from StartProcess and ExitProcess, the channel is closed when the context is done
+func (s *System) WatchProcesses(ctx context.Context) <-chan system.ProcessEvent {
+	watcher := &processWatcher{ctx: ctx, channel: make(chan system.ProcessEvent)}
+
+	s.mu.Lock()
+	s.watchers = append(s.watchers, watcher)
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		watcher.close()
+	}()
+
+	return watcher.channel
+}
+
+func (s *System) IdleTime() (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.idle, nil
+}
+
+func (s *System) ScreenLocked() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.locked, nil
+}
+
+// Events are sent from SetLocked and Power, the channel is closed when the context is done
+func (s *System) WatchPower(ctx context.Context) <-chan system.PowerEvent {
+	watcher := &powerWatcher{ctx: ctx, channel: make(chan system.PowerEvent)}
+
+	s.mu.Lock()
+	s.power = append(s.power, watcher)
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		watcher.close()
+	}()
+
+	return watcher.channel
+}
+
+// Must be called with the lock held
+func (s *System) addProcess(process *system.Process) {
+	s.processes[process.ProcessID] = copyProcess(process)
+
+	if parent, ok := s.processes[process.Parent]; ok && process.Parent != process.ProcessID {
+		parent.Children = append(removeChild(parent.Children, process.ProcessID), process.ProcessID)
+	}
+}
+
+// Must be called with the lock held, watchers that are done are removed
+func (s *System) processWatchers() []*processWatcher {
+	watchers := make([]*processWatcher, 0, len(s.watchers))
+	for _, watcher := range s.watchers {
+		if watcher.ctx.Err() == nil {
+			watchers = append(watchers, watcher)
+		}
+	}
+
+	s.watchers = watchers
+	return watchers
+}
+
+// Must be called with the lock held
+func (s *System) processTree(processID int64, visited map[int64]bool) (*system.ProcessNode, error) {
+	process, ok := s.processes[processID]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("process %d does not exist", processID))
+	}
+
+	visited[processID] = true
+	node := &system.ProcessNode{Process: copyProcess(process)}
+
+	for _, child := range process.Children {
+		if visited[child] {
+			continue
+		}
+
+		childNode, err := s.processTree(child, visited)
+		if err != nil {
+			continue
+		}
+
+		node.Children = append(node.Children, childNode)
+	}
+
+	return node, nil
+}
+
+func copyProcess(process *system.Process) *system.Process {
+	result := *process
+	result.Children = append([]int64(nil), process.Children...)
+	result.CommandLine = append([]string(nil), process.CommandLine...)
+	return &result
+}
+
+func removeChild(children []int64, processID int64) []int64 {
+	result := children[:0]
+	for _, child := range children {
+		if child != processID {
+			result = append(result, child)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,44 @@
+package systemtest
+
+import (
+	"context"
+	"github.com/pacerank/client/pkg/system"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSystem_Processes(t *testing.T) {
+	sys := New()
+	sys.AddProcess(&system.Process{ProcessID: 1, FileName: "init"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := sys.WatchProcesses(ctx)
+
+	go sys.StartProcess(&system.Process{ProcessID: 2, Parent: 1, FileName: "shell"})
+
+	event := <-events
+	assert.Equal(t, system.ProcessStarted, event.Type, "started process should be reported")
+	assert.Equal(t, int64(2), event.ProcessID, "started process should be reported")
+
+	tree, err := sys.ProcessTree(1)
+	assert.NoError(t, err, "getting process tree should not result in error")
+	assert.Len(t, tree.Children, 1, "started process should be a child of its parent")
+
+	sys.SetActive(2)
+	go sys.ExitProcess(2)
+
+	event = <-events
+	assert.Equal(t, system.ProcessExited, event.Type, "exited process should be reported")
+
+	_, err = sys.ActiveProcess()
+	assert.Error(t, err, "exited process should not be active")
+
+	processes, err := sys.Processes()
+	assert.NoError(t, err, "listing processes should not result in error")
+	assert.Len(t, processes, 1, "exited process should not be listed")
+
+	cancel()
+
+	_, ok := <-events
+	assert.False(t, ok, "channel should be closed when the context is done")
+}
@@ -0,0 +1,8 @@
+package system
+
+// Window is the window that has focus, together with the process that owns it. The title is what the window manager
+// shows, editors often put the file and project in it.
+type Window struct {
+	ProcessID int64
+	Title     string
+}
@@ -3,13 +3,11 @@
 package system
 
 import (
-	"os/exec"
+	"os"
 	"syscall"
 )
 
-type target struct {
-	processes []*Process
-}
+type target struct{}
 
 // kernel32.dll API calls
 var (
@@ -19,6 +17,7 @@ var (
 	procProcess32First           = modKernel32.NewProc("Process32FirstW")
 	procProcess32Next            = modKernel32.NewProc("Process32NextW")
 	procModule32First            = modKernel32.NewProc("Module32FirstW")
+	procGetTickCount             = modKernel32.NewProc("GetTickCount")
 )
 
 // User32.dll API calls
@@ -30,6 +29,11 @@ var (
 	procCallNextHookEx        = modUser32.NewProc("CallNextHookEx")
 	procGetMessage            = modUser32.NewProc("GetMessageW")
 	procUnhookWindowsHookEx   = modUser32.NewProc("UnhookWindowsHookEx")
+	procGetLastInputInfo      = modUser32.NewProc("GetLastInputInfo")
+	procOpenInputDesktop      = modUser32.NewProc("OpenInputDesktop")
+	procCloseDesktop          = modUser32.NewProc("CloseDesktop")
+	procGetWindowTextLength   = modUser32.NewProc("GetWindowTextLengthW")
+	procGetWindowText         = modUser32.NewProc("GetWindowTextW")
 )
 
 // Type structure for windows API
@@ -46,6 +50,14 @@ type (
 	BYTE      int64
 )
 
+// Open url in the default browser, returns ErrNoBrowser if it could not be opened
 func OpenBrowser(url string) error {
-	return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	return launchBrowser(url, []browserLauncher{
+		{name: "rundll32", args: []string{"url.dll,FileProtocolHandler"}},
+	}, startBrowser)
+}
+
+// Files are told apart by path, size and modification time on Windows
+func fileIdentity(info os.FileInfo) uint64 {
+	return 0
 }
@@ -0,0 +1,8 @@
+// +build windows
+
+package system
+
+// Applications are recognised by their executable on Windows, so there is nothing more to resolve
+func (t *target) Application(process *Process) (*Application, error) {
+	return nil, ErrUnknownApplication
+}
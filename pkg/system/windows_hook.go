@@ -4,6 +4,7 @@ package system
 
 import (
 	"syscall"
+	"time"
 	"unsafe"
 )
 
@@ -114,16 +115,78 @@ func getMessage(msg *MSG, hwnd HWND, msgFilterMin uint32, msgFilterMax uint32) i
 	return int(ret)
 }
 
-// This function setups a listener on the channel that send back the byte type of the key press
-func (t *target) ListenKeyboard(channel chan byte) {
+// Virtual key codes that are not characters
+// https://docs.microsoft.com/en-us/windows/win32/inputdev/virtual-key-codes
+var (
+	windowsEditKeys = map[DWORD]bool{
+		0x08: true, // VK_BACK
+		0x2D: true, // VK_INSERT
+		0x2E: true, // VK_DELETE
+	}
+
+	windowsNavigationKeys = map[DWORD]bool{
+		0x21: true, // VK_PRIOR
+		0x22: true, // VK_NEXT
+		0x23: true, // VK_END
+		0x24: true, // VK_HOME
+		0x25: true, // VK_LEFT
+		0x26: true, // VK_UP
+		0x27: true, // VK_RIGHT
+		0x28: true, // VK_DOWN
+	}
+
+	// The value tells if it is a command modifier
+	windowsModifierKeys = map[DWORD]bool{
+		0x10: false, // VK_SHIFT
+		0x11: true,  // VK_CONTROL
+		0x12: true,  // VK_MENU
+		0x14: false, // VK_CAPITAL
+		0x5B: true,  // VK_LWIN
+		0x5C: true,  // VK_RWIN
+		0xA0: false, // VK_LSHIFT
+		0xA1: false, // VK_RSHIFT
+		0xA2: true,  // VK_LCONTROL
+		0xA3: true,  // VK_RCONTROL
+		0xA4: true,  // VK_LMENU
+		0xA5: true,  // VK_RMENU
+	}
+)
+
+// This function setups a listener on the channel that send back the class of every key that goes down or up
+func (t *target) ListenKeyboard(channel chan KeyEvent) {
 	var keyboardHook HHOOK
 
+	tracker := newKeyTracker()
+
 	keyboardHook = setWindowsHookEx(whKeyboardLL, func(nCode int, wParam WPARAM, lParam LPARAM) LRESULT {
 		next := callNextHookEx(keyboardHook, nCode, wParam, lParam)
-		if nCode == 0 && wParam == wmKeyDown {
-			structure := (*kbDllHookStruct)(unsafe.Pointer(lParam))
-			channel <- byte(structure.VkCode)
+		if nCode != 0 {
+			return next
+		}
+
+		var down bool
+		switch wParam {
+		case wmKeyDown, wmSysKeyDown:
+			down = true
+		case wmKeyUp, wmSysKeyUp:
+			down = false
+		default:
+			return next
+		}
+
+		structure := (*kbDllHookStruct)(unsafe.Pointer(lParam))
+		class, command := windowsKeyClass(structure.VkCode)
+
+		event, ok := tracker.event(uint32(structure.VkCode), down, class, command, time.Now())
+		if !ok {
+			return next
+		}
+
+		if processID, err := activeProcessID(); err == nil {
+			event.ProcessID = int64(processID)
 		}
+
+		channel <- event
 		return next
 	}, 0, 0)
 
@@ -135,3 +198,22 @@ func (t *target) ListenKeyboard(channel chan byte) {
 	unhookWindowsHookEx(keyboardHook)
 	keyboardHook = 0
 }
+
+// Classify a virtual key code, and tell if it is a modifier that turns other keys into shortcuts
+func windowsKeyClass(code DWORD) (KeyClass, bool) {
+	if command, ok := windowsModifierKeys[code]; ok {
+		return KeyModifier, command
+	}
+
+	switch {
+	case windowsEditKeys[code]:
+		return KeyEdit, false
+	case windowsNavigationKeys[code]:
+		return KeyNavigation, false
+	case code == 0x1B || (code >= 0x70 && code <= 0x87):
+		// VK_ESCAPE and the function keys VK_F1 to VK_F24 run commands rather than type
+		return KeyShortcut, false
+	}
+
+	return KeyCharacter, false
+}
@@ -0,0 +1,45 @@
+// +build windows
+
+package system
+
+import (
+	"time"
+	"unsafe"
+)
+
+// Access right to switch desktop, the input desktop can not be opened with it while the workstation is locked
+const desktopSwitchDesktop = 0x0100
+
+// Contains the time of the last input.
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/ns-winuser-lastinputinfo
+type lastInputInfo struct {
+	Size DWORD
+	Time DWORD
+}
+
+// Retrieves the time of the last input event, and compare it to the milliseconds since the system was started. Both
+// are 32 bit tick counts, so the subtraction is done in 32 bits to handle the wrap around after 49.7 days.
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-getlastinputinfo
+func (t *target) IdleTime() (time.Duration, error) {
+	var info lastInputInfo
+	info.Size = DWORD(unsafe.Sizeof(info))
+
+	if ok, _, err := procGetLastInputInfo.Call(uintptr(unsafe.Pointer(&info))); ok == 0 {
+		return 0, err
+	}
+
+	ticks, _, _ := procGetTickCount.Call()
+	return time.Duration(uint32(ticks)-uint32(info.Time)) * time.Millisecond, nil
+}
+
+// Opens the desktop that receives user input, this fails while the workstation is locked.
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/nf-winuser-openinputdesktop
+func (t *target) ScreenLocked() (bool, error) {
+	desktop, _, _ := procOpenInputDesktop.Call(0, 0, desktopSwitchDesktop)
+	if desktop == 0 {
+		return true, nil
+	}
+
+	_, _, _ = procCloseDesktop.Call(desktop)
+	return false, nil
+}
@@ -0,0 +1,15 @@
+// +build windows
+
+package system
+
+import (
+	"context"
+)
+
+// Session changes are only sent to windows, so the lock state is polled instead. Sleep is not reported here, it is
+// seen by the watcher as a gap between the wall clock and the monotonic clock.
+func (t *target) WatchPower(ctx context.Context) <-chan PowerEvent {
+	channel := make(chan PowerEvent)
+	go pollLock(ctx, t.ScreenLocked, channel)
+	return channel
+}
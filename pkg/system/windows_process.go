@@ -3,10 +3,12 @@
 package system
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/rs/zerolog/log"
 	"syscall"
+	"time"
 	"unsafe"
 )
 
@@ -15,6 +17,10 @@ const (
 	MaxPath         = 260
 )
 
+// Access right that is enough to read the process times
+// https://docs.microsoft.com/en-us/windows/win32/procthread/process-security-and-access-rights
+const processQueryLimitedInformation = 0x1000
+
 // processEntry is the Windows API structure that contains a process's information.
 // https://docs.microsoft.com/en-us/windows/win32/api/tlhelp32/ns-tlhelp32-processentry32w
 type processEntry struct {
@@ -122,21 +128,14 @@ func (t *target) Processes() ([]*Process, error) {
 			continue
 		}
 
-		cs, err := checksum(path)
-		if err != nil {
-			if ok, _, _ := procProcess32Next.Call(hProcessSnap, uintptr(unsafe.Pointer(&process))); ok == 0 {
-				break
-			}
-
-			continue
-		}
+		startTime, _ := processStartTime(process.ProcessID)
 
 		result = append(result, &Process{
 			ProcessID:  int64(process.ProcessID),
 			Parent:     int64(process.ParentProcessID),
-			Checksum:   cs,
 			Executable: path,
 			FileName:   fileName,
+			StartTime:  startTime,
 		})
 
 		if ok, _, _ := procProcess32Next.Call(hProcessSnap, uintptr(unsafe.Pointer(&process))); ok == 0 {
@@ -148,35 +147,103 @@ func (t *target) Processes() ([]*Process, error) {
 }
 
 func (t *target) ActiveProcess() (*Process, error) {
+	processID, err := activeProcessID()
+	if err != nil {
+		return nil, err
+	}
+
+	return getProcess(processID)
+}
+
+func (t *target) ActiveWindow() (*Window, error) {
 	handle, _, _ := procForegroundWindow.Call()
 	if handle == 0 {
 		return nil, errors.New("no window is currently active")
 	}
 
+	processID, err := windowProcessID(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Window{ProcessID: int64(processID), Title: windowTitle(handle)}, nil
+}
+
+// Get the process ID of the foreground window
+func activeProcessID() (DWORD, error) {
+	handle, _, _ := procForegroundWindow.Call()
+	if handle == 0 {
+		return 0, errors.New("no window is currently active")
+	}
+
+	return windowProcessID(handle)
+}
+
+func windowProcessID(handle uintptr) (DWORD, error) {
 	var processID DWORD
 
 	_, _, _ = procWindowThreadProcessId.Call(handle, uintptr(unsafe.Pointer(&processID)))
 	if processID == 0 {
-		return nil, errors.New("process id could not be found for window handle")
+		return 0, errors.New("process id could not be found for window handle")
 	}
 
-	for _, process := range t.processes {
-		if process.ProcessID == int64(processID) {
-			return process, nil
-		}
+	return processID, nil
+}
+
+// Get the title of a window, it is empty when the window has none
+func windowTitle(handle uintptr) string {
+	length, _, _ := procGetWindowTextLength.Call(handle)
+	if length == 0 {
+		return ""
 	}
 
-	process, err := getProcess(processID)
+	buffer := make([]uint16, length+1)
+	n, _, _ := procGetWindowText.Call(handle, uintptr(unsafe.Pointer(&buffer[0])), uintptr(len(buffer)))
+	return syscall.UTF16ToString(buffer[:n])
+}
+
+func (t *target) ProcessTree(processID int64) (*ProcessNode, error) {
+	return processTree(processID, func(processID int64) (*Process, error) {
+		return getProcess(DWORD(processID))
+	}, make(map[int64]bool))
+}
+
+func (t *target) WatchProcesses(ctx context.Context) <-chan ProcessEvent {
+	channel := make(chan ProcessEvent)
+	go pollProcesses(ctx, t.Processes, channel)
+	return channel
+}
+
+// Get process information. Everything but the children is cached for as long as the process lives, the start time
+// tells if the process ID has been reused.
+func getProcess(processID DWORD) (*Process, error) {
+	startTime, err := processStartTime(processID)
+	if err != nil {
+		// Without a start time the process can not be told apart from a later process with the same ID
+		return loadProcess(processID)
+	}
+
+	processes.prune(processAlive)
+
+	// The image of a process does not change on Windows
+	cached, err := processes.lookup(int64(processID), startTime, "", func() (*Process, error) {
+		return loadProcess(processID)
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	t.processes = append(t.processes, process)
-	return process, nil
+	process := *cached
+	process.Children, err = processChildren(processID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &process, nil
 }
 
-// Get process information
-func getProcess(processID DWORD) (result *Process, err error) {
+// Read process information from a process snapshot
+func loadProcess(processID DWORD) (result *Process, err error) {
 	// Create a process snap handler, with TH32CS_SNAPTHREAD (0x00000004)
 	hProcessSnap, _, _ := procCreateToolhelp32Snapshot.Call(0x00000002, uintptr(processID))
 	if hProcessSnap < 0 {
@@ -230,23 +297,15 @@ func getProcess(processID DWORD) (result *Process, err error) {
 				continue
 			}
 
-			cs, err := checksum(path)
-			if err != nil {
-				log.Error().Err(err).Msg("could not get checksum of executable")
-				if ok, _, _ := procProcess32Next.Call(hProcessSnap, uintptr(unsafe.Pointer(&process))); ok == 0 {
-					break
-				}
-
-				continue
-			}
+			startTime, _ := processStartTime(processID)
 
 			result = &Process{
 				ProcessID:  int64(process.ProcessID),
 				Parent:     int64(process.ParentProcessID),
 				Children:   nil,
 				FileName:   fileName,
-				Checksum:   cs,
 				Executable: path,
+				StartTime:  startTime,
 			}
 		}
 
@@ -264,6 +323,69 @@ func getProcess(processID DWORD) (result *Process, err error) {
 	return result, nil
 }
 
+// Get the process IDs of the direct children of a process
+func processChildren(processID DWORD) ([]int64, error) {
+	// Create a process snap handler, with TH32CS_SNAPPROCESS (0x00000002)
+	hProcessSnap, _, _ := procCreateToolhelp32Snapshot.Call(0x00000002, 0)
+	if hProcessSnap < 0 {
+		return nil, syscall.GetLastError()
+	}
+
+	// Close handler after method is ready
+	defer func() {
+		_, _, _ = procCloseHandle.Call(hProcessSnap)
+	}()
+
+	var process processEntry
+	process.Size = DWORD(unsafe.Sizeof(process))
+
+	if ok, _, _ := procProcess32First.Call(hProcessSnap, uintptr(unsafe.Pointer(&process))); ok == 0 {
+		return nil, errors.New("could not retrieve process info")
+	}
+
+	var children []int64
+	for {
+		if process.ParentProcessID == processID && process.ProcessID != processID {
+			children = append(children, int64(process.ProcessID))
+		}
+
+		if ok, _, _ := procProcess32Next.Call(hProcessSnap, uintptr(unsafe.Pointer(&process))); ok == 0 {
+			break
+		}
+	}
+
+	return children, nil
+}
+
+// Get the time a process was created
+// https://docs.microsoft.com/en-us/windows/win32/api/processthreadsapi/nf-processthreadsapi-getprocesstimes
+func processStartTime(processID DWORD) (time.Time, error) {
+	handle, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(processID))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	defer syscall.CloseHandle(handle)
+
+	var creation, exit, kernel, user syscall.Filetime
+	err = syscall.GetProcessTimes(handle, &creation, &exit, &kernel, &user)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Unix(0, creation.Nanoseconds()), nil
+}
+
+// Check if a process with the given start time is still running
+func processAlive(processID int64, startTime time.Time) bool {
+	current, err := processStartTime(DWORD(processID))
+	if err != nil {
+		return false
+	}
+
+	return current.Equal(startTime)
+}
+
 // Get executable path for a ProcessID in string format
 func getProcessPath(processID int64) (string, error) {
 	// Create a module snap handler with TH32CS_SNAPMODULE (0x00000008) for given process ID